@@ -1,7 +1,11 @@
 package main
 
 import (
-	"chirpy/internal/database"
+	"chirpy/internal/auth"
+	"chirpy/internal/mail"
+	"chirpy/internal/plans"
+	"chirpy/internal/storage"
+	"chirpy/internal/store"
 	"fmt"
 	"net/http"
 	"sync/atomic"
@@ -9,10 +13,16 @@ import (
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
-	Queries        database.Queries
+	Store          store.Store
 	Platform       string
 	Secret         string
-	PolkaKey       string
+	Keys           *auth.KeySet
+	PolkaWebhook   *auth.WebhookVerifier
+	AdminKey       string
+	Mailer         mail.Mailer
+	BaseURL        string
+	Storage        storage.Storage
+	Plans          *plans.Config
 }
 
 var ApiCfg = apiConfig{
@@ -41,7 +51,7 @@ func (apiCfg *apiConfig) reset(writer http.ResponseWriter, request *http.Request
 	}
 
 	apiCfg.fileserverHits.Store(0)
-	if err := apiCfg.Queries.Reset(request.Context()); err != nil {
+	if err := apiCfg.Store.Reset(request.Context()); err != nil {
 		errorResponse := fmt.Sprint(err)
 		respondWithJsonError(writer, errorResponse, 500)
 	}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type principalKey struct{}
+
+// authenticate resolves the bearer token on the request, which is either a
+// JWT access token or a personal access token, to the principal it acts
+// for. Requests that went through requireScope reuse the principal it
+// already resolved.
+func authenticate(request *http.Request) (auth.Principal, error) {
+	if principal, ok := request.Context().Value(principalKey{}).(auth.Principal); ok {
+		return principal, nil
+	}
+
+	token, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	if auth.IsPersonalAccessToken(token) {
+		return authenticatePersonalAccessToken(request.Context(), token)
+	}
+	return ApiCfg.Keys.ValidateJWT(token)
+}
+
+func authenticatePersonalAccessToken(ctx context.Context, token string) (auth.Principal, error) {
+	accessToken, err := ApiCfg.Store.GetPersonalAccessTokenByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if accessToken.RevokedAt.Valid {
+		return auth.Principal{}, errors.New("personal access token has been revoked")
+	}
+	if accessToken.ExpiresAt.Valid && accessToken.ExpiresAt.Time.Before(time.Now().UTC()) {
+		return auth.Principal{}, errors.New("personal access token has expired")
+	}
+
+	if err := ApiCfg.Store.TouchPersonalAccessToken(ctx, accessToken.ID); err != nil {
+		log.Printf("unable to update personal access token %s: %s", accessToken.ID, err)
+	}
+
+	return auth.Principal{
+		UserID:  accessToken.UserID,
+		TokenID: uuid.NullUUID{UUID: accessToken.ID, Valid: true},
+		Scopes:  accessToken.Scopes,
+	}, nil
+}
+
+// requireScope wraps a handler so that it only runs for requests whose
+// token carries scope. It answers 401 for a missing or invalid token and
+// 403 for a valid token without the scope.
+func requireScope(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		principal, err := authenticate(request)
+		if err != nil {
+			respondWithJsonError(writer, "Unauthorized", 401)
+			return
+		}
+		if !principal.HasScope(scope) {
+			respondWithJsonError(writer, "Token is missing the "+scope+" scope", 403)
+			return
+		}
+
+		ctx := context.WithValue(request.Context(), principalKey{}, principal)
+		handler(writer, request.WithContext(ctx))
+	}
+}
+
+// authenticatedUserID returns the ID of the user the request's token was
+// issued to.
+func authenticatedUserID(request *http.Request) (uuid.UUID, error) {
+	principal, err := authenticate(request)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return principal.UserID, nil
+}
+
+// authenticatedSession is authenticatedUserID that also returns the login
+// session the token was issued for. Personal access tokens and tokens
+// issued before sessions existed have none.
+func authenticatedSession(request *http.Request) (uuid.UUID, uuid.NullUUID, error) {
+	principal, err := authenticate(request)
+	if err != nil {
+		return uuid.UUID{}, uuid.NullUUID{}, err
+	}
+	return principal.UserID, principal.SessionID, nil
+}
+
+// optionalUserID is authenticatedUserID for endpoints that also serve
+// anonymous requests; a missing or invalid token counts as anonymous.
+func optionalUserID(request *http.Request) uuid.NullUUID {
+	if request.Header.Get("Authorization") == "" {
+		return uuid.NullUUID{}
+	}
+
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+// isAdmin reports whether the request carries the ADMIN_KEY as an
+// "ApiKey" authorization. No key configured means no admin access.
+func isAdmin(request *http.Request) bool {
+	if ApiCfg.AdminKey == "" {
+		return false
+	}
+
+	apiKey, err := auth.GetApiKey(request.Header)
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(apiKey), []byte(ApiCfg.AdminKey)) == 1
+}
@@ -1,25 +1,34 @@
 package main
 
 import (
-	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"slices"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	chirpKindChirp   = "chirp"
+	chirpKindRechirp = "rechirp"
+	chirpKindQuote   = "quote"
+)
+
 type chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Body      string        `json:"body"`
+	UserID    uuid.UUID     `json:"user_id"`
+	InReplyTo uuid.NullUUID `json:"in_reply_to"`
+	QuoteOf   uuid.NullUUID `json:"quote_of"`
 }
 
 func createChirp(writer http.ResponseWriter, request *http.Request) {
@@ -36,46 +45,132 @@ func createChirp(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	if len(chirp.Body) > 140 {
-		respondWithJsonError(writer, "Chirp is too long", 400)
+	id, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+	if !requireVerifiedEmail(writer, request, id) {
 		return
 	}
 
-	token, err := auth.GetBearerToken(request.Header)
-	if err != nil {
-		respondWithJsonError(writer, "Unauthorized", 401)
+	// the chirp length and posting rate depend on the user's plan
+	limits, ok := requireEntitlements(writer, request, id)
+	if !ok {
+		return
+	}
+	if len(chirp.Body) > limits.MaxChirpLength {
+		respondWithJsonError(writer, fmt.Sprintf("Chirp is too long, the limit is %d characters", limits.MaxChirpLength), 400)
 		return
 	}
 
-	id, err := auth.ValidateJWT(token, ApiCfg.Secret)
+	// replies must point at a chirp that still exists
+	if chirp.InReplyTo.Valid {
+		parent, err := ApiCfg.Store.GetChirp(request.Context(), chirp.InReplyTo.UUID)
+		if err != nil {
+			respondWithJsonError(writer, "Parent chirp not found", 400)
+			return
+		}
+		if parent.DeletedAt.Valid {
+			respondWithJsonError(writer, "Cannot reply to a deleted chirp", 400)
+			return
+		}
+	}
+
+	// quotes embed the chirp they quote alongside the new commentary
+	kind := chirpKindChirp
+	originalID := uuid.NullUUID{}
+	if chirp.QuoteOf.Valid {
+		if chirp.InReplyTo.Valid {
+			respondWithJsonError(writer, "A chirp cannot both reply to and quote a chirp", 400)
+			return
+		}
+		if strings.TrimSpace(chirp.Body) == "" {
+			respondWithJsonError(writer, "Quotes need a body", 400)
+			return
+		}
+
+		original, err := getOriginalChirp(request.Context(), chirp.QuoteOf.UUID)
+		if err != nil {
+			respondWithJsonError(writer, "Quoted chirp not found", 400)
+			return
+		}
+		if original.DeletedAt.Valid {
+			respondWithJsonError(writer, "Cannot quote a deleted chirp", 400)
+			return
+		}
+
+		kind = chirpKindQuote
+		originalID = uuid.NullUUID{UUID: original.ID, Valid: true}
+	}
+
+	// run the banned term filters
+	filters, err := ApiCfg.Store.ListChirpFilters(request.Context())
 	if err != nil {
-		respondWithJsonError(writer, "Unauthorized", 401)
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	censored := censorChirp(chirp.Body, filters)
+	if censored.Rejected != nil {
+		respondWithJsonError(writer, "Chirp contains a banned word", 400)
 		return
 	}
 
 	chirpParams := database.CreateChirpParams{
-		Body:   censorChirp(chirp.Body),
-		UserID: id,
+		Body:            censored.Body,
+		UserID:          id,
+		InReplyTo:       chirp.InReplyTo,
+		Kind:            kind,
+		OriginalChirpID: originalID,
 	}
 
-	chirpData, err := ApiCfg.Queries.CreateChirp(request.Context(), chirpParams)
+	chirpData, err := createChirpWithinRate(request.Context(), chirpParams, limits)
+	if errors.Is(err, errChirpRateLimited) {
+		respondWithJsonError(writer, "You have reached the hourly chirp limit of your plan", 429)
+		return
+	}
 	if err != nil {
 		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
 
-	respondWithJson(writer, 201, makeChirpMap(chirpData))
+	flagChirp(request.Context(), chirpData.ID, censored.Flagged, filters)
+	tagChirp(request.Context(), chirpData)
+	mentionChirp(request.Context(), chirpData, nil)
+	publishWebhookEvent(request.Context(), webhookEventChirpCreated, chirpData.UserID, makeChirpMap(chirpData))
+
+	respondWithChirp(writer, request, 201, chirpData)
 	request.Body.Close()
 }
 
 func getChirps(writer http.ResponseWriter, request *http.Request) {
-	authorID := request.URL.Query().Get("author_id")
-	sortReq := request.URL.Query().Get("sort")
+	query := request.URL.Query()
+	authorID := query.Get("author_id")
+
+	page, err := parsePageRequest(query.Get("limit"), query.Get("cursor"), query.Get("sort"))
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return
+	}
+
+	// fetch one extra row to find out whether there is a next page
+	rowLimit := page.Limit + 1
 	var chirps []database.Chirp
-	var err error
 
 	if authorID == "" {
-		chirps, err = ApiCfg.Queries.GetChirps(request.Context())
+		if page.Desc {
+			chirps, err = ApiCfg.Store.ListChirpsDesc(request.Context(), database.ListChirpsDescParams{
+				CursorCreatedAt: page.Cursor.CreatedAt,
+				CursorID:        page.Cursor.ID,
+				RowLimit:        rowLimit,
+			})
+		} else {
+			chirps, err = ApiCfg.Store.ListChirpsAsc(request.Context(), database.ListChirpsAscParams{
+				CursorCreatedAt: page.Cursor.CreatedAt,
+				CursorID:        page.Cursor.ID,
+				RowLimit:        rowLimit,
+			})
+		}
 	} else {
 		authorUUID, errParse := uuid.Parse(authorID)
 		if errParse != nil {
@@ -83,7 +178,21 @@ func getChirps(writer http.ResponseWriter, request *http.Request) {
 			return
 		}
 
-		chirps, err = ApiCfg.Queries.GetChirpsByUser(request.Context(), authorUUID)
+		if page.Desc {
+			chirps, err = ApiCfg.Store.ListChirpsByUserDesc(request.Context(), database.ListChirpsByUserDescParams{
+				UserID:          authorUUID,
+				CursorCreatedAt: page.Cursor.CreatedAt,
+				CursorID:        page.Cursor.ID,
+				RowLimit:        rowLimit,
+			})
+		} else {
+			chirps, err = ApiCfg.Store.ListChirpsByUserAsc(request.Context(), database.ListChirpsByUserAscParams{
+				UserID:          authorUUID,
+				CursorCreatedAt: page.Cursor.CreatedAt,
+				CursorID:        page.Cursor.ID,
+				RowLimit:        rowLimit,
+			})
+		}
 	}
 
 	if err != nil {
@@ -91,12 +200,55 @@ func getChirps(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	if sortReq == "desc" {
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
-		})
+	// clients that don't ask for a limit get the bare array this endpoint
+	// has always returned
+	if query.Get("limit") == "" {
+		respondWithChirpArray(writer, request, chirps, page.Limit)
+		return
 	}
-	respondWithJson(writer, 200, makeChirpsSlice(chirps))
+	respondWithChirpPage(writer, request, chirps, page.Limit)
+}
+
+// respondWithChirpArray is respondWithChirpPage for clients that expect a
+// bare array. The following page is linked from the Link header; the link
+// has no limit either, so following it keeps the same shape.
+func respondWithChirpArray(writer http.ResponseWriter, request *http.Request, chirps []database.Chirp, limit int32) {
+	chirps, next := trimPage(chirps, limit, chirpCursor)
+	chirpsSlice, err := makeChirpsSlice(request, chirps)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	if next != nil {
+		nextURL := *request.URL
+		query := nextURL.Query()
+		query.Set("cursor", next.(string))
+		nextURL.RawQuery = query.Encode()
+		writer.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", nextURL.RequestURI()))
+	}
+
+	respondWithJson(writer, 200, chirpsSlice)
+}
+
+// respondWithChirpPage writes a page of chirps fetched with one extra row
+// along with the cursor for the following page.
+func respondWithChirpPage(writer http.ResponseWriter, request *http.Request, chirps []database.Chirp, limit int32) {
+	chirps, next := trimPage(chirps, limit, chirpCursor)
+	chirpsSlice, err := makeChirpsSlice(request, chirps)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{
+		"chirps":      chirpsSlice,
+		"next_cursor": next,
+	})
+}
+
+func chirpCursor(chirp database.Chirp) pageCursor {
+	return pageCursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
 }
 
 func getChirp(writer http.ResponseWriter, request *http.Request) {
@@ -107,43 +259,158 @@ func getChirp(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	chirp, err := ApiCfg.Queries.GetChirp(request.Context(), chirpID)
-	if err != nil {
+	chirp, err := ApiCfg.Store.GetChirp(request.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
 		respondWithJsonError(writer, "Chirp not found", 404)
 		return
 	}
 
-	respondWithJson(writer, 200, makeChirpMap(chirp))
+	respondWithChirp(writer, request, 200, chirp)
+}
+
+// makeChirpsSlice renders chirps for a response. Like counts and mentions
+// are loaded in one batch, liked_by_me is included when the request is
+// authenticated, and rechirps and quotes embed the chirp they point at.
+func makeChirpsSlice(request *http.Request, chirps []database.Chirp) ([]map[string]interface{}, error) {
+	return renderChirps(request, chirps, true)
 }
 
-func censorChirp(body string) string {
-	wordList := strings.Fields(body)
-	bannedWords := []string{"kerfuffle", "sharbert", "fornax"}
-	for i, word := range wordList {
-		if slices.Contains(bannedWords, strings.ToLower(word)) {
-			wordList[i] = "****"
+func renderChirps(request *http.Request, chirps []database.Chirp, embedOriginals bool) ([]map[string]interface{}, error) {
+	chirpsSlice := []map[string]interface{}{}
+	if len(chirps) == 0 {
+		return chirpsSlice, nil
+	}
+
+	chirpIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	counts, err := ApiCfg.Store.CountChirpLikes(request.Context(), chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	likeCounts := map[uuid.UUID]int64{}
+	for _, count := range counts {
+		likeCounts[count.ChirpID] = count.LikeCount
+	}
+
+	viewerID := optionalUserID(request)
+	likedByViewer := map[uuid.UUID]bool{}
+	if viewerID.Valid {
+		likedIDs, err := ApiCfg.Store.ListLikedChirpIDs(request.Context(), database.ListLikedChirpIDsParams{
+			UserID:   viewerID.UUID,
+			ChirpIds: chirpIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, chirpID := range likedIDs {
+			likedByViewer[chirpID] = true
+		}
+	}
+
+	chirpEntities, err := loadChirpEntities(request, chirps, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	originals := map[uuid.UUID]map[string]interface{}{}
+	if embedOriginals {
+		originals, err = renderOriginals(request, chirps)
+		if err != nil {
+			return nil, err
 		}
 	}
-	return strings.Join(wordList, " ")
-}
 
-func makeChirpsSlice(chirps []database.Chirp) []map[string]string {
-	chirpsSlice := []map[string]string{}
+	for _, chirp := range chirps {
+		chirpMap := makeChirpMap(chirp)
+		chirpMap["like_count"] = likeCounts[chirp.ID]
+		chirpMap["entities"] = chirpEntities[chirp.ID]
+		if viewerID.Valid {
+			chirpMap["liked_by_me"] = likedByViewer[chirp.ID]
+		}
+		if embedOriginals && chirp.OriginalChirpID.Valid {
+			chirpMap["original_chirp"] = originals[chirp.OriginalChirpID.UUID]
+		}
+		chirpsSlice = append(chirpsSlice, chirpMap)
+	}
+
+	return chirpsSlice, nil
+}
 
+// renderOriginals loads and renders the chirps that rechirps and quotes in
+// chirps point at, keyed by ID. Deleted originals come back as tombstones.
+func renderOriginals(request *http.Request, chirps []database.Chirp) (map[uuid.UUID]map[string]interface{}, error) {
+	originalIDs := []uuid.UUID{}
 	for _, chirp := range chirps {
-		chirpsSlice = append(chirpsSlice, makeChirpMap(chirp))
+		if chirp.OriginalChirpID.Valid {
+			originalIDs = append(originalIDs, chirp.OriginalChirpID.UUID)
+		}
+	}
+
+	rendered := map[uuid.UUID]map[string]interface{}{}
+	if len(originalIDs) == 0 {
+		return rendered, nil
+	}
+
+	originals, err := ApiCfg.Store.ListChirpsByIDs(request.Context(), originalIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	return chirpsSlice
+	originalsSlice, err := renderChirps(request, originals, false)
+	if err != nil {
+		return nil, err
+	}
+	for i, original := range originals {
+		rendered[original.ID] = originalsSlice[i]
+	}
+	return rendered, nil
 }
 
-func makeChirpMap(chirp database.Chirp) map[string]string {
-	chirpMap := map[string]string{
-		"id":         chirp.ID.String(),
-		"created_at": chirp.CreatedAt.String(),
-		"updated_at": chirp.UpdatedAt.String(),
-		"body":       chirp.Body,
-		"user_id":    chirp.UserID.String(),
+// getOriginalChirp returns the chirp with the given ID, following a rechirp
+// through to the chirp it reposts.
+func getOriginalChirp(ctx context.Context, chirpID uuid.UUID) (database.Chirp, error) {
+	chirp, err := ApiCfg.Store.GetChirp(ctx, chirpID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if chirp.Kind == chirpKindRechirp && chirp.OriginalChirpID.Valid {
+		return ApiCfg.Store.GetChirp(ctx, chirp.OriginalChirpID.UUID)
+	}
+	return chirp, nil
+}
+
+// respondWithChirp renders a single chirp the same way makeChirpsSlice does.
+func respondWithChirp(writer http.ResponseWriter, request *http.Request, status int, chirp database.Chirp) {
+	chirps, err := makeChirpsSlice(request, []database.Chirp{chirp})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	respondWithJson(writer, status, chirps[0])
+}
+
+func makeChirpMap(chirp database.Chirp) map[string]interface{} {
+	chirpMap := map[string]interface{}{
+		"id":                chirp.ID.String(),
+		"created_at":        chirp.CreatedAt.String(),
+		"updated_at":        chirp.UpdatedAt.String(),
+		"body":              chirp.Body,
+		"user_id":           chirp.UserID.String(),
+		"in_reply_to":       nil,
+		"deleted":           chirp.DeletedAt.Valid,
+		"kind":              chirp.Kind,
+		"original_chirp_id": nil,
+	}
+
+	if chirp.InReplyTo.Valid {
+		chirpMap["in_reply_to"] = chirp.InReplyTo.UUID.String()
+	}
+	if chirp.OriginalChirpID.Valid {
+		chirpMap["original_chirp_id"] = chirp.OriginalChirpID.UUID.String()
 	}
 
 	return chirpMap
@@ -160,23 +427,16 @@ func deleteChirp(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	// get the token from the header
-	authorization, err := auth.GetBearerToken(request.Header)
-	if err != nil {
-		respondWithJsonError(writer, "Unauthorized", 401)
-		return
-	}
-
-	// verify the token
-	userID, err := auth.ValidateJWT(authorization, ApiCfg.Secret)
+	// authenticate the user
+	userID, err := authenticatedUserID(request)
 	if err != nil {
 		respondWithJsonError(writer, "Unauthorized", 401)
 		return
 	}
 
 	// get the chirp
-	chirp, err := ApiCfg.Queries.GetChirp(request.Context(), chirpID)
-	if err != nil {
+	chirp, err := ApiCfg.Store.GetChirp(request.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
 		respondWithJsonError(writer, "Chirp not found", 404)
 		return
 	}
@@ -187,7 +447,116 @@ func deleteChirp(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	// delete the chirp
-	ApiCfg.Queries.DeleteChirp(request.Context(), chirpID)
+	// delete the chirp, leaving a tombstone so replies keep their place in the thread
+	ApiCfg.Store.DeleteChirp(request.Context(), chirpID)
+	publishChirpDeleted(request.Context(), chirp)
 	respondWithJson(writer, 204, "Chirp deleted")
 }
+
+// editClock is replaced in tests to move past the edit window.
+var editClock = time.Now
+
+// editChirp replaces the body of a chirp, for users whose plan allows it and
+// only for a while after posting. Hashtags and mentions are extracted again;
+// users mentioned before the edit aren't notified a second time.
+func editChirp(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		respondWithJsonError(writer, "Chirp not found", 404)
+		return
+	}
+
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	existing, err := ApiCfg.Store.GetChirp(request.Context(), chirpID)
+	if err != nil || existing.DeletedAt.Valid {
+		respondWithJsonError(writer, "Chirp not found", 404)
+		return
+	}
+	if existing.UserID != userID {
+		respondWithJsonError(writer, "Forbidden", 403)
+		return
+	}
+	if existing.Kind == chirpKindRechirp {
+		respondWithJsonError(writer, "Rechirps cannot be edited", 400)
+		return
+	}
+
+	limits, ok := requireEntitlements(writer, request, userID)
+	if !ok {
+		return
+	}
+	if !limits.CanEdit() {
+		respondWithJsonError(writer, "Your plan does not include editing chirps", 403)
+		return
+	}
+	if editClock().Sub(existing.CreatedAt) > time.Duration(limits.EditWindowMinutes)*time.Minute {
+		respondWithJsonError(writer, fmt.Sprintf("Chirps can only be edited for %d minutes after posting", limits.EditWindowMinutes), 403)
+		return
+	}
+
+	edit := chirp{}
+	if err := json.NewDecoder(request.Body).Decode(&edit); err != nil {
+		respondWithJsonError(writer, "Invalid request body", 400)
+		return
+	}
+	if len(edit.Body) > limits.MaxChirpLength {
+		respondWithJsonError(writer, fmt.Sprintf("Chirp is too long, the limit is %d characters", limits.MaxChirpLength), 400)
+		return
+	}
+	if existing.Kind == chirpKindQuote && strings.TrimSpace(edit.Body) == "" {
+		respondWithJsonError(writer, "Quotes need a body", 400)
+		return
+	}
+
+	// run the banned term filters
+	filters, err := ApiCfg.Store.ListChirpFilters(request.Context())
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	censored := censorChirp(edit.Body, filters)
+	if censored.Rejected != nil {
+		respondWithJsonError(writer, "Chirp contains a banned word", 400)
+		return
+	}
+
+	// remember who was already notified before the mentions are replaced
+	previous, err := ApiCfg.Store.ListChirpMentions(request.Context(), []uuid.UUID{chirpID})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	notified := map[uuid.UUID]bool{}
+	for _, mention := range previous {
+		notified[mention.UserID] = true
+	}
+
+	updated, err := ApiCfg.Store.UpdateChirpBody(request.Context(), database.UpdateChirpBodyParams{
+		ID:   chirpID,
+		Body: censored.Body,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	flagChirp(request.Context(), updated.ID, censored.Flagged, filters)
+	if err := ApiCfg.Store.DeleteChirpTags(request.Context(), updated.ID); err != nil {
+		log.Printf("unable to clear tags of chirp %s: %s", updated.ID, err)
+	}
+	tagChirp(request.Context(), updated)
+	if err := ApiCfg.Store.DeleteChirpMentions(request.Context(), updated.ID); err != nil {
+		log.Printf("unable to clear mentions of chirp %s: %s", updated.ID, err)
+	}
+	mentionChirp(request.Context(), updated, notified)
+	publishWebhookEvent(request.Context(), webhookEventChirpUpdated, updated.UserID, makeChirpMap(updated))
+
+	respondWithChirp(writer, request, 200, updated)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/mail"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	maxEmailLength       = 254
+)
+
+// normalizeEmail trims email and reports whether it is a bare address, not
+// one with a display name or angle brackets.
+func normalizeEmail(email string) (string, bool) {
+	email = strings.TrimSpace(email)
+	if email == "" || len(email) > maxEmailLength {
+		return "", false
+	}
+	address, err := netmail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email {
+		return "", false
+	}
+	return email, true
+}
+
+// sendEmailVerification mails a link that verifies email for user. If email
+// isn't the user's current address, following the link changes it. Any
+// earlier link stops working.
+func sendEmailVerification(ctx context.Context, user database.User, email string) error {
+	token, err := auth.MakeOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	if err := ApiCfg.Store.InvalidateEmailVerificationTokens(ctx, user.ID); err != nil {
+		return err
+	}
+	err = ApiCfg.Store.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().UTC().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := ApiCfg.BaseURL + "/api/email/verify?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Welcome to Chirpy! Follow this link to verify your email address:\n\n    %s\n\n"+
+			"The link works for 24 hours.\n", link),
+	}
+	if email != user.Email {
+		msg.Subject = "Confirm your new Chirpy email address"
+		msg.Body = fmt.Sprintf("Someone asked to change the email address of the Chirpy account @%s to this one. "+
+			"Follow this link to confirm:\n\n    %s\n\n"+
+			"The link works for 24 hours. If you didn't ask for this you can ignore this email.\n", user.Handle, link)
+	}
+	return ApiCfg.Mailer.Send(ctx, msg)
+}
+
+// requireVerifiedEmail responds with 403 unless the user has verified their
+// email address.
+func requireVerifiedEmail(writer http.ResponseWriter, request *http.Request, userID uuid.UUID) bool {
+	user, err := ApiCfg.Store.GetUser(request.Context(), userID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return false
+	}
+	if !user.EmailVerifiedAt.Valid {
+		respondWithJsonError(writer, "Verify your email address first", 403)
+		return false
+	}
+	return true
+}
+
+// verifyEmail is the target of the link in verification emails, so it is
+// a GET with the token in the query.
+func verifyEmail(writer http.ResponseWriter, request *http.Request) {
+	token := request.URL.Query().Get("token")
+	if token == "" {
+		respondWithJsonError(writer, "Invalid or expired verification token", 400)
+		return
+	}
+
+	verification, err := ApiCfg.Store.UseEmailVerificationToken(request.Context(), auth.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Invalid or expired verification token", 400)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// someone else may have claimed the address since the link was sent
+	other, err := ApiCfg.Store.GetUserByEmail(request.Context(), verification.Email)
+	if err == nil && other.ID != verification.UserID {
+		respondWithJsonError(writer, "Email is already in use", 409)
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	previous, err := ApiCfg.Store.GetUser(request.Context(), verification.UserID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	user, err := ApiCfg.Store.SetVerifiedEmail(request.Context(), database.SetVerifiedEmailParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if previous.Email != user.Email {
+		logSecurityEvent("email_changed", user.ID, "from", previous.Email, "to", user.Email)
+	}
+
+	publishUserUpdated(request.Context(), user)
+	respondWithJson(writer, 200, makeUserMap(request.Context(), user))
+}
+
+func resendEmailVerification(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	user, err := ApiCfg.Store.GetUser(request.Context(), userID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respondWithJsonError(writer, "Email is already verified", 409)
+		return
+	}
+
+	if err := sendEmailVerification(request.Context(), user, user.Email); err != nil {
+		log.Printf("unable to send verification email to %s: %s", user.ID, err)
+		respondWithJsonError(writer, "Unable to send verification email", 500)
+		return
+	}
+
+	writer.WriteHeader(202)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEmailVerification(t *testing.T) {
+	mux := newTestMux(t)
+
+	for _, email := range []string{"", "walt", "Walt <walt@breakingbad.com>", "walt@breakingbad.com\nBcc: x@y.z"} {
+		if rec := doRequest(t, mux, "POST", "/api/users", "", userRequest{Email: email, Password: "hunter2"}); rec.Code != 400 {
+			t.Errorf("create user with email %q: expected 400, got %d", email, rec.Code)
+		}
+	}
+
+	credentials := userRequest{Email: "walt@breakingbad.com", Password: "hunter2"}
+	rec := doRequest(t, mux, "POST", "/api/users", "", credentials)
+	if rec.Code != 201 {
+		t.Fatalf("create user: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	created := map[string]interface{}{}
+	decodeResponse(t, rec, &created)
+	if created["email_verified"] != false {
+		t.Errorf("expected a new user to be unverified, got %v", created["email_verified"])
+	}
+	first := verificationToken(t, "walt@breakingbad.com")
+
+	login := map[string]interface{}{}
+	decodeResponse(t, doRequest(t, mux, "POST", "/api/login", "", credentials), &login)
+	token := login["token"].(string)
+
+	if rec := doRequest(t, mux, "POST", "/api/chirps", token, map[string]string{"body": "unverified"}); rec.Code != 403 {
+		t.Errorf("chirp before verifying: expected 403, got %d", rec.Code)
+	}
+
+	// resending replaces the earlier link
+	if rec := doRequest(t, mux, "POST", "/api/email/verify/resend", token, nil); rec.Code != 202 {
+		t.Fatalf("resend: status %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "GET", "/api/email/verify?token="+first, "", nil); rec.Code != 400 {
+		t.Errorf("superseded link: expected 400, got %d", rec.Code)
+	}
+
+	verifyTestEmail(t, mux, "walt@breakingbad.com")
+	if rec := doRequest(t, mux, "POST", "/api/chirps", token, map[string]string{"body": "verified"}); rec.Code != 201 {
+		t.Errorf("chirp after verifying: expected 201, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/email/verify/resend", token, nil); rec.Code != 409 {
+		t.Errorf("resend when verified: expected 409, got %d", rec.Code)
+	}
+}
+
+func TestEmailChangeConfirmation(t *testing.T) {
+	mux := newTestMux(t)
+	token := createTestUser(t, mux, "walt@breakingbad.com")["token"].(string)
+	createTestUser(t, mux, "jesse@breakingbad.com")
+
+	if rec := doRequest(t, mux, "PUT", "/api/users", token, putUserRequest{Email: "jesse@breakingbad.com", Password: "hunter2", CurrentPassword: "hunter2"}); rec.Code != 409 {
+		t.Errorf("change to a taken email: expected 409, got %d", rec.Code)
+	}
+
+	rec := doRequest(t, mux, "PUT", "/api/users", token, putUserRequest{Email: "heisenberg@breakingbad.com", Password: "hunter2", CurrentPassword: "hunter2"})
+	if rec.Code != 200 {
+		t.Fatalf("update user: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	updated := map[string]interface{}{}
+	decodeResponse(t, rec, &updated)
+	if updated["email"] != "walt@breakingbad.com" || updated["pending_email"] != "heisenberg@breakingbad.com" {
+		t.Errorf("expected the change to be pending, got %v", updated)
+	}
+
+	// the old address keeps working until the new one is confirmed
+	if rec := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "heisenberg@breakingbad.com", Password: "hunter2"}); rec.Code != 401 {
+		t.Errorf("login with unconfirmed email: expected 401, got %d", rec.Code)
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/email/verify?token="+verificationToken(t, "heisenberg@breakingbad.com"), "", nil)
+	if rec.Code != 200 {
+		t.Fatalf("confirm email change: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	confirmed := map[string]interface{}{}
+	decodeResponse(t, rec, &confirmed)
+	if confirmed["email"] != "heisenberg@breakingbad.com" || confirmed["email_verified"] != true {
+		t.Errorf("expected the new email to be verified, got %v", confirmed)
+	}
+
+	if rec := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "heisenberg@breakingbad.com", Password: "hunter2"}); rec.Code != 200 {
+		t.Errorf("login with confirmed email: expected 200, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/plans"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// planFor returns the plan user is on: their subscription's plan while they
+// have Chirpy Red, the free plan otherwise. subscription may be nil.
+func planFor(user database.User, subscription *database.Subscription) string {
+	if !user.IsChirpyRed {
+		return plans.Free
+	}
+	if subscription != nil {
+		return subscription.Plan
+	}
+	return chirpyRedPlan
+}
+
+// entitlementsFor returns the limits of the plan the user is on.
+func entitlementsFor(ctx context.Context, user database.User) (plans.Limits, error) {
+	if !user.IsChirpyRed {
+		return ApiCfg.Plans.Limits(plans.Free), nil
+	}
+
+	subscription, err := ApiCfg.Store.GetSubscription(ctx, user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ApiCfg.Plans.Limits(planFor(user, nil)), nil
+	}
+	if err != nil {
+		return plans.Limits{}, err
+	}
+	return ApiCfg.Plans.Limits(planFor(user, &subscription)), nil
+}
+
+// requireEntitlements loads the limits of the user's plan, writing the error
+// response itself if it can't.
+func requireEntitlements(writer http.ResponseWriter, request *http.Request, userID uuid.UUID) (plans.Limits, bool) {
+	user, err := ApiCfg.Store.GetUser(request.Context(), userID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return plans.Limits{}, false
+	}
+
+	limits, err := entitlementsFor(request.Context(), user)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return plans.Limits{}, false
+	}
+	return limits, true
+}
+
+// errChirpRateLimited means the user has already posted as many chirps in
+// the last hour as their plan allows.
+var errChirpRateLimited = errors.New("hourly chirp limit reached")
+
+// createChirpWithinRate inserts a chirp, reply or quote unless the plan's
+// hourly limit has been reached. The check is part of the insert, which
+// takes a lock on the user's posting times, so concurrent posts can't go
+// over the limit.
+func createChirpWithinRate(ctx context.Context, params database.CreateChirpParams, limits plans.Limits) (database.Chirp, error) {
+	if limits.ChirpsPerHour == 0 {
+		return ApiCfg.Store.CreateChirp(ctx, params)
+	}
+
+	chirp, err := ApiCfg.Store.CreateChirpWithinRate(ctx, database.CreateChirpWithinRateParams{
+		UserID:          params.UserID,
+		ChirpsPerHour:   int32(limits.ChirpsPerHour),
+		Body:            params.Body,
+		InReplyTo:       params.InReplyTo,
+		Kind:            params.Kind,
+		OriginalChirpID: params.OriginalChirpID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Chirp{}, errChirpRateLimited
+	}
+	return chirp, err
+}
+
+func makeEntitlementsMap(plan string, limits plans.Limits) map[string]interface{} {
+	return map[string]interface{}{
+		"plan":                plan,
+		"max_chirp_length":    limits.MaxChirpLength,
+		"edit_window_minutes": limits.EditWindowMinutes,
+		"chirps_per_hour":     limits.ChirpsPerHour,
+	}
+}
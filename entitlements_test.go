@@ -0,0 +1,135 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/plans"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// upgradeTestUser gives the user Chirpy Red through a signed Polka webhook.
+func upgradeTestUser(t *testing.T, mux *http.ServeMux, userID string) {
+	t.Helper()
+	body := []byte(`{"id":"upgrade-` + userID + `","event":"user.upgraded","data":{"user_id":"` + userID + `"}}`)
+	if rec := sendPolkaWebhook(t, mux, auth.SignWebhook("test-polka-secret", time.Now(), body), body); rec.Code != 204 {
+		t.Fatalf("upgrade: expected 204, got %d", rec.Code)
+	}
+}
+
+func TestChirpLengthEntitlement(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	token := user["token"].(string)
+	long := strings.Repeat("a", 200)
+
+	entitlements := user["entitlements"].(map[string]interface{})
+	if entitlements["plan"] != "free" || entitlements["max_chirp_length"] != float64(140) {
+		t.Errorf("unexpected free entitlements: %v", entitlements)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/chirps", token, map[string]string{"body": long}); rec.Code != 400 {
+		t.Errorf("long chirp on the free plan: expected 400, got %d", rec.Code)
+	}
+
+	upgradeTestUser(t, mux, user["id"].(string))
+	if rec := doRequest(t, mux, "POST", "/api/chirps", token, map[string]string{"body": long}); rec.Code != 201 {
+		t.Errorf("long chirp on chirpy red: expected 201, got %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, mux, "POST", "/api/chirps", token, map[string]string{"body": strings.Repeat("a", 281)}); rec.Code != 400 {
+		t.Errorf("chirp over the chirpy red limit: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestChirpRateEntitlement(t *testing.T) {
+	mux := newTestMux(t)
+	path := filepath.Join(t.TempDir(), "plans.json")
+	if err := os.WriteFile(path, []byte(`{"plans": {"free": {"max_chirp_length": 140, "chirps_per_hour": 2}}}`), 0o600); err != nil {
+		t.Fatalf("unable to write plan file: %v", err)
+	}
+	config, err := plans.Load(path)
+	if err != nil {
+		t.Fatalf("load plans: %v", err)
+	}
+	ApiCfg.Plans = config
+
+	token := createTestUser(t, mux, "walt@breakingbad.com")["token"].(string)
+	for i, want := range []int{201, 201, 429} {
+		if rec := doRequest(t, mux, "POST", "/api/chirps", token, map[string]string{"body": "Say my name"}); rec.Code != want {
+			t.Errorf("chirp %d: expected %d, got %d", i+1, want, rec.Code)
+		}
+	}
+
+	// a burst of posts can't get past the limit either
+	token = createTestUser(t, mux, "jesse@breakingbad.com")["token"].(string)
+	codes := make(chan int, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- doRequest(t, mux, "POST", "/api/chirps", token, map[string]string{"body": "Yeah science"}).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	posted := 0
+	for code := range codes {
+		if code == 201 {
+			posted++
+		} else if code != 429 {
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if posted != 2 {
+		t.Errorf("expected 2 chirps to be posted, got %d", posted)
+	}
+}
+
+func TestEditChirp(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUserWithHandle(t, mux, "walt@breakingbad.com", "heisenberg")
+	jesse := createTestUserWithHandle(t, mux, "jesse@breakingbad.com", "cap_n_cook")
+	waltToken := walt["token"].(string)
+
+	chirp := postChirp(t, mux, waltToken, map[string]interface{}{"body": "Cooking with @cap_n_cook #blue"})
+	path := "/api/chirps/" + chirp["id"].(string)
+
+	if rec := doRequest(t, mux, "PUT", path, waltToken, map[string]string{"body": "Cooking"}); rec.Code != 403 {
+		t.Errorf("edit on the free plan: expected 403, got %d", rec.Code)
+	}
+
+	upgradeTestUser(t, mux, walt["id"].(string))
+	if rec := doRequest(t, mux, "PUT", path, jesse["token"].(string), map[string]string{"body": "Cooking"}); rec.Code != 403 {
+		t.Errorf("edit of someone else's chirp: expected 403, got %d", rec.Code)
+	}
+
+	rec := doRequest(t, mux, "PUT", path, waltToken, map[string]string{"body": "Still cooking with @cap_n_cook #crystal"})
+	if rec.Code != 200 {
+		t.Fatalf("edit: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	edited := map[string]interface{}{}
+	decodeResponse(t, rec, &edited)
+	hashtags := edited["entities"].(map[string]interface{})["hashtags"].([]interface{})
+	if edited["body"] != "Still cooking with @cap_n_cook #crystal" || len(hashtags) != 1 || hashtags[0].(map[string]interface{})["tag"] != "crystal" {
+		t.Errorf("unexpected edited chirp: %v", edited)
+	}
+
+	// jesse was mentioned before the edit and isn't notified again
+	page := notificationPage{}
+	decodeResponse(t, doRequest(t, mux, "GET", "/api/notifications", jesse["token"].(string), nil), &page)
+	if len(page.Notifications) != 1 {
+		t.Errorf("expected one mention notification, got %d", len(page.Notifications))
+	}
+
+	// chirps can't be edited once the window has passed
+	editClock = func() time.Time { return time.Now().Add(31 * time.Minute) }
+	defer func() { editClock = time.Now }()
+	if rec := doRequest(t, mux, "PUT", path, waltToken, map[string]string{"body": "Too late"}); rec.Code != 403 {
+		t.Errorf("edit after the window: expected 403, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/filter"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type filterRequest struct {
+	Term   string `json:"term"`
+	Action string `json:"action"`
+}
+
+// censorChirp runs the admin-managed filters over a chirp body.
+func censorChirp(body string, filters []database.ChirpFilter) filter.Result {
+	rules := []filter.Rule{}
+	for _, chirpFilter := range filters {
+		rules = append(rules, filter.Rule{
+			Term:   chirpFilter.Term,
+			Action: filter.Action(chirpFilter.Action),
+		})
+	}
+
+	return filter.Apply(body, rules)
+}
+
+// flagChirp records a review flag for every flagged term found in a chirp.
+// The chirp has already been posted, so failures are logged, not returned.
+func flagChirp(ctx context.Context, chirpID uuid.UUID, flagged []filter.Rule, filters []database.ChirpFilter) {
+	for _, rule := range flagged {
+		params := database.CreateChirpFlagParams{
+			ChirpID: chirpID,
+			Term:    rule.Term,
+		}
+		for _, chirpFilter := range filters {
+			if chirpFilter.Term == rule.Term {
+				params.FilterID = uuid.NullUUID{UUID: chirpFilter.ID, Valid: true}
+			}
+		}
+
+		if err := ApiCfg.Store.CreateChirpFlag(ctx, params); err != nil {
+			log.Printf("unable to flag chirp %s for %q: %s", chirpID, rule.Term, err)
+		}
+	}
+}
+
+func getFilters(writer http.ResponseWriter, request *http.Request) {
+	if !isAdmin(request) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	filters, err := ApiCfg.Store.ListChirpFilters(request.Context())
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	filtersSlice := []map[string]string{}
+	for _, chirpFilter := range filters {
+		filtersSlice = append(filtersSlice, makeFilterMap(chirpFilter))
+	}
+	respondWithJson(writer, 200, filtersSlice)
+}
+
+func createFilter(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+	if !isAdmin(request) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	filterReq, ok := readFilterRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	chirpFilter, err := ApiCfg.Store.CreateChirpFilter(request.Context(), database.CreateChirpFilterParams{
+		Term:   filterReq.Term,
+		Action: filterReq.Action,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Unable to create filter", 409)
+		return
+	}
+
+	respondWithJson(writer, 201, makeFilterMap(chirpFilter))
+}
+
+func updateFilter(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+	if !isAdmin(request) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	filterID, err := uuid.Parse(request.PathValue("filterID"))
+	if err != nil {
+		respondWithJsonError(writer, "Filter not found", 404)
+		return
+	}
+
+	filterReq, ok := readFilterRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if _, err := ApiCfg.Store.GetChirpFilter(request.Context(), filterID); err != nil {
+		respondWithJsonError(writer, "Filter not found", 404)
+		return
+	}
+
+	chirpFilter, err := ApiCfg.Store.UpdateChirpFilter(request.Context(), database.UpdateChirpFilterParams{
+		Term:   filterReq.Term,
+		Action: filterReq.Action,
+		ID:     filterID,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Unable to update filter", 409)
+		return
+	}
+
+	respondWithJson(writer, 200, makeFilterMap(chirpFilter))
+}
+
+func deleteFilter(writer http.ResponseWriter, request *http.Request) {
+	if !isAdmin(request) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	filterID, err := uuid.Parse(request.PathValue("filterID"))
+	if err != nil {
+		respondWithJsonError(writer, "Filter not found", 404)
+		return
+	}
+
+	deleted, err := ApiCfg.Store.DeleteChirpFilter(request.Context(), filterID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if deleted == 0 {
+		respondWithJsonError(writer, "Filter not found", 404)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
+
+// readFilterRequest decodes and validates the body of a filter create or
+// update, writing the error response itself when it is invalid.
+func readFilterRequest(writer http.ResponseWriter, request *http.Request) (filterRequest, bool) {
+	filterReq := filterRequest{}
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return filterRequest{}, false
+	}
+
+	if err := json.Unmarshal(body, &filterReq); err != nil {
+		respondWithJsonError(writer, "Invalid request body", 400)
+		return filterRequest{}, false
+	}
+
+	term, err := filter.NormalizeTerm(filterReq.Term)
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return filterRequest{}, false
+	}
+	filterReq.Term = term
+
+	if filterReq.Action == "" {
+		filterReq.Action = string(filter.ActionMask)
+	}
+	if !filter.Action(filterReq.Action).Valid() {
+		respondWithJsonError(writer, "action must be mask, reject or flag", 400)
+		return filterRequest{}, false
+	}
+
+	return filterReq, true
+}
+
+func makeFilterMap(chirpFilter database.ChirpFilter) map[string]string {
+	return map[string]string{
+		"id":         chirpFilter.ID.String(),
+		"created_at": chirpFilter.CreatedAt.String(),
+		"updated_at": chirpFilter.UpdatedAt.String(),
+		"term":       chirpFilter.Term,
+		"action":     chirpFilter.Action,
+	}
+}
+
+func getFlags(writer http.ResponseWriter, request *http.Request) {
+	if !isAdmin(request) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	flags, err := ApiCfg.Store.ListUnresolvedChirpFlags(request.Context())
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	flagsSlice := []map[string]interface{}{}
+	for _, flag := range flags {
+		flagMap := map[string]interface{}{
+			"id":         flag.ID.String(),
+			"created_at": flag.CreatedAt.String(),
+			"chirp_id":   flag.ChirpID.String(),
+			"filter_id":  nil,
+			"term":       flag.Term,
+		}
+		if flag.FilterID.Valid {
+			flagMap["filter_id"] = flag.FilterID.UUID.String()
+		}
+		flagsSlice = append(flagsSlice, flagMap)
+	}
+	respondWithJson(writer, 200, flagsSlice)
+}
+
+func resolveFlag(writer http.ResponseWriter, request *http.Request) {
+	if !isAdmin(request) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	flagID, err := uuid.Parse(request.PathValue("flagID"))
+	if err != nil {
+		respondWithJsonError(writer, "Flag not found", 404)
+		return
+	}
+
+	resolved, err := ApiCfg.Store.ResolveChirpFlag(request.Context(), flagID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if resolved == 0 {
+		respondWithJsonError(writer, "Flag not found", 404)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestAdminFilters(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	token := user["token"].(string)
+
+	if rec := doRequest(t, mux, "GET", "/admin/filters", token, nil); rec.Code != 401 {
+		t.Errorf("filters without admin key: expected 401, got %d", rec.Code)
+	}
+
+	for _, filterReq := range []filterRequest{{Term: "Crikey", Action: "reject"}, {Term: "blimey", Action: "flag"}} {
+		if rec := doAdminRequest(t, mux, "POST", "/admin/filters", filterReq); rec.Code != 201 {
+			t.Fatalf("create filter: status %d, body %s", rec.Code, rec.Body.String())
+		}
+	}
+	if rec := doAdminRequest(t, mux, "POST", "/admin/filters", filterRequest{Term: "two words"}); rec.Code != 400 {
+		t.Errorf("multi-word term: expected 400, got %d", rec.Code)
+	}
+
+	if rec := doRequest(t, mux, "POST", "/api/chirps", token, map[string]string{"body": "CRIKEY!"}); rec.Code != 400 {
+		t.Errorf("rejected term: expected 400, got %d", rec.Code)
+	}
+
+	chirp := postChirp(t, mux, token, map[string]interface{}{"body": "Blimey,  what a  kerfuffle."})
+	if chirp["body"] != "Blimey,  what a  ****." {
+		t.Errorf("unexpected censored body %q", chirp["body"])
+	}
+
+	rec := doAdminRequest(t, mux, "GET", "/admin/flags", nil)
+	flags := []map[string]interface{}{}
+	decodeResponse(t, rec, &flags)
+	if len(flags) != 1 || flags[0]["chirp_id"] != chirp["id"] || flags[0]["term"] != "blimey" {
+		t.Fatalf("unexpected flags: %v", flags)
+	}
+
+	if rec := doAdminRequest(t, mux, "POST", "/admin/flags/"+flags[0]["id"].(string)+"/resolve", nil); rec.Code != 204 {
+		t.Errorf("resolve flag: expected 204, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func followUser(writer http.ResponseWriter, request *http.Request) {
+	followerID, followeeID, ok := followParams(writer, request)
+	if !ok {
+		return
+	}
+
+	params := database.FollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	}
+	if err := ApiCfg.Store.FollowUser(request.Context(), params); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
+
+func unfollowUser(writer http.ResponseWriter, request *http.Request) {
+	followerID, followeeID, ok := followParams(writer, request)
+	if !ok {
+		return
+	}
+
+	params := database.UnfollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	}
+	if err := ApiCfg.Store.UnfollowUser(request.Context(), params); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
+
+// followParams authenticates the follower and resolves the followee from
+// the URL. It writes the error response itself and reports whether the
+// handler should continue.
+func followParams(writer http.ResponseWriter, request *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	followerID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	followeeID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		respondWithJsonError(writer, "User not found", 404)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	if followerID == followeeID {
+		respondWithJsonError(writer, "You cannot follow yourself", 400)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	if _, err := ApiCfg.Store.GetUser(request.Context(), followeeID); err != nil {
+		respondWithJsonError(writer, "User not found", 404)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	return followerID, followeeID, true
+}
+
+func getFollowers(writer http.ResponseWriter, request *http.Request) {
+	userID, page, ok := userListParams(writer, request)
+	if !ok {
+		return
+	}
+
+	follows, err := ApiCfg.Store.ListFollowers(request.Context(), database.ListFollowersParams{
+		FolloweeID:      userID,
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		RowLimit:        page.Limit + 1,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	follows, next := trimPage(follows, page.Limit, func(follow database.Follow) pageCursor {
+		return pageCursor{CreatedAt: follow.CreatedAt, ID: follow.FollowerID}
+	})
+
+	users := []map[string]string{}
+	for _, follow := range follows {
+		users = append(users, makeFollowMap(follow.FollowerID, follow))
+	}
+	respondWithJson(writer, 200, map[string]interface{}{
+		"users":       users,
+		"next_cursor": next,
+	})
+}
+
+func getFollowing(writer http.ResponseWriter, request *http.Request) {
+	userID, page, ok := userListParams(writer, request)
+	if !ok {
+		return
+	}
+
+	follows, err := ApiCfg.Store.ListFollowing(request.Context(), database.ListFollowingParams{
+		FollowerID:      userID,
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		RowLimit:        page.Limit + 1,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	follows, next := trimPage(follows, page.Limit, func(follow database.Follow) pageCursor {
+		return pageCursor{CreatedAt: follow.CreatedAt, ID: follow.FolloweeID}
+	})
+
+	users := []map[string]string{}
+	for _, follow := range follows {
+		users = append(users, makeFollowMap(follow.FolloweeID, follow))
+	}
+	respondWithJson(writer, 200, map[string]interface{}{
+		"users":       users,
+		"next_cursor": next,
+	})
+}
+
+// userListParams resolves the user from the URL and the page from the
+// query string for per-user listings, which are always newest first.
+func userListParams(writer http.ResponseWriter, request *http.Request) (uuid.UUID, pageRequest, bool) {
+	userID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		respondWithJsonError(writer, "User not found", 404)
+		return uuid.UUID{}, pageRequest{}, false
+	}
+
+	query := request.URL.Query()
+	page, err := parsePageRequest(query.Get("limit"), query.Get("cursor"), "desc")
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return uuid.UUID{}, pageRequest{}, false
+	}
+
+	if _, err := ApiCfg.Store.GetUser(request.Context(), userID); err != nil {
+		respondWithJsonError(writer, "User not found", 404)
+		return uuid.UUID{}, pageRequest{}, false
+	}
+
+	return userID, page, true
+}
+
+func makeFollowMap(userID uuid.UUID, follow database.Follow) map[string]string {
+	return map[string]string{
+		"user_id":     userID.String(),
+		"followed_at": follow.CreatedAt.String(),
+	}
+}
+
+func getTimeline(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	query := request.URL.Query()
+	page, err := parsePageRequest(query.Get("limit"), query.Get("cursor"), "desc")
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return
+	}
+
+	chirps, err := ApiCfg.Store.ListTimeline(request.Context(), database.ListTimelineParams{
+		FollowerID:      userID,
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		RowLimit:        page.Limit + 1,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	respondWithChirpPage(writer, request, chirps, page.Limit)
+}
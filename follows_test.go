@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestFollowAndTimeline(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	jesse := createTestUser(t, mux, "jesse@breakingbad.com")
+	saul := createTestUser(t, mux, "saul@bettercall.com")
+	waltToken := walt["token"].(string)
+
+	for _, user := range []map[string]interface{}{jesse, saul} {
+		rec := doRequest(t, mux, "POST", "/api/chirps", user["token"].(string), map[string]string{"body": "hello"})
+		if rec.Code != 201 {
+			t.Fatalf("create chirp: status %d", rec.Code)
+		}
+	}
+
+	if rec := doRequest(t, mux, "POST", "/api/users/"+walt["id"].(string)+"/follow", waltToken, nil); rec.Code != 400 {
+		t.Errorf("self follow: expected 400, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/users/"+jesse["id"].(string)+"/follow", "", nil); rec.Code != 401 {
+		t.Errorf("anonymous follow: expected 401, got %d", rec.Code)
+	}
+	for i := 0; i < 2; i++ {
+		if rec := doRequest(t, mux, "POST", "/api/users/"+jesse["id"].(string)+"/follow", waltToken, nil); rec.Code != 204 {
+			t.Fatalf("follow: expected 204, got %d", rec.Code)
+		}
+	}
+
+	rec := doRequest(t, mux, "GET", "/api/timeline", waltToken, nil)
+	if rec.Code != 200 {
+		t.Fatalf("timeline: status %d", rec.Code)
+	}
+	page := chirpPage{}
+	decodeResponse(t, rec, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0]["user_id"] != jesse["id"] {
+		t.Errorf("timeline should only contain jesse's chirp, got %v", page.Chirps)
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/users/"+jesse["id"].(string)+"/followers", "", nil)
+	followers := struct {
+		Users []map[string]string `json:"users"`
+	}{}
+	decodeResponse(t, rec, &followers)
+	if len(followers.Users) != 1 || followers.Users[0]["user_id"] != walt["id"] {
+		t.Errorf("unexpected followers: %v", followers.Users)
+	}
+
+	if rec := doRequest(t, mux, "DELETE", "/api/users/"+jesse["id"].(string)+"/follow", waltToken, nil); rec.Code != 204 {
+		t.Fatalf("unfollow: expected 204, got %d", rec.Code)
+	}
+	rec = doRequest(t, mux, "GET", "/api/users/"+walt["id"].(string)+"/following", "", nil)
+	decodeResponse(t, rec, &followers)
+	if len(followers.Users) != 0 {
+		t.Errorf("expected no followees after unfollow, got %v", followers.Users)
+	}
+}
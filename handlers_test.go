@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"chirpy/internal/auth"
+	"chirpy/internal/mail"
+	"chirpy/internal/plans"
+	"chirpy/internal/storage"
+	"chirpy/internal/store"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestMux(t *testing.T) *http.ServeMux {
+	t.Helper()
+	ApiCfg.Store = store.NewMemory()
+	ApiCfg.Platform = "dev"
+	ApiCfg.Secret = "test-secret"
+	keys, err := auth.NewKeySet(ApiCfg.Secret, nil)
+	if err != nil {
+		t.Fatalf("unable to create key set: %v", err)
+	}
+	ApiCfg.Keys = keys
+	ApiCfg.PolkaWebhook = auth.NewWebhookVerifier([]string{"test-polka-secret"}, auth.DefaultWebhookTolerance)
+	ApiCfg.AdminKey = "test-admin-key"
+	mailer, err := mail.NewFileMailer(t.TempDir(), "chirpy@example.com")
+	if err != nil {
+		t.Fatalf("unable to create mailer: %v", err)
+	}
+	ApiCfg.Mailer = newTestMailer(mailer)
+	ApiCfg.Plans = plans.Default()
+	ApiCfg.Storage, err = storage.NewLocal(t.TempDir(), "http://localhost:8080/media")
+	if err != nil {
+		t.Fatalf("unable to create storage: %v", err)
+	}
+	trending = &trendingCache{}
+	webhookClient = newWebhookClient(true)
+
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+	return mux
+}
+
+// doRequest sends a request authenticated with token as a bearer token,
+// or an anonymous one if token is empty.
+func doRequest(t *testing.T, mux *http.ServeMux, method, path, token string, payload interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	authorization := ""
+	if token != "" {
+		authorization = "Bearer " + token
+	}
+	return sendRequest(t, mux, method, path, authorization, payload)
+}
+
+// doAdminRequest sends a request authenticated with the admin api key.
+func doAdminRequest(t *testing.T, mux *http.ServeMux, method, path string, payload interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	return sendRequest(t, mux, method, path, "ApiKey "+ApiCfg.AdminKey, payload)
+}
+
+func sendRequest(t *testing.T, mux *http.ServeMux, method, path, authorization string, payload interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			t.Fatalf("unable to encode payload: %v", err)
+		}
+	}
+
+	request := httptest.NewRequest(method, path, &body)
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// sentMail returns every email the test server has sent, oldest first.
+// testMailer keeps messages in a FileMailer and counts them, so tests can
+// wait for mail sent in the background.
+type testMailer struct {
+	*mail.FileMailer
+	mu   sync.Mutex
+	cond *sync.Cond
+	sent int
+}
+
+func newTestMailer(fileMailer *mail.FileMailer) *testMailer {
+	mailer := &testMailer{FileMailer: fileMailer}
+	mailer.cond = sync.NewCond(&mailer.mu)
+	return mailer
+}
+
+func (mailer *testMailer) Send(ctx context.Context, msg mail.Message) error {
+	err := mailer.FileMailer.Send(ctx, msg)
+	mailer.mu.Lock()
+	mailer.sent++
+	mailer.mu.Unlock()
+	mailer.cond.Broadcast()
+	return err
+}
+
+func sentMail(t *testing.T) []mail.Message {
+	t.Helper()
+	messages, err := ApiCfg.Mailer.(*testMailer).Messages()
+	if err != nil {
+		t.Fatalf("unable to read sent mail: %v", err)
+	}
+	return messages
+}
+
+// waitForMail waits until count messages have been sent in total and
+// returns them.
+func waitForMail(t *testing.T, count int) []mail.Message {
+	t.Helper()
+	mailer := ApiCfg.Mailer.(*testMailer)
+	mailer.mu.Lock()
+	for mailer.sent < count {
+		mailer.cond.Wait()
+	}
+	mailer.mu.Unlock()
+	return sentMail(t)
+}
+
+func decodeResponse(t *testing.T, recorder *httptest.ResponseRecorder, target interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(recorder.Body.Bytes(), target); err != nil {
+		t.Fatalf("unable to decode response %q: %v", recorder.Body.String(), err)
+	}
+}
+
+var verificationLinkPattern = regexp.MustCompile(`/api/email/verify\?token=([0-9a-f]{64})`)
+
+// verificationToken returns the token from the latest verification email
+// sent to email.
+func verificationToken(t *testing.T, email string) string {
+	t.Helper()
+	messages := sentMail(t)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].To != email {
+			continue
+		}
+		if match := verificationLinkPattern.FindStringSubmatch(messages[i].Body); match != nil {
+			return match[1]
+		}
+	}
+	t.Fatalf("no verification email sent to %s", email)
+	return ""
+}
+
+// verifyTestEmail follows the verification link sent to email.
+func verifyTestEmail(t *testing.T, mux *http.ServeMux, email string) {
+	t.Helper()
+	rec := doRequest(t, mux, "GET", "/api/email/verify?token="+verificationToken(t, email), "", nil)
+	if rec.Code != 200 {
+		t.Fatalf("verify email: status %d, body %s", rec.Code, rec.Body.String())
+	}
+}
+
+// createTestUser registers a user, verifies their email and logs them in,
+// returning the login response.
+func createTestUser(t *testing.T, mux *http.ServeMux, email string) map[string]interface{} {
+	t.Helper()
+	credentials := userRequest{Email: email, Password: "hunter2"}
+	if rec := doRequest(t, mux, "POST", "/api/users", "", credentials); rec.Code != 201 {
+		t.Fatalf("create user: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	verifyTestEmail(t, mux, email)
+
+	rec := doRequest(t, mux, "POST", "/api/login", "", credentials)
+	if rec.Code != 200 {
+		t.Fatalf("login: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	loginResp := map[string]interface{}{}
+	decodeResponse(t, rec, &loginResp)
+	return loginResp
+}
+
+func TestChirpLifecycle(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	token := user["token"].(string)
+
+	rec := doRequest(t, mux, "POST", "/api/chirps", token, map[string]string{"body": "I had a real kerfuffle today"})
+	if rec.Code != 201 {
+		t.Fatalf("create chirp: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	created := map[string]interface{}{}
+	decodeResponse(t, rec, &created)
+	if created["body"] != "I had a real **** today" {
+		t.Errorf("chirp body was not censored: %q", created["body"])
+	}
+	if created["user_id"] != user["id"] {
+		t.Errorf("chirp user_id %q does not match %q", created["user_id"], user["id"])
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/chirps/"+created["id"].(string), "", nil)
+	if rec.Code != 200 {
+		t.Fatalf("get chirp: status %d", rec.Code)
+	}
+
+	other := createTestUser(t, mux, "jesse@breakingbad.com")
+	rec = doRequest(t, mux, "DELETE", "/api/chirps/"+created["id"].(string), other["token"].(string), nil)
+	if rec.Code != 403 {
+		t.Errorf("delete by another user: expected 403, got %d", rec.Code)
+	}
+
+	rec = doRequest(t, mux, "DELETE", "/api/chirps/"+created["id"].(string), token, nil)
+	if rec.Code != 204 {
+		t.Errorf("delete chirp: expected 204, got %d", rec.Code)
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/chirps/"+created["id"].(string), "", nil)
+	if rec.Code != 404 {
+		t.Errorf("get deleted chirp: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestRefreshAndRevoke(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "saul@bettercall.com")
+	refreshToken := user["refresh_token"].(string)
+
+	rec := doRequest(t, mux, "POST", "/api/refresh", refreshToken, nil)
+	if rec.Code != 200 {
+		t.Fatalf("refresh: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	refreshResp := map[string]string{}
+	decodeResponse(t, rec, &refreshResp)
+	refreshToken = refreshResp["refresh_token"]
+
+	if rec := doRequest(t, mux, "POST", "/api/revoke", refreshToken, nil); rec.Code != 204 {
+		t.Fatalf("revoke: status %d", rec.Code)
+	}
+
+	if rec := doRequest(t, mux, "POST", "/api/refresh", refreshToken, nil); rec.Code != 401 {
+		t.Errorf("refresh with revoked token: expected 401, got %d", rec.Code)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "saul@bettercall.com")
+	original := user["refresh_token"].(string)
+
+	refreshWith := func(token string, expected int) string {
+		t.Helper()
+		rec := doRequest(t, mux, "POST", "/api/refresh", token, nil)
+		if rec.Code != expected {
+			t.Fatalf("refresh: expected %d, got %d, body %s", expected, rec.Code, rec.Body.String())
+		}
+		if rec.Code != 200 {
+			return ""
+		}
+		refreshResp := map[string]string{}
+		decodeResponse(t, rec, &refreshResp)
+		if refreshResp["token"] == "" || refreshResp["refresh_token"] == token {
+			t.Fatalf("expected a new access and refresh token, got %v", refreshResp)
+		}
+		return refreshResp["refresh_token"]
+	}
+
+	rotated := refreshWith(original, 200)
+	latest := refreshWith(rotated, 200)
+
+	// a second login is a separate family and survives the reuse below
+	credentials := userRequest{Email: "saul@bettercall.com", Password: "hunter2"}
+	otherLogin := map[string]interface{}{}
+	decodeResponse(t, doRequest(t, mux, "POST", "/api/login", "", credentials), &otherLogin)
+
+	// replaying a rotated token revokes the whole family
+	refreshWith(original, 401)
+	refreshWith(latest, 401)
+
+	refreshWith(otherLogin["refresh_token"].(string), 200)
+}
+
+// failingSigner is an Ed25519 key that can't sign, like a signing key on an
+// unreachable KMS.
+type failingSigner struct {
+	ed25519.PrivateKey
+}
+
+func (failingSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("signer unavailable")
+}
+
+func TestRefreshSigningFailure(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "saul@bettercall.com")
+	refreshToken := user["refresh_token"].(string)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := ApiCfg.Keys
+	ApiCfg.Keys, err = auth.NewKeySet("", failingSigner{key})
+	if err != nil {
+		t.Fatalf("unable to create key set: %v", err)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/refresh", refreshToken, nil); rec.Code != 500 {
+		t.Fatalf("refresh with a failing signer: expected 500, got %d", rec.Code)
+	}
+
+	// the token wasn't rotated, so the retry isn't mistaken for reuse
+	ApiCfg.Keys = keys
+	if rec := doRequest(t, mux, "POST", "/api/refresh", refreshToken, nil); rec.Code != 200 {
+		t.Errorf("retry after the signing failure: expected 200, got %d", rec.Code)
+	}
+}
+
+type chirpPage struct {
+	Chirps     []map[string]interface{} `json:"chirps"`
+	NextCursor *string                  `json:"next_cursor"`
+}
+
+func TestGetChirpsPagination(t *testing.T) {
+	mux := newTestMux(t)
+	author := createTestUser(t, mux, "walt@breakingbad.com")
+	other := createTestUser(t, mux, "jesse@breakingbad.com")
+
+	for i := 0; i < 5; i++ {
+		for _, user := range []map[string]interface{}{author, other} {
+			rec := doRequest(t, mux, "POST", "/api/chirps", user["token"].(string), map[string]string{"body": "chirp"})
+			if rec.Code != 201 {
+				t.Fatalf("create chirp: status %d", rec.Code)
+			}
+		}
+	}
+
+	for _, sortReq := range []string{"asc", "desc"} {
+		seen := []string{}
+		path := "/api/chirps?limit=2&sort=" + sortReq + "&author_id=" + author["id"].(string)
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatalf("%s: pagination did not terminate", sortReq)
+			}
+			rec := doRequest(t, mux, "GET", path, "", nil)
+			if rec.Code != 200 {
+				t.Fatalf("%s: status %d, body %s", sortReq, rec.Code, rec.Body.String())
+			}
+			page := chirpPage{}
+			decodeResponse(t, rec, &page)
+			for _, chirp := range page.Chirps {
+				if chirp["user_id"] != author["id"] {
+					t.Errorf("%s: chirp from %v leaked through author filter", sortReq, chirp["user_id"])
+				}
+				seen = append(seen, chirp["created_at"].(string))
+			}
+			if page.NextCursor == nil {
+				break
+			}
+			path = "/api/chirps?limit=2&sort=" + sortReq + "&author_id=" + author["id"].(string) + "&cursor=" + *page.NextCursor
+		}
+
+		if len(seen) != 5 {
+			t.Fatalf("%s: expected 5 chirps across pages, got %d", sortReq, len(seen))
+		}
+		for i := 1; i < len(seen); i++ {
+			if (sortReq == "asc" && seen[i] < seen[i-1]) || (sortReq == "desc" && seen[i] > seen[i-1]) {
+				t.Errorf("%s: chirps out of order: %v", sortReq, seen)
+				break
+			}
+		}
+	}
+
+	for _, path := range []string{"/api/chirps?limit=0", "/api/chirps?cursor=nope", "/api/chirps?sort=sideways"} {
+		if rec := doRequest(t, mux, "GET", path, "", nil); rec.Code != 400 {
+			t.Errorf("%s: expected 400, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestGetChirpsWithoutLimit(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	for i := 0; i <= defaultPageLimit; i++ {
+		postChirp(t, mux, user["token"].(string), map[string]interface{}{"body": "chirp"})
+	}
+
+	rec := doRequest(t, mux, "GET", "/api/chirps?sort=desc", "", nil)
+	chirps := []map[string]interface{}{}
+	decodeResponse(t, rec, &chirps)
+	if len(chirps) != defaultPageLimit {
+		t.Fatalf("expected a bare array of %d chirps, got %d", defaultPageLimit, len(chirps))
+	}
+
+	link := rec.Header().Get("Link")
+	if !strings.HasPrefix(link, "</api/chirps?") || !strings.HasSuffix(link, `>; rel="next"`) {
+		t.Fatalf("expected a link to the next page, got %q", link)
+	}
+	next := strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+	rec = doRequest(t, mux, "GET", next, "", nil)
+	chirps = []map[string]interface{}{}
+	decodeResponse(t, rec, &chirps)
+	if len(chirps) != 1 || rec.Header().Get("Link") != "" {
+		t.Errorf("expected the last chirp and no further link, got %d chirps and %q", len(chirps), rec.Header().Get("Link"))
+	}
+}
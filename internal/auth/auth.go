@@ -5,12 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
 	"crypto/rand"
 
-	"github.com/google/uuid"
-
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -30,43 +27,13 @@ func CheckPasswordHash(hashed_password, password string) error {
 	return nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Duration(3600000000))),
-		Subject:   userID.String(),
-	})
-
-	key, err := token.SignedString([]byte(tokenSecret))
-	if err != nil {
-		return "", err
-	}
-
-	return key, nil
-}
-
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	}
-
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, keyFunc)
-	if err != nil {
-		return uuid.UUID{}, err
-	}
-
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
-	if !ok {
-		return uuid.UUID{}, err
-	}
-
-	id, err := uuid.Parse(claims.Subject)
-	if err != nil {
-		return uuid.UUID{}, err
-	}
-
-	return id, nil
+// Claims are the JWT claims chirpy issues. SessionID ties an access token
+// to the login session it was refreshed from and Scope lists what the token
+// may do, separated by spaces.
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+	Scope     string `json:"scope,omitempty"`
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -81,7 +48,19 @@ func GetBearerToken(headers http.Header) (string, error) {
 
 func MakeRefreshToken() (string, error) {
 	randBytes := make([]byte, 32)
-	rand.Read(randBytes)
+	if _, err := rand.Read(randBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randBytes), nil
+}
+
+// MakeOneTimeToken returns a random token for links sent by email, such as
+// password resets. Only its HashToken should be stored.
+func MakeOneTimeToken() (string, error) {
+	randBytes := make([]byte, 32)
+	if _, err := rand.Read(randBytes); err != nil {
+		return "", err
+	}
 	return hex.EncodeToString(randBytes), nil
 }
 
@@ -42,11 +42,12 @@ func TestMakeJWT(t *testing.T) {
 
 	for _, c := range cases {
 		testOut := testValidateOutput{}
-		testToken, _ := MakeJWT(c.input.id, c.input.secret)
-		_, err := ValidateJWT(testToken, c.input.secret)
+		keySet := mustKeySet(t, c.input.secret, nil)
+		testToken, _ := keySet.MakeJWT(c.input.id, uuid.NullUUID{}, AllScopes)
+		_, err := keySet.ValidateJWT(testToken)
 		testOut.err1 = err
 		time.Sleep(15 * time.Second)
-		_, err = ValidateJWT(testToken, c.input.secret)
+		_, err = keySet.ValidateJWT(testToken)
 		testOut.err2 = err
 		if testOut.err1 != c.expected.err1 {
 			fmt.Println(err)
@@ -91,3 +92,48 @@ func testGetBearerToken(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateJWTSession(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+	keySet := mustKeySet(t, "test", nil)
+
+	token, err := keySet.MakeJWT(userID, uuid.NullUUID{UUID: sessionID, Valid: true}, AllScopes)
+	if err != nil {
+		t.Fatalf("MakeJWT: %v", err)
+	}
+	principal, err := keySet.ValidateJWT(token)
+	if err != nil || principal.UserID != userID || !principal.SessionID.Valid || principal.SessionID.UUID != sessionID {
+		t.Errorf("ValidateJWT = %+v, %v", principal, err)
+	}
+
+	token, _ = keySet.MakeJWT(userID, uuid.NullUUID{}, AllScopes)
+	if principal, err := keySet.ValidateJWT(token); err != nil || principal.SessionID.Valid {
+		t.Errorf("expected no session for a plain token, got %v, %v", principal.SessionID, err)
+	}
+
+	if _, err := mustKeySet(t, "wrong", nil).ValidateJWT(token); err == nil {
+		t.Errorf("expected an error for the wrong secret")
+	}
+}
+
+func TestPersonalAccessToken(t *testing.T) {
+	token, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Fatalf("MakePersonalAccessToken: %v", err)
+	}
+	if !IsPersonalAccessToken(token) {
+		t.Errorf("%q is not recognised as a personal access token", token)
+	}
+	if prefix := PersonalAccessTokenPrefix(token); prefix != token[:17] {
+		t.Errorf("unexpected prefix %q", prefix)
+	}
+	if HashToken(token) == token || HashToken(token) != HashToken(token) {
+		t.Errorf("HashToken is not a stable digest")
+	}
+
+	jwt, _ := mustKeySet(t, "test", nil).MakeJWT(uuid.New(), uuid.NullUUID{}, AllScopes)
+	if IsPersonalAccessToken(jwt) {
+		t.Errorf("a JWT was recognised as a personal access token")
+	}
+}
@@ -0,0 +1,398 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	accessTokenTTL    = time.Duration(3600000000)
+	challengeTokenTTL = 5 * time.Minute
+	minRSAKeyBits     = 2048
+
+	// challengeAudience marks a token that only proves the password step of
+	// a two-factor login. Access tokens have no audience.
+	challengeAudience = "chirpy-2fa"
+)
+
+// KeySet signs and verifies access tokens. Tokens are signed with a single
+// key and verified against every key in the set, so a new signing key can
+// be introduced while tokens signed by the previous one are still valid.
+//
+// Asymmetric keys are identified by the kid header, which is the RFC 7638
+// thumbprint of the public key. A KeySet without a private key signs and
+// verifies HS256 tokens without a kid instead. The two are never mixed, so
+// once a private key is configured the shared secret can't mint tokens.
+type KeySet struct {
+	secret []byte
+	signer *verificationKey
+	keys   map[string]*verificationKey
+}
+
+type verificationKey struct {
+	id        string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	public    crypto.PublicKey
+	publicJWK JWK
+}
+
+// JWK is the public half of a key in JSON Web Key form.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set as served from /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeySet returns a KeySet that signs with signer, or with HS256 and
+// secret if signer is nil. The public keys in verifiers are accepted in
+// addition to the signer's own. The secret is ignored when there is a
+// signer.
+func NewKeySet(secret string, signer crypto.Signer, verifiers ...crypto.PublicKey) (*KeySet, error) {
+	keySet := &KeySet{keys: map[string]*verificationKey{}}
+	if secret != "" && signer == nil {
+		keySet.secret = []byte(secret)
+	}
+
+	if signer != nil {
+		key, err := newVerificationKey(signer.Public())
+		if err != nil {
+			return nil, err
+		}
+		key.private = signer
+		keySet.signer = key
+		keySet.keys[key.id] = key
+	} else if keySet.secret == nil {
+		return nil, fmt.Errorf("a signing key or secret is required")
+	}
+
+	for _, public := range verifiers {
+		key, err := newVerificationKey(public)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := keySet.keys[key.id]; !ok {
+			keySet.keys[key.id] = key
+		}
+	}
+
+	return keySet, nil
+}
+
+// LoadKeySet reads the PEM encoded private key at signingKeyPath and the
+// public (or private) keys at verificationKeyPaths and builds a KeySet from
+// them. An empty signingKeyPath signs with HS256 and secret.
+func LoadKeySet(secret, signingKeyPath string, verificationKeyPaths []string) (*KeySet, error) {
+	var signer crypto.Signer
+	if signingKeyPath != "" {
+		pemBytes, err := os.ReadFile(signingKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		signer, err = ParsePrivateKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", signingKeyPath, err)
+		}
+	}
+
+	verifiers := []crypto.PublicKey{}
+	for _, path := range verificationKeyPaths {
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		public, err := ParsePublicKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		verifiers = append(verifiers, public)
+	}
+
+	return NewKeySet(secret, signer, verifiers...)
+}
+
+// ParsePrivateKey parses a PEM encoded Ed25519 or RSA private key in PKCS #8
+// form, or an RSA key in PKCS #1 form.
+func ParsePrivateKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// ParsePublicKey parses a PEM encoded Ed25519 or RSA public key. A private
+// key is accepted too, in which case its public half is returned.
+func ParsePublicKey(pemBytes []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		signer, err := ParsePrivateKey(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func newVerificationKey(public crypto.PublicKey) (*verificationKey, error) {
+	key := &verificationKey{public: public}
+
+	switch public := public.(type) {
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+		key.publicJWK = JWK{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       base64.RawURLEncoding.EncodeToString(public),
+		}
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA keys must be at least %d bits", minRSAKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+		key.publicJWK = JWK{
+			KeyType: "RSA",
+			N:       base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+
+	id, err := thumbprint(key.publicJWK)
+	if err != nil {
+		return nil, err
+	}
+	key.id = id
+	key.publicJWK.KeyID = id
+	key.publicJWK.Use = "sig"
+	key.publicJWK.Algorithm = key.method.Alg()
+	return key, nil
+}
+
+// thumbprint computes the RFC 7638 thumbprint of a public key: the SHA-256
+// of its required members in lexicographic order.
+func thumbprint(jwk JWK) (string, error) {
+	var members interface{}
+	switch jwk.KeyType {
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	default:
+		return "", fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+
+	encoded, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// MakeJWT issues an access token for userID with the given scopes, tied to
+// sessionID if it is valid.
+func (keySet *KeySet) MakeJWT(userID uuid.UUID, sessionID uuid.NullUUID, scopes []string) (string, error) {
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(accessTokenTTL)),
+			Subject:   userID.String(),
+		},
+		Scope: strings.Join(scopes, " "),
+	}
+	if sessionID.Valid {
+		claims.SessionID = sessionID.UUID.String()
+	}
+
+	return keySet.sign(claims)
+}
+
+// ValidateJWT checks an access token and returns the user it was issued
+// to, its scopes and the session it belongs to, if any. A token without a
+// scope claim has no scopes.
+func (keySet *KeySet) ValidateJWT(tokenString string) (Principal, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keySet.keyFunc)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return Principal{}, fmt.Errorf("unexpected claims type")
+	}
+	if len(claims.Audience) != 0 {
+		return Principal{}, fmt.Errorf("not an access token")
+	}
+
+	principal := Principal{Scopes: strings.Fields(claims.Scope)}
+	principal.UserID, err = uuid.Parse(claims.Subject)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	if claims.SessionID != "" {
+		principal.SessionID.UUID, err = uuid.Parse(claims.SessionID)
+		if err != nil {
+			return Principal{}, err
+		}
+		principal.SessionID.Valid = true
+	}
+
+	return principal, nil
+}
+
+// Challenge is a validated challenge token. Its ID is recorded when the
+// token is exchanged, so each token logs in once.
+type Challenge struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+// MakeChallengeToken issues a short lived token that a user who passed the
+// password check exchanges, together with a second factor, for a login.
+func (keySet *KeySet) MakeChallengeToken(userID uuid.UUID) (string, error) {
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(challengeTokenTTL)),
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{challengeAudience},
+		},
+	}
+	return keySet.sign(claims)
+}
+
+// ValidateChallengeToken checks a token from MakeChallengeToken. It does
+// not know whether the token was already exchanged; the caller records
+// Challenge.ID for that.
+func (keySet *KeySet) ValidateChallengeToken(tokenString string) (Challenge, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keySet.keyFunc, jwt.WithAudience(challengeAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return Challenge{}, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return Challenge{}, fmt.Errorf("unexpected claims type")
+	}
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return Challenge{}, err
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return Challenge{}, err
+	}
+	return Challenge{ID: id, UserID: userID, ExpiresAt: claims.ExpiresAt.Time}, nil
+}
+
+func (keySet *KeySet) sign(claims Claims) (string, error) {
+	if keySet.signer == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(keySet.secret)
+	}
+
+	token := jwt.NewWithClaims(keySet.signer.method, claims)
+	token.Header["kid"] = keySet.signer.id
+	return token.SignedString(keySet.signer.private)
+}
+
+// keyFunc picks the verification key for a token. The algorithm in the
+// header must match the key, so an RSA public key can never be used as an
+// HMAC secret.
+func (keySet *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, hasKid := token.Header["kid"].(string)
+	if !hasKid {
+		if keySet.secret == nil || token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("token has no key id")
+		}
+		return keySet.secret, nil
+	}
+
+	key, ok := keySet.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("key %q does not sign with %s", kid, token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public verification keys, signing key first.
+func (keySet *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if keySet.signer != nil {
+		jwks.Keys = append(jwks.Keys, keySet.signer.publicJWK)
+	}
+	for id, key := range keySet.keys {
+		if keySet.signer == nil || id != keySet.signer.id {
+			jwks.Keys = append(jwks.Keys, key.publicJWK)
+		}
+	}
+	return jwks
+}
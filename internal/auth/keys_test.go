@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestKeySetRoundTrip(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, keySet := range map[string]*KeySet{
+		"HS256": mustKeySet(t, "secret", nil),
+		"EdDSA": mustKeySet(t, "", edKey),
+		"RS256": mustKeySet(t, "", rsaKey),
+	} {
+		userID := uuid.New()
+		sessionID := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+		token, err := keySet.MakeJWT(userID, sessionID, []string{ScopeChirpsRead, ScopeChirpsWrite})
+		if err != nil {
+			t.Fatalf("%s: MakeJWT: %v", name, err)
+		}
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if parsed.Method.Alg() != name {
+			t.Errorf("%s: signed with %s", name, parsed.Method.Alg())
+		}
+		if _, hasKid := parsed.Header["kid"]; hasKid == (name == "HS256") {
+			t.Errorf("%s: unexpected kid header %v", name, parsed.Header)
+		}
+
+		principal, err := keySet.ValidateJWT(token)
+		if err != nil || principal.UserID != userID || principal.SessionID != sessionID {
+			t.Errorf("%s: ValidateJWT = %+v, %v", name, principal, err)
+		}
+		if !principal.HasScope(ScopeChirpsWrite) || principal.HasScope(ScopeAccountWrite) {
+			t.Errorf("%s: unexpected scopes %v", name, principal.Scopes)
+		}
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	oldKeySet := mustKeySet(t, "", oldKey)
+	oldToken, _ := oldKeySet.MakeJWT(uuid.New(), uuid.NullUUID{}, AllScopes)
+
+	// the new signing key alone rejects tokens from the old one
+	if _, err := mustKeySet(t, "", newKey).ValidateJWT(oldToken); err == nil {
+		t.Errorf("expected a token signed by an unknown key to be rejected")
+	}
+
+	rotated, err := NewKeySet("", newKey, oldKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotated.ValidateJWT(oldToken); err != nil {
+		t.Errorf("expected the old key to verify during rotation: %v", err)
+	}
+	if jwks := rotated.JWKS(); len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != rotated.signer.id {
+		t.Errorf("expected the signing key first in %+v", jwks)
+	}
+
+	// without a secret, HS256 tokens are not accepted at all
+	hmacToken, _ := mustKeySet(t, "secret", nil).MakeJWT(uuid.New(), uuid.NullUUID{}, AllScopes)
+	if _, err := rotated.ValidateJWT(hmacToken); err == nil {
+		t.Errorf("expected an HS256 token to be rejected")
+	}
+}
+
+func TestKeySetSignerDisablesSecret(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	hmacToken, err := mustKeySet(t, "secret", nil).MakeJWT(uuid.New(), uuid.NullUUID{}, AllScopes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the old shared secret can't keep minting tokens once a key signs them
+	if _, err := mustKeySet(t, "secret", edKey).ValidateJWT(hmacToken); err == nil {
+		t.Errorf("expected an HS256 token to be rejected when a signing key is set")
+	}
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keySet := mustKeySet(t, "secret", rsaKey)
+
+	// an HS256 token whose kid points at the RSA key, "signed" with the
+	// public key bytes
+	publicDER, _ := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: uuid.New().String()},
+	})
+	token.Header["kid"] = keySet.signer.id
+	forged, err := token.SignedString(publicDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := keySet.ValidateJWT(forged); err == nil {
+		t.Errorf("expected a forged HS256 token to be rejected")
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	// the example from RFC 7638, section 3.1
+	jwk := JWK{
+		KeyType: "RSA",
+		E:       "AQAB",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMs" +
+			"tn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5h" +
+			"ajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	}
+
+	id, err := thumbprint(jwk)
+	if err != nil || id != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("thumbprint = %q, %v", id, err)
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	_, signingKey, _ := ed25519.GenerateKey(rand.Reader)
+	oldPublic, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	signingDER, _ := x509.MarshalPKCS8PrivateKey(signingKey)
+	signingPath := filepath.Join(dir, "signing.pem")
+	writePEM(t, signingPath, "PRIVATE KEY", signingDER)
+
+	oldDER, _ := x509.MarshalPKIXPublicKey(oldPublic)
+	oldPath := filepath.Join(dir, "old.pem")
+	writePEM(t, oldPath, "PUBLIC KEY", oldDER)
+
+	keySet, err := LoadKeySet("", signingPath, []string{oldPath})
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	if len(keySet.JWKS().Keys) != 2 {
+		t.Errorf("expected 2 keys, got %+v", keySet.JWKS())
+	}
+
+	if _, err := LoadKeySet("", "", nil); err == nil {
+		t.Errorf("expected an error without a key or secret")
+	}
+	weakKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	if _, err := NewKeySet("", weakKey); err == nil {
+		t.Errorf("expected short RSA keys to be rejected")
+	}
+}
+
+func mustKeySet(t *testing.T, secret string, signer crypto.Signer) *KeySet {
+	t.Helper()
+	keySet, err := NewKeySet(secret, signer)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	return keySet
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Scopes limit what a token may do. Logins get every scope; personal
+// access tokens get the ones their owner picked.
+const (
+	ScopeChirpsRead         = "chirps:read"
+	ScopeChirpsWrite        = "chirps:write"
+	ScopeFollowsWrite       = "follows:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+	ScopeAccountRead        = "account:read"
+	ScopeAccountWrite       = "account:write"
+)
+
+// AllScopes lists every scope in a stable order.
+var AllScopes = []string{
+	ScopeChirpsRead,
+	ScopeChirpsWrite,
+	ScopeFollowsWrite,
+	ScopeNotificationsRead,
+	ScopeNotificationsWrite,
+	ScopeAccountRead,
+	ScopeAccountWrite,
+}
+
+const personalAccessTokenPrefix = "chirpy_pat_"
+
+func ValidScope(scope string) bool {
+	return slices.Contains(AllScopes, scope)
+}
+
+// Principal is who an authenticated request acts for and what it may do.
+// SessionID is set for access tokens from a login and TokenID for personal
+// access tokens.
+type Principal struct {
+	UserID    uuid.UUID
+	SessionID uuid.NullUUID
+	TokenID   uuid.NullUUID
+	Scopes    []string
+}
+
+func (principal Principal) HasScope(scope string) bool {
+	return slices.Contains(principal.Scopes, scope)
+}
+
+// MakePersonalAccessToken returns a new random personal access token. Only
+// its HashToken digest should be stored.
+func MakePersonalAccessToken() (string, error) {
+	randBytes := make([]byte, 32)
+	if _, err := rand.Read(randBytes); err != nil {
+		return "", err
+	}
+	return personalAccessTokenPrefix + hex.EncodeToString(randBytes), nil
+}
+
+// IsPersonalAccessToken reports whether a bearer token is a personal access
+// token rather than a JWT.
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
+// PersonalAccessTokenPrefix returns the start of a token that is safe to
+// show in listings so users can tell their tokens apart.
+func PersonalAccessTokenPrefix(token string) string {
+	return token[:min(len(token), len(personalAccessTokenPrefix)+6)]
+}
+
+// HashToken returns the SHA-256 digest of a token in hex. Tokens are 256
+// bits of randomness, so unlike passwords they don't need a slow hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
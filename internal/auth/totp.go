@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters. These are the defaults every authenticator app assumes,
+// so they are not configurable.
+const (
+	totpPeriod       = 30
+	totpDigits       = 6
+	totpSkew         = 1
+	totpSecretBytes  = 20
+	recoveryCodeSize = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MakeTOTPSecret returns a random base32 encoded TOTP secret.
+func MakeTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPURI returns the otpauth:// URI authenticator apps read from a QR code.
+func TOTPURI(secret, issuer, account string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}).String()
+}
+
+// TOTPCode returns the code for secret at time t, as an authenticator app
+// would show it.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil {
+		return "", err
+	}
+	return totp(sha1.New, key, t, totpPeriod, totpDigits), nil
+}
+
+// ValidateTOTP checks code against secret at time t, allowing one period of
+// clock drift either way. It returns the time step the code belongs to so
+// callers can refuse to accept the same step twice.
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	step := t.Unix() / totpPeriod
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		expected := hotp(sha1.New, key, uint64(step+offset), totpDigits)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step + offset, true
+		}
+	}
+	return 0, false
+}
+
+// totp computes the RFC 6238 code for time t.
+func totp(newHash func() hash.Hash, key []byte, t time.Time, period int64, digits int) string {
+	return hotp(newHash, key, uint64(t.Unix()/period), digits)
+}
+
+// hotp computes the RFC 4226 code for counter.
+func hotp(newHash func() hash.Hash, key []byte, counter uint64, digits int) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, counter)
+
+	mac := hmac.New(newHash, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation: the low nibble of the last byte picks four bytes
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulus)
+}
+
+// MakeRecoveryCodes returns n single use recovery codes in xxxxx-xxxxx form.
+func MakeRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		raw := make([]byte, recoveryCodeSize*5/8)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(raw))
+		codes = append(codes, code[:recoveryCodeSize/2]+"-"+code[recoveryCodeSize/2:])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode strips the formatting users tend to add or drop when
+// typing a recovery code, so it can be hashed and compared.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"hash"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestTOTPVectors checks the reference values from RFC 6238 Appendix B.
+func TestTOTPVectors(t *testing.T) {
+	seeds := map[string]struct {
+		newHash func() hash.Hash
+		key     []byte
+	}{
+		"SHA1":   {sha1.New, []byte("12345678901234567890")},
+		"SHA256": {sha256.New, []byte("12345678901234567890123456789012")},
+		"SHA512": {sha512.New, []byte("1234567890123456789012345678901234567890123456789012345678901234")},
+	}
+
+	vectors := []struct {
+		unix int64
+		want map[string]string
+	}{
+		{59, map[string]string{"SHA1": "94287082", "SHA256": "46119246", "SHA512": "90693936"}},
+		{1111111109, map[string]string{"SHA1": "07081804", "SHA256": "68084774", "SHA512": "25091201"}},
+		{1111111111, map[string]string{"SHA1": "14050471", "SHA256": "67062674", "SHA512": "99943326"}},
+		{1234567890, map[string]string{"SHA1": "89005924", "SHA256": "91819424", "SHA512": "93441116"}},
+		{2000000000, map[string]string{"SHA1": "69279037", "SHA256": "90698825", "SHA512": "38618901"}},
+		{20000000000, map[string]string{"SHA1": "65353130", "SHA256": "77737706", "SHA512": "47863826"}},
+	}
+
+	for _, vector := range vectors {
+		for name, seed := range seeds {
+			got := totp(seed.newHash, seed.key, time.Unix(vector.unix, 0), 30, 8)
+			if got != vector.want[name] {
+				t.Errorf("%s at %d: expected %s, got %s", name, vector.unix, vector.want[name], got)
+			}
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+	at := time.Unix(1111111111, 0)
+	code := totp(sha1.New, key, at, 30, 6)
+
+	step, ok := ValidateTOTP(secret, code, at)
+	if !ok || step != at.Unix()/30 {
+		t.Fatalf("expected code %s to be valid at step %d, got %d %v", code, at.Unix()/30, step, ok)
+	}
+
+	// one period of drift either way is allowed, two is not
+	if _, ok := ValidateTOTP(secret, code, at.Add(30*time.Second)); !ok {
+		t.Errorf("expected code to be valid one period later")
+	}
+	if _, ok := ValidateTOTP(secret, code, at.Add(-30*time.Second)); !ok {
+		t.Errorf("expected code to be valid one period earlier")
+	}
+	if _, ok := ValidateTOTP(secret, code, at.Add(90*time.Second)); ok {
+		t.Errorf("expected code to be rejected three periods later")
+	}
+
+	for _, bad := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := ValidateTOTP(secret, bad, at); ok {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", code, at); ok {
+		t.Errorf("expected an invalid secret to be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := MakeTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := TOTPURI(secret, "Chirpy", "walt@breakingbad.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:walt@breakingbad.com?") {
+		t.Errorf("unexpected uri %q", uri)
+	}
+	if !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("uri %q does not contain the secret", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := MakeRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected recovery code format %q", code)
+		}
+		normalized := NormalizeRecoveryCode(code)
+		if seen[normalized] {
+			t.Errorf("duplicate recovery code %q", code)
+		}
+		seen[normalized] = true
+		if NormalizeRecoveryCode(" "+strings.ToUpper(code)+" ") != normalized {
+			t.Errorf("recovery code %q does not normalize consistently", code)
+		}
+	}
+}
+
+func TestChallengeToken(t *testing.T) {
+	keySet := mustKeySet(t, "secret", nil)
+	userID := uuid.New()
+
+	challenge, err := keySet.MakeChallengeToken(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := keySet.ValidateChallengeToken(challenge)
+	if err != nil || got.UserID != userID {
+		t.Fatalf("expected challenge for %s, got %s %v", userID, got.UserID, err)
+	}
+	if got.ID == uuid.Nil || got.ExpiresAt.Before(time.Now()) {
+		t.Errorf("expected an id and an expiry, got %+v", got)
+	}
+	if other, _ := keySet.MakeChallengeToken(userID); other == challenge {
+		t.Errorf("challenge tokens should be unique")
+	}
+
+	// a challenge token is not an access token, and vice versa
+	if _, err := keySet.ValidateJWT(challenge); err == nil {
+		t.Errorf("challenge token was accepted as an access token")
+	}
+	access, err := keySet.MakeJWT(userID, uuid.NullUUID{}, AllScopes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keySet.ValidateChallengeToken(access); err == nil {
+		t.Errorf("access token was accepted as a challenge token")
+	}
+}
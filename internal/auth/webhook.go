@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader carries the signature of an incoming webhook, in
+// the form "t=<unix seconds>,v1=<hex hmac>". A sender rotating its secret
+// may include several v1 values.
+const WebhookSignatureHeader = "Polka-Signature"
+
+// DefaultWebhookTolerance is how far a webhook timestamp may be from the
+// current time. It bounds how long a captured request can be replayed.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+var (
+	ErrMissingSignature   = errors.New("missing webhook signature")
+	ErrInvalidSignature   = errors.New("webhook signature does not match")
+	ErrSignatureTimestamp = errors.New("webhook timestamp outside tolerance")
+)
+
+// WebhookVerifier checks HMAC-SHA256 signatures over "<timestamp>.<body>".
+// A request is accepted if any signature in it matches any of the secrets,
+// so a new secret can be added before the sender switches to it and the old
+// one removed afterwards.
+type WebhookVerifier struct {
+	secrets   [][]byte
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewWebhookVerifier returns a verifier for the given secrets. Empty
+// secrets are ignored; a verifier without secrets rejects everything.
+func NewWebhookVerifier(secrets []string, tolerance time.Duration) *WebhookVerifier {
+	verifier := &WebhookVerifier{tolerance: tolerance, now: time.Now}
+	for _, secret := range secrets {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			verifier.secrets = append(verifier.secrets, []byte(secret))
+		}
+	}
+	return verifier
+}
+
+// SignWebhook returns the signature header value for body sent at
+// timestamp.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(webhookMAC([]byte(secret), unix, body))
+}
+
+// Verify checks the signature header of a webhook request against its raw
+// body and returns the signed timestamp.
+func (verifier *WebhookVerifier) Verify(headers http.Header, body []byte) (time.Time, error) {
+	header := headers.Get(WebhookSignatureHeader)
+	if header == "" {
+		return time.Time{}, ErrMissingSignature
+	}
+
+	timestamp := ""
+	signatures := [][]byte{}
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			// undecodable signatures are skipped, they can't match anyway
+			if signature, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return time.Time{}, ErrMissingSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrSignatureTimestamp
+	}
+	signedAt := time.Unix(unix, 0)
+	age := verifier.now().Sub(signedAt)
+	if age > verifier.tolerance || age < -verifier.tolerance {
+		return time.Time{}, ErrSignatureTimestamp
+	}
+
+	// every pair is compared so the time taken doesn't reveal which secret
+	// matched
+	matched := false
+	for _, secret := range verifier.secrets {
+		expected := webhookMAC(secret, timestamp, body)
+		for _, signature := range signatures {
+			if hmac.Equal(expected, signature) {
+				matched = true
+			}
+		}
+	}
+	if !matched {
+		return time.Time{}, ErrInvalidSignature
+	}
+	return signedAt, nil
+}
+
+func webhookMAC(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
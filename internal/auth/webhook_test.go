@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWebhookVerifier(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+
+	verifier := NewWebhookVerifier([]string{"new-secret", " ", "old-secret"}, DefaultWebhookTolerance)
+	verifier.now = func() time.Time { return now }
+
+	headers := func(value string) http.Header {
+		h := http.Header{}
+		if value != "" {
+			h.Set(WebhookSignatureHeader, value)
+		}
+		return h
+	}
+
+	cases := []struct {
+		name   string
+		header string
+		body   []byte
+		err    error
+	}{
+		{"current secret", SignWebhook("new-secret", now, body), body, nil},
+		{"previous secret", SignWebhook("old-secret", now, body), body, nil},
+		{"rotating sender", SignWebhook("next-secret", now, body) + ",v1=" + SignWebhook("new-secret", now, body)[len("t=1700000000,v1="):], body, nil},
+		{"within tolerance", SignWebhook("new-secret", now.Add(-4*time.Minute), body), body, nil},
+		{"unknown secret", SignWebhook("leaked-api-key", now, body), body, ErrInvalidSignature},
+		{"tampered body", SignWebhook("new-secret", now, body), []byte(`{"event":"user.upgraded"}`), ErrInvalidSignature},
+		{"stale timestamp", SignWebhook("new-secret", now.Add(-6*time.Minute), body), body, ErrSignatureTimestamp},
+		{"future timestamp", SignWebhook("new-secret", now.Add(6*time.Minute), body), body, ErrSignatureTimestamp},
+		{"missing header", "", body, ErrMissingSignature},
+		{"missing timestamp", "v1=abcd", body, ErrMissingSignature},
+		{"malformed signature", "t=1700000000,v1=zz", body, ErrMissingSignature},
+	}
+	for _, tc := range cases {
+		signedAt, err := verifier.Verify(headers(tc.header), tc.body)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.err, err)
+		}
+		if err == nil && signedAt.IsZero() {
+			t.Errorf("%s: expected the signed timestamp", tc.name)
+		}
+	}
+
+	empty := NewWebhookVerifier(nil, DefaultWebhookTolerance)
+	if _, err := empty.Verify(headers(SignWebhook("", time.Now(), body)), body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("verifier without secrets accepted a request: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+// Package avatar turns uploaded images into square PNG avatars.
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+)
+
+const (
+	// Size is the width and height of every avatar.
+	Size = 256
+	// MaxDimension bounds the width and height of uploads, so a small file
+	// can't decode into an enormous image.
+	MaxDimension = 4096
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("avatar: image must be a PNG, JPEG or GIF")
+	ErrTooLarge          = errors.New("avatar: image is too large")
+)
+
+// Process decodes an uploaded image, crops it to a centred square and
+// scales it to Size by Size, returning it encoded as PNG.
+func Process(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if format != "png" && format != "jpeg" && format != "gif" {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > MaxDimension || config.Height > MaxDimension {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, resize(cropSquare(img), Size)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cropSquare returns the largest centred square of img as RGBA.
+func cropSquare(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	origin := image.Point{
+		X: bounds.Min.X + (bounds.Dx()-side)/2,
+		Y: bounds.Min.Y + (bounds.Dy()-side)/2,
+	}
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, origin, draw.Src)
+	return square
+}
+
+// resize scales a square image to size by size. Each output pixel is the
+// average of the source pixels it covers, which keeps downscaled photos
+// from looking noisy; upscaling repeats pixels.
+func resize(src *image.RGBA, size int) *image.RGBA {
+	side := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		y0, y1 := y*side/size, max((y+1)*side/size, y*side/size+1)
+		for x := 0; x < size; x++ {
+			x0, x1 := x*side/size, max((x+1)*side/size, x*side/size+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+
+			count := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[offset+c] = uint8(sum[c] / count)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,82 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	// a wide image: the outer thirds are blue and get cropped away
+	src := image.NewRGBA(image.Rect(0, 0, 900, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 900; x++ {
+			if x >= 300 && x < 600 {
+				src.Set(x, y, red)
+			} else {
+				src.Set(x, y, blue)
+			}
+		}
+	}
+
+	processed, err := Process(bytes.NewReader(encodePNG(t, src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, format, err := image.Decode(bytes.NewReader(processed))
+	if err != nil || format != "png" {
+		t.Fatalf("expected a png, got %q %v", format, err)
+	}
+	if out.Bounds().Dx() != Size || out.Bounds().Dy() != Size {
+		t.Fatalf("expected %dx%d, got %v", Size, Size, out.Bounds())
+	}
+	for _, point := range []image.Point{{0, 0}, {Size / 2, Size / 2}, {Size - 1, Size - 1}} {
+		if got := color.RGBAModel.Convert(out.At(point.X, point.Y)); got != red {
+			t.Errorf("pixel %v: expected red, got %v", point, got)
+		}
+	}
+}
+
+func TestProcessUpscalesJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 40, 60)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	processed, err := Process(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := png.DecodeConfig(bytes.NewReader(processed))
+	if err != nil || config.Width != Size || config.Height != Size {
+		t.Errorf("expected a %dx%d png, got %+v %v", Size, Size, config, err)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	if _, err := Process(strings.NewReader("definitely not an image")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("text: expected ErrUnsupportedFormat, got %v", err)
+	}
+
+	huge := encodePNG(t, image.NewGray(image.Rect(0, 0, MaxDimension+1, 1)))
+	if _, err := Process(bytes.NewReader(huge)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("oversized image: expected ErrTooLarge, got %v", err)
+	}
+}
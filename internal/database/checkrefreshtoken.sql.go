@@ -10,7 +10,7 @@ import (
 )
 
 const checkRefreshToken = `-- name: CheckRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by
 FROM refresh_tokens
 WHERE token = $1
 `
@@ -25,6 +25,8 @@ func (q *Queries) CheckRefreshToken(ctx context.Context, token string) (RefreshT
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
 	)
 	return i, err
 }
@@ -12,24 +12,30 @@ import (
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, in_reply_to, kind, original_chirp_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     nOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, kind, original_chirp_id, search_vector
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body            string
+	UserID          uuid.UUID
+	InReplyTo       uuid.NullUUID
+	Kind            string
+	OriginalChirpID uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.InReplyTo, arg.Kind, arg.OriginalChirpID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +43,67 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.Kind,
+		&i.OriginalChirpID,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
+const createChirpWithinRate = `-- name: CreateChirpWithinRate :one
+WITH slot AS (
+    INSERT INTO chirp_rate_limits(user_id, posted_at)
+    VALUES ($1, ARRAY[NOW()::timestamp])
+    ON CONFLICT (user_id) DO UPDATE
+    SET posted_at = ARRAY(
+        SELECT posted FROM unnest(chirp_rate_limits.posted_at) posted
+        WHERE posted > NOW() - INTERVAL '1 hour'
+    ) || NOW()::timestamp
+    WHERE (
+        SELECT COUNT(*) FROM unnest(chirp_rate_limits.posted_at) posted
+        WHERE posted > NOW() - INTERVAL '1 hour'
+    ) < $2::int
+    RETURNING user_id
+)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, in_reply_to, kind, original_chirp_id)
+SELECT
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $3,
+    slot.user_id,
+    $4,
+    $5,
+    $6
+FROM slot
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, kind, original_chirp_id, search_vector
+`
+
+type CreateChirpWithinRateParams struct {
+	UserID          uuid.UUID
+	ChirpsPerHour   int32
+	Body            string
+	InReplyTo       uuid.NullUUID
+	Kind            string
+	OriginalChirpID uuid.NullUUID
+}
+
+func (q *Queries) CreateChirpWithinRate(ctx context.Context, arg CreateChirpWithinRateParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createChirpWithinRate, arg.UserID, arg.ChirpsPerHour, arg.Body, arg.InReplyTo, arg.Kind, arg.OriginalChirpID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.Kind,
+		&i.OriginalChirpID,
+		&i.SearchVector,
 	)
 	return i, err
 }
@@ -12,7 +12,10 @@ import (
 )
 
 const deleteChirp = `-- name: DeleteChirp :exec
-DELETE FROM chirps
+UPDATE chirps
+SET body = '',
+    deleted_at = NOW(),
+    updated_at = NOW()
 WHERE id = $1
 `
 
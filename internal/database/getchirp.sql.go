@@ -12,7 +12,7 @@ import (
 )
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, kind, original_chirp_id, search_vector
 FROM chirps
 WHERE id = $1
 `
@@ -26,6 +26,11 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.Kind,
+		&i.OriginalChirpID,
+		&i.SearchVector,
 	)
 	return i, err
 }
@@ -10,7 +10,7 @@ import (
 )
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, handle, email_verified_at, bio, location, website, avatar_key
 FROM users
 WHERE email = $1
 `
@@ -25,6 +25,13 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarKey,
 	)
 	return i, err
 }
@@ -10,7 +10,7 @@ import (
 )
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.display_name, users.handle, users.email_verified_at, users.bio, users.location, users.website, users.avatar_key
 FROM users
 INNER JOIN refresh_tokens
 ON users.id = refresh_tokens.user_id
@@ -27,6 +27,13 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Us
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarKey,
 	)
 	return i, err
 }
@@ -12,27 +12,226 @@ import (
 )
 
 type Chirp struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Body            string
+	UserID          uuid.UUID
+	InReplyTo       uuid.NullUUID
+	DeletedAt       sql.NullTime
+	Kind            string
+	OriginalChirpID uuid.NullUUID
+	SearchVector    interface{}
+}
+
+type ChirpFilter struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
+	Term      string
+	Action    string
+}
+
+type ChirpFlag struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ChirpID    uuid.UUID
+	FilterID   uuid.NullUUID
+	Term       string
+	ResolvedAt sql.NullTime
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+type ChirpRateLimit struct {
+	UserID   uuid.UUID
+	PostedAt []time.Time
+}
+
+type ChirpTag struct {
+	ChirpID   uuid.UUID
+	TagID     uuid.UUID
+	CreatedAt time.Time
+}
+
+type EmailVerificationToken struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginThrottle struct {
+	Kind          string
+	Subject       string
+	Failures      int32
+	LastFailureAt time.Time
+	LockedUntil   sql.NullTime
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
 	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	Kind      string
+	ChirpID   uuid.UUID
+	ReadAt    sql.NullTime
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type PersonalAccessToken struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	TokenHash   string
+	TokenPrefix string
+	Scopes      []string
+	ExpiresAt   sql.NullTime
+	LastUsedAt  sql.NullTime
+	RevokedAt   sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
 }
 
 type RefreshToken struct {
-	Token     string
+	Token      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	ReplacedBy sql.NullString
+}
+
+type Session struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	UserID     uuid.UUID
+	UserAgent  string
+	Ip         string
+	DeviceName string
+}
+
+type Subscription struct {
+	ID                uuid.UUID
+	UserID            uuid.UUID
+	Plan              string
+	Status            string
+	StartedAt         time.Time
+	CurrentPeriodEnd  time.Time
+	CancelledAt       sql.NullTime
+	GraceNoticeSentAt sql.NullTime
+	UpdatedAt         time.Time
+}
+
+type Tag struct {
+	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	Name      string
+}
+
+type TotpCredential struct {
+	UserID       uuid.UUID
+	CreatedAt    time.Time
+	Secret       string
+	EnabledAt    sql.NullTime
+	LastUsedStep int64
+}
+
+type UsedChallengeToken struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
 }
 
 type User struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	HashedPassword  string
+	IsChirpyRed     bool
+	DisplayName     string
+	Handle          string
+	EmailVerifiedAt sql.NullTime
+	Bio             string
+	Location        string
+	Website         string
+	AvatarKey       sql.NullString
+}
+
+type WebhookDelivery struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	EndpointID     uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastAttemptAt  sql.NullTime
+	ResponseStatus sql.NullInt32
+	LastError      sql.NullString
+	DeliveredAt    sql.NullTime
+}
+
+type WebhookEndpoint struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.NullUUID
+	Url       string
+	Secret    string
+	Events    []string
+}
+
+type WebhookEvent struct {
+	ID          uuid.UUID
+	Source      string
+	EventID     string
+	EventType   string
+	Payload     string
+	Status      string
+	Attempts    int32
+	LastError   sql.NullString
+	ReceivedAt  time.Time
+	ProcessedAt sql.NullTime
 }
@@ -7,27 +7,102 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
 const makeRefreshToken = `-- name: MakeRefreshToken :exec
-INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, family_id)
 VALUES(
     $1,
     NOW(),
     NOW(),
     $2,
-    NOW() + INTERVAL '60 DAYS'
+    NOW() + INTERVAL '60 DAYS',
+    $3
 )
 `
 
 type MakeRefreshTokenParams struct {
-	Token  string
-	UserID uuid.UUID
+	Token    string
+	UserID   uuid.UUID
+	FamilyID uuid.UUID
 }
 
 func (q *Queries) MakeRefreshToken(ctx context.Context, arg MakeRefreshTokenParams) error {
-	_, err := q.db.ExecContext(ctx, makeRefreshToken, arg.Token, arg.UserID)
+	_, err := q.db.ExecContext(ctx, makeRefreshToken, arg.Token, arg.UserID, arg.FamilyID)
 	return err
 }
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+WITH rotated AS (
+    UPDATE refresh_tokens
+    SET revoked_at = NOW(),
+        updated_at = NOW(),
+        replaced_by = $1
+    WHERE token = $2
+    AND revoked_at IS NULL
+    AND expires_at > NOW()
+    RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by
+), replacement AS (
+    INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, family_id)
+    SELECT replaced_by, NOW(), NOW(), user_id, NOW() + INTERVAL '60 DAYS', family_id
+    FROM rotated
+)
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by
+FROM rotated
+`
+
+type RotateRefreshTokenParams struct {
+	ReplacedBy sql.NullString
+	Token      string
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, rotateRefreshToken, arg.ReplacedBy, arg.Token)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+	)
+	return i, err
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1
+AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -7,25 +7,116 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
-const updateUser = `-- name: UpdateUser :one
+const updateUserPassword = `-- name: UpdateUserPassword :exec
 UPDATE users
-SET email = $1, hashed_password = $2, updated_at = NOW()
-WHERE id = $3
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1
 `
 
-type UpdateUserParams struct {
-	Email          string
-	HashedPassword string
+type UpdateUserPasswordParams struct {
 	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
+const setVerifiedEmail = `-- name: SetVerifiedEmail :one
+UPDATE users
+SET email = $2, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, handle, email_verified_at, bio, location, website, avatar_key
+`
+
+type SetVerifiedEmailParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) SetVerifiedEmail(ctx context.Context, arg SetVerifiedEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setVerifiedEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarKey,
+	)
+	return i, err
+}
+
+const patchUser = `-- name: PatchUser :one
+UPDATE users
+SET display_name = COALESCE($1, display_name),
+    handle = COALESCE($2, handle),
+    bio = COALESCE($3, bio),
+    location = COALESCE($4, location),
+    website = COALESCE($5, website),
+    updated_at = NOW()
+WHERE id = $6
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, handle, email_verified_at, bio, location, website, avatar_key
+`
+
+type PatchUserParams struct {
+	DisplayName sql.NullString
+	Handle      sql.NullString
+	Bio         sql.NullString
+	Location    sql.NullString
+	Website     sql.NullString
+	ID          uuid.UUID
+}
+
+func (q *Queries) PatchUser(ctx context.Context, arg PatchUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, patchUser, arg.DisplayName, arg.Handle, arg.Bio, arg.Location, arg.Website, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarKey,
+	)
+	return i, err
+}
+
+const setUserAvatar = `-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_key = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, handle, email_verified_at, bio, location, website, avatar_key
+`
+
+type SetUserAvatarParams struct {
+	ID        uuid.UUID
+	AvatarKey sql.NullString
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+func (q *Queries) SetUserAvatar(ctx context.Context, arg SetUserAvatarParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserAvatar, arg.ID, arg.AvatarKey)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -34,6 +125,13 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarKey,
 	)
 	return i, err
 }
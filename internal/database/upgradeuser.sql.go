@@ -11,13 +11,21 @@ import (
 	"github.com/google/uuid"
 )
 
-const upgradeUser = `-- name: UpgradeUser :exec
+const setUserChirpyRed = `-- name: SetUserChirpyRed :execrows
 UPDATE users
-SET is_chirpy_red = true
+SET is_chirpy_red = $2, updated_at = NOW()
 WHERE id = $1
 `
 
-func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, upgradeUser, id)
-	return err
+type SetUserChirpyRedParams struct {
+	ID          uuid.UUID
+	IsChirpyRed bool
+}
+
+func (q *Queries) SetUserChirpyRed(ctx context.Context, arg SetUserChirpyRedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserChirpyRed, arg.ID, arg.IsChirpyRed)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
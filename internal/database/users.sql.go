@@ -7,27 +7,61 @@ package database
 
 import (
 	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, display_name, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, handle, email_verified_at, bio, location, website, avatar_key
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	DisplayName    string
+	Handle         string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.DisplayName, arg.Handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarKey,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, handle, email_verified_at, bio, location, website, avatar_key
+FROM users
+WHERE handle = $1
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -36,6 +70,80 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DisplayName,
+		&i.Handle,
+		&i.EmailVerifiedAt,
+		&i.Bio,
+		&i.Location,
+		&i.Website,
+		&i.AvatarKey,
+	)
+	return i, err
+}
+
+const listUsersByHandles = `-- name: ListUsersByHandles :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, display_name, handle, email_verified_at, bio, location, website, avatar_key
+FROM users
+WHERE handle = ANY($1::text[])
+`
+
+func (q *Queries) ListUsersByHandles(ctx context.Context, handles []string) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.DisplayName,
+			&i.Handle,
+			&i.EmailVerifiedAt,
+			&i.Bio,
+			&i.Location,
+			&i.Website,
+			&i.AvatarKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserStats = `-- name: GetUserStats :one
+SELECT
+    (SELECT COUNT(*) FROM follows WHERE followee_id = $1) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follower_id = $1) AS following_count,
+    (SELECT COUNT(*) FROM chirps WHERE user_id = $1 AND deleted_at IS NULL) AS chirp_count
+`
+
+type GetUserStatsRow struct {
+	FollowerCount  int64
+	FollowingCount int64
+	ChirpCount     int64
+}
+
+func (q *Queries) GetUserStats(ctx context.Context, userID uuid.UUID) (GetUserStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserStats, userID)
+	var i GetUserStatsRow
+	err := row.Scan(
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.ChirpCount,
 	)
 	return i, err
 }
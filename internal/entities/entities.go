@@ -0,0 +1,121 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	maxTagLength    = 50
+	minHandleLength = 3
+	maxHandleLength = 15
+)
+
+// Hashtag is a #tag found in a chirp body. Start and End are rune offsets
+// covering the tag including its leading '#'.
+type Hashtag struct {
+	Tag   string
+	Start int
+	End   int
+}
+
+// Mention is an @handle found in a chirp body. Start and End are rune
+// offsets covering the handle including its leading '@'.
+type Mention struct {
+	Handle string
+	Start  int
+	End    int
+}
+
+// Hashtags returns the hashtags in body in the order they appear. A tag
+// starts with '#' at the beginning of the body or after a character that
+// cannot be part of a word, and runs over letters, digits and underscores.
+// Tags are lowercased; tags longer than 50 characters are ignored.
+func Hashtags(body string) []Hashtag {
+	hashtags := []Hashtag{}
+	scan(body, '#', isTagRune, func(word string, start, end int) {
+		if len([]rune(word)) > maxTagLength {
+			return
+		}
+		hashtags = append(hashtags, Hashtag{Tag: word, Start: start, End: end})
+	})
+	return hashtags
+}
+
+// Mentions returns the @handles in body in the order they appear, using the
+// same boundary rules as Hashtags so that addresses like walt@example.com
+// are not mentions. Handles are lowercased; anything that is not a valid
+// handle is ignored.
+func Mentions(body string) []Mention {
+	mentions := []Mention{}
+	scan(body, '@', isTagRune, func(word string, start, end int) {
+		if !validHandle(word) {
+			return
+		}
+		mentions = append(mentions, Mention{Handle: word, Start: start, End: end})
+	})
+	return mentions
+}
+
+// scan calls found with the lowercased word, start and end of every run of
+// word runes that follows a sigil at a word boundary.
+func scan(body string, sigil rune, isWordRune func(rune) bool, found func(word string, start, end int)) {
+	runes := []rune(body)
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != sigil || (i > 0 && isTagRune(runes[i-1])) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if end > i+1 {
+			found(strings.ToLower(string(runes[i+1:end])), i, end)
+		}
+		i = end - 1
+	}
+}
+
+// NormalizeTag turns user input such as "#Golang" into the stored form
+// "golang". It returns false if the result is not a valid tag.
+func NormalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if tag == "" || len([]rune(tag)) > maxTagLength {
+		return "", false
+	}
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return "", false
+		}
+	}
+	return tag, true
+}
+
+// NormalizeHandle turns user input such as "@Heisenberg" into the stored
+// form "heisenberg". Handles are 3 to 15 ASCII letters, digits or
+// underscores; it returns false for anything else.
+func NormalizeHandle(handle string) (string, bool) {
+	handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+	if !validHandle(handle) {
+		return "", false
+	}
+	return handle, true
+}
+
+func validHandle(handle string) bool {
+	if len(handle) < minHandleLength || len(handle) > maxHandleLength {
+		return false
+	}
+	for _, r := range handle {
+		if r != '_' && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
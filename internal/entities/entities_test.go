@@ -0,0 +1,101 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []Hashtag
+	}{
+		{
+			input:    "no tags here",
+			expected: []Hashtag{},
+		},
+		{
+			input:    "#Go is great, #go_lang!",
+			expected: []Hashtag{{Tag: "go", Start: 0, End: 3}, {Tag: "go_lang", Start: 14, End: 22}},
+		},
+		{
+			input:    "email#notatag # ## #ünïcode",
+			expected: []Hashtag{{Tag: "ünïcode", Start: 19, End: 27}},
+		},
+	}
+
+	for _, c := range cases {
+		hashtags := Hashtags(c.input)
+		if !reflect.DeepEqual(hashtags, c.expected) {
+			t.Errorf("Hashtags(%q) = %v, expected %v", c.input, hashtags, c.expected)
+		}
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{input: "#Golang", expected: "golang", valid: true},
+		{input: "chirpy_red", expected: "chirpy_red", valid: true},
+		{input: "#", valid: false},
+		{input: "two words", valid: false},
+	}
+
+	for _, c := range cases {
+		tag, ok := NormalizeTag(c.input)
+		if ok != c.valid || tag != c.expected {
+			t.Errorf("NormalizeTag(%q) = %q, %v, expected %q, %v", c.input, tag, ok, c.expected, c.valid)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []Mention
+	}{
+		{
+			input:    "no mentions here",
+			expected: []Mention{},
+		},
+		{
+			input:    "@Walt and @jesse_p, cook!",
+			expected: []Mention{{Handle: "walt", Start: 0, End: 5}, {Handle: "jesse_p", Start: 10, End: 18}},
+		},
+		{
+			input:    "walt@example.com @ @ab @wält @this_handle_is_too_long (@saul)",
+			expected: []Mention{{Handle: "saul", Start: 55, End: 60}},
+		},
+	}
+
+	for _, c := range cases {
+		mentions := Mentions(c.input)
+		if !reflect.DeepEqual(mentions, c.expected) {
+			t.Errorf("Mentions(%q) = %v, expected %v", c.input, mentions, c.expected)
+		}
+	}
+}
+
+func TestNormalizeHandle(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{input: "@Heisenberg", expected: "heisenberg", valid: true},
+		{input: "jesse_p", expected: "jesse_p", valid: true},
+		{input: "ab", valid: false},
+		{input: "wält", valid: false},
+		{input: "this_handle_is_too_long", valid: false},
+	}
+
+	for _, c := range cases {
+		handle, ok := NormalizeHandle(c.input)
+		if ok != c.valid || handle != c.expected {
+			t.Errorf("NormalizeHandle(%q) = %q, %v, expected %q, %v", c.input, handle, ok, c.expected, c.valid)
+		}
+	}
+}
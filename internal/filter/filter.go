@@ -0,0 +1,117 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type Action string
+
+const (
+	// ActionMask replaces the term with asterisks.
+	ActionMask Action = "mask"
+	// ActionReject refuses the whole chirp.
+	ActionReject Action = "reject"
+	// ActionFlag lets the chirp through unchanged but records it for review.
+	ActionFlag Action = "flag"
+)
+
+const mask = "****"
+
+func (action Action) Valid() bool {
+	switch action {
+	case ActionMask, ActionReject, ActionFlag:
+		return true
+	}
+	return false
+}
+
+type Rule struct {
+	Term   string
+	Action Action
+}
+
+type Result struct {
+	// Body is the input with every masked term replaced. Everything else,
+	// including whitespace and punctuation, is left as it was.
+	Body string
+	// Rejected holds the first term with ActionReject that matched, if any.
+	Rejected *Rule
+	// Flagged holds each distinct term with ActionFlag that matched.
+	Flagged []Rule
+}
+
+// NormalizeTerm lowercases and trims a banned term and checks that it is a
+// single word, since matching works on words.
+func NormalizeTerm(term string) (string, error) {
+	term = strings.ToLower(strings.TrimSpace(term))
+	if term == "" {
+		return "", fmt.Errorf("term must not be empty")
+	}
+	for _, r := range term {
+		if !isWordRune(r) {
+			return "", fmt.Errorf("term must be a single word of letters and digits")
+		}
+	}
+	return term, nil
+}
+
+// Apply runs the rules over body. Words are runs of letters and digits, so
+// punctuation next to a term ("kerfuffle!") does not hide it, and matching
+// ignores case.
+func Apply(body string, rules []Rule) Result {
+	byTerm := map[string]Rule{}
+	for _, rule := range rules {
+		byTerm[strings.ToLower(rule.Term)] = rule
+	}
+
+	result := Result{}
+	flagged := map[string]bool{}
+	var out strings.Builder
+	runes := []rune(body)
+
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			out.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := string(runes[i:end])
+
+		rule, ok := byTerm[strings.ToLower(word)]
+		switch {
+		case !ok:
+			out.WriteString(word)
+		case rule.Action == ActionMask:
+			out.WriteString(mask)
+		case rule.Action == ActionReject:
+			if result.Rejected == nil {
+				rejected := rule
+				result.Rejected = &rejected
+			}
+			out.WriteString(word)
+		case rule.Action == ActionFlag:
+			if !flagged[rule.Term] {
+				flagged[rule.Term] = true
+				result.Flagged = append(result.Flagged, rule)
+			}
+			out.WriteString(word)
+		default:
+			out.WriteString(word)
+		}
+		i = end
+	}
+
+	result.Body = out.String()
+	return result
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
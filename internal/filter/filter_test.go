@@ -0,0 +1,82 @@
+package filter
+
+import "testing"
+
+var testRules = []Rule{
+	{Term: "kerfuffle", Action: ActionMask},
+	{Term: "sharbert", Action: ActionMask},
+	{Term: "fornax", Action: ActionReject},
+	{Term: "crikey", Action: ActionFlag},
+}
+
+func TestApplyMasks(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "I had something interesting for breakfast",
+			expected: "I had something interesting for breakfast",
+		},
+		{
+			input:    "I hear Mastodon is better than Chirpy. sharbert I need to migrate",
+			expected: "I hear Mastodon is better than Chirpy. **** I need to migrate",
+		},
+		{
+			input:    "What a Kerfuffle!",
+			expected: "What a ****!",
+		},
+		{
+			input:    "  spaced   out\tkerfuffle\n(sharbert) ",
+			expected: "  spaced   out\t****\n(****) ",
+		},
+		{
+			input:    "kerfuffles are fine",
+			expected: "kerfuffles are fine",
+		},
+	}
+
+	for _, c := range cases {
+		result := Apply(c.input, testRules)
+		if result.Body != c.expected {
+			t.Errorf("Apply(%q) = %q, expected %q", c.input, result.Body, c.expected)
+		}
+		if result.Rejected != nil || len(result.Flagged) != 0 {
+			t.Errorf("Apply(%q) unexpectedly rejected or flagged", c.input)
+		}
+	}
+}
+
+func TestApplyRejectAndFlag(t *testing.T) {
+	result := Apply("FORNAX, crikey and crikey again", testRules)
+	if result.Rejected == nil || result.Rejected.Term != "fornax" {
+		t.Errorf("expected fornax to reject the chirp, got %v", result.Rejected)
+	}
+	if len(result.Flagged) != 1 || result.Flagged[0].Term != "crikey" {
+		t.Errorf("expected crikey to be flagged once, got %v", result.Flagged)
+	}
+}
+
+func TestNormalizeTerm(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{input: " Kerfuffle ", expected: "kerfuffle", valid: true},
+		{input: "", valid: false},
+		{input: "two words", valid: false},
+		{input: "bad!", valid: false},
+	}
+
+	for _, c := range cases {
+		term, err := NormalizeTerm(c.input)
+		if (err == nil) != c.valid {
+			t.Errorf("NormalizeTerm(%q) error = %v, expected valid = %v", c.input, err, c.valid)
+			continue
+		}
+		if term != c.expected {
+			t.Errorf("NormalizeTerm(%q) = %q, expected %q", c.input, term, c.expected)
+		}
+	}
+}
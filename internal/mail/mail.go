@@ -0,0 +1,171 @@
+// Package mail sends the emails chirpy needs, such as password resets,
+// through a Mailer chosen at startup.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	netmail "net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// encode renders msg as an RFC 5322 message. Addresses and subjects with
+// line breaks are rejected so they can't inject headers.
+func (msg Message) encode(from string, date time.Time) ([]byte, error) {
+	for _, header := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(header, "\r\n") {
+			return nil, fmt.Errorf("mail header contains a line break")
+		}
+	}
+	if _, err := netmail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
+
+// SMTPMailer sends messages through an SMTP server, using STARTTLS when the
+// server offers it.
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer returns a mailer for the server at host:port. Username and
+// password are optional.
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	mailer := &SMTPMailer{addr: host + ":" + port, from: from}
+	if username != "" {
+		mailer.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return mailer
+}
+
+func (mailer *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	encoded, err := msg.encode(mailer.from, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(mailer.addr, mailer.auth, mailer.from, []string{msg.To}, encoded)
+}
+
+// LogMailer writes messages to an io.Writer instead of sending them, which
+// is enough for local development.
+type LogMailer struct {
+	mu   sync.Mutex
+	out  io.Writer
+	from string
+}
+
+// NewLogMailer returns a mailer that writes to out, usually os.Stdout.
+func NewLogMailer(out io.Writer, from string) *LogMailer {
+	return &LogMailer{out: out, from: from}
+}
+
+func (mailer *LogMailer) Send(ctx context.Context, msg Message) error {
+	encoded, err := msg.encode(mailer.from, time.Now())
+	if err != nil {
+		return err
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	_, err = fmt.Fprintf(mailer.out, "----- mail -----\n%s\n----- end mail -----\n", bytes.ReplaceAll(encoded, []byte("\r\n"), []byte("\n")))
+	return err
+}
+
+// FileMailer drops each message into a directory as an .eml file. Tests
+// read them back with Messages.
+type FileMailer struct {
+	mu   sync.Mutex
+	dir  string
+	from string
+	sent int
+}
+
+// NewFileMailer returns a mailer that writes into dir, creating it if
+// needed.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (mailer *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	encoded, err := msg.encode(mailer.from, now)
+	if err != nil {
+		return err
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	mailer.sent++
+	name := fmt.Sprintf("%d-%06d.eml", now.UnixNano(), mailer.sent)
+	return os.WriteFile(filepath.Join(mailer.dir, name), encoded, 0o644)
+}
+
+// Messages reads back every message in the directory, oldest first.
+func (mailer *FileMailer) Messages() ([]Message, error) {
+	paths, err := filepath.Glob(filepath.Join(mailer.dir, "*.eml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	messages := []Message{}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := netmail.ReadMessage(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		body, err := io.ReadAll(parsed.Body)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, Message{
+			To:      parsed.Header.Get("To"),
+			Subject: parsed.Header.Get("Subject"),
+			Body:    strings.ReplaceAll(string(body), "\r\n", "\n"),
+		})
+	}
+	return messages, nil
+}
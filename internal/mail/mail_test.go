@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	mailer, err := NewFileMailer(t.TempDir(), "chirpy@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := []Message{
+		{To: "walt@breakingbad.com", Subject: "First", Body: "line one\nline two"},
+		{To: "jesse@breakingbad.com", Subject: "Second", Body: "yo"},
+	}
+	for _, msg := range sent {
+		if err := mailer.Send(context.Background(), msg); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	received, err := mailer.Messages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != len(sent) {
+		t.Fatalf("expected %d messages, got %d", len(sent), len(received))
+	}
+	for i := range sent {
+		if received[i] != sent[i] {
+			t.Errorf("message %d: expected %+v, got %+v", i, sent[i], received[i])
+		}
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var out bytes.Buffer
+	mailer := NewLogMailer(&out, "chirpy@example.com")
+	if err := mailer.Send(context.Background(), Message{To: "walt@breakingbad.com", Subject: "Hello", Body: "say my name"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"From: chirpy@example.com", "To: walt@breakingbad.com", "Subject: Hello", "say my name"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output %q does not contain %q", out.String(), want)
+		}
+	}
+}
+
+func TestHeaderInjection(t *testing.T) {
+	mailer := NewLogMailer(&bytes.Buffer{}, "chirpy@example.com")
+	for _, msg := range []Message{
+		{To: "walt@breakingbad.com\r\nBcc: everyone@example.com", Subject: "Hello"},
+		{To: "walt@breakingbad.com", Subject: "Hello\nBcc: everyone@example.com"},
+		{To: "not an address", Subject: "Hello"},
+	} {
+		if err := mailer.Send(context.Background(), msg); err == nil {
+			t.Errorf("expected %+v to be rejected", msg)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package plans
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Free is the plan of users without a subscription. Every config has it.
+const Free = "free"
+
+// Limits are what a plan entitles its users to.
+type Limits struct {
+	// MaxChirpLength is the longest chirp body in bytes.
+	MaxChirpLength int `json:"max_chirp_length"`
+	// EditWindowMinutes is how long after posting a chirp can be edited.
+	// Zero means chirps can't be edited.
+	EditWindowMinutes int `json:"edit_window_minutes"`
+	// ChirpsPerHour caps how many chirps, replies and quotes a user can
+	// post in any hour. Zero means no cap.
+	ChirpsPerHour int `json:"chirps_per_hour"`
+}
+
+// CanEdit reports whether the plan allows editing chirps at all.
+func (limits Limits) CanEdit() bool {
+	return limits.EditWindowMinutes > 0
+}
+
+// Config maps plan names to their limits.
+type Config struct {
+	plans map[string]Limits
+}
+
+// Default is used when no plan file is configured.
+func Default() *Config {
+	return &Config{plans: map[string]Limits{
+		Free: {
+			MaxChirpLength: 140,
+		},
+		"chirpy_red": {
+			MaxChirpLength:    280,
+			EditWindowMinutes: 30,
+		},
+	}}
+}
+
+// Load reads a JSON plan file of the form
+//
+//	{"plans": {"free": {"max_chirp_length": 140, ...}, ...}}
+//
+// Plans missing from the file are unknown; users on them get the free
+// plan.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := struct {
+		Plans map[string]Limits `json:"plans"`
+	}{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if _, ok := file.Plans[Free]; !ok {
+		return nil, fmt.Errorf("%s: the %q plan is required", path, Free)
+	}
+	for name, limits := range file.Plans {
+		if limits.MaxChirpLength < 1 {
+			return nil, fmt.Errorf("%s: plan %q: max_chirp_length must be positive", path, name)
+		}
+		if limits.EditWindowMinutes < 0 || limits.ChirpsPerHour < 0 {
+			return nil, fmt.Errorf("%s: plan %q: limits must not be negative", path, name)
+		}
+	}
+
+	return &Config{plans: file.Plans}, nil
+}
+
+// Limits returns the limits of plan, or of the free plan if plan is
+// unknown.
+func (config *Config) Limits(plan string) Limits {
+	if limits, ok := config.plans[plan]; ok {
+		return limits
+	}
+	return config.plans[Free]
+}
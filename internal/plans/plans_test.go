@@ -0,0 +1,66 @@
+package plans
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePlanFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plans.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("unable to write plan file: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writePlanFile(t, `{"plans": {
+		"free": {"max_chirp_length": 100, "chirps_per_hour": 5},
+		"chirpy_red": {"max_chirp_length": 500, "edit_window_minutes": 60}
+	}}`)
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	red := config.Limits("chirpy_red")
+	if red.MaxChirpLength != 500 || !red.CanEdit() || red.ChirpsPerHour != 0 {
+		t.Errorf("unexpected chirpy_red limits: %+v", red)
+	}
+	if free := config.Limits("enterprise"); free.MaxChirpLength != 100 || free.CanEdit() || free.ChirpsPerHour != 5 {
+		t.Errorf("unknown plan did not fall back to free: %+v", free)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	cases := map[string]string{
+		"no free plan":    `{"plans": {"chirpy_red": {"max_chirp_length": 280}}}`,
+		"no chirp length": `{"plans": {"free": {"chirps_per_hour": 5}}}`,
+		"negative limit":  `{"plans": {"free": {"max_chirp_length": 140, "chirps_per_hour": -1}}}`,
+		"not json":        `plans: free`,
+	}
+	for name, contents := range cases {
+		if _, err := Load(writePlanFile(t, contents)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil || !strings.Contains(err.Error(), "missing.json") {
+		t.Errorf("missing file: expected an error naming the file, got %v", err)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	config := Default()
+	// posting rates are only capped when a plan file asks for it
+	if free := config.Limits(Free); free.MaxChirpLength != 140 || free.CanEdit() || free.ChirpsPerHour != 0 {
+		t.Errorf("unexpected default free limits: %+v", free)
+	}
+	if red := config.Limits("chirpy_red"); red.MaxChirpLength <= 140 || !red.CanEdit() {
+		t.Errorf("chirpy_red should allow longer chirps and edits: %+v", red)
+	}
+}
@@ -0,0 +1,103 @@
+// Package storage keeps uploaded files, such as avatars, under string keys
+// like "avatars/<id>.png".
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Storage stores files and says where clients can fetch them.
+type Storage interface {
+	Put(ctx context.Context, key string, data io.Reader) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// Local stores files in a directory on disk. The server is expected to
+// serve that directory at baseURL.
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocal returns a Local storing files under dir, creating it if needed.
+func NewLocal(dir, baseURL string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// path maps key to a file under the storage directory, refusing keys that
+// would escape it.
+func (local *Local) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "\\") || path.IsAbs(key) || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(local.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes data to a temporary file first so a half written upload is
+// never served.
+func (local *Local) Put(ctx context.Context, key string, data io.Reader) error {
+	target, err := local.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// Delete removes the file for key. Deleting a missing file is not an error.
+func (local *Local) Delete(ctx context.Context, key string) error {
+	target, err := local.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (local *Local) URL(key string) string {
+	return local.baseURL + "/" + key
+}
+
+// ServeHTTP serves the stored files. Directory listings are not served.
+func (local *Local) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if strings.HasSuffix(request.URL.Path, "/") {
+		http.NotFound(writer, request)
+		return
+	}
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	http.FileServer(http.Dir(local.dir)).ServeHTTP(writer, request)
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	local, err := NewLocal(dir, "http://localhost:8080/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := local.Put(ctx, "avatars/walt.png", strings.NewReader("png bytes")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "avatars", "walt.png"))
+	if err != nil || string(data) != "png bytes" {
+		t.Fatalf("expected the file on disk, got %q %v", data, err)
+	}
+	if url := local.URL("avatars/walt.png"); url != "http://localhost:8080/media/avatars/walt.png" {
+		t.Errorf("unexpected url %q", url)
+	}
+
+	if err := local.Delete(ctx, "avatars/walt.png"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "avatars", "walt.png")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the file to be gone, got %v", err)
+	}
+	if err := local.Delete(ctx, "avatars/walt.png"); err != nil {
+		t.Errorf("deleting a missing file: %v", err)
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	local, err := NewLocal(t.TempDir(), "http://localhost:8080/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "../secret", "/etc/passwd", "avatars/../../secret", "avatars//walt.png", `avatars\walt.png`} {
+		if err := local.Put(context.Background(), key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,608 @@
+package store
+
+import (
+	"bytes"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	errUniqueViolation     = errors.New("store: unique constraint violation")
+	errForeignKeyViolation = errors.New("store: foreign key violation")
+	errCheckViolation      = errors.New("store: check constraint violation")
+)
+
+// Memory is an in-process Store. It mirrors the behaviour of the Postgres
+// schema (unique emails, cascading deletes, sql.ErrNoRows for missing rows)
+// so handlers behave the same against either backend.
+type Memory struct {
+	mu            sync.RWMutex
+	users         map[uuid.UUID]database.User
+	chirps        map[uuid.UUID]database.Chirp
+	refreshTokens map[string]database.RefreshToken
+	sessions      map[uuid.UUID]database.Session
+	accessTokens  map[uuid.UUID]database.PersonalAccessToken
+	totp          map[uuid.UUID]database.TotpCredential
+	recoveryCodes map[uuid.UUID]database.RecoveryCode
+	challenges    map[uuid.UUID]database.UsedChallengeToken
+	throttles     map[throttleKey]database.LoginThrottle
+	resetTokens   map[uuid.UUID]database.PasswordResetToken
+	emailTokens   map[uuid.UUID]database.EmailVerificationToken
+	follows       map[followKey]database.Follow
+	likes         map[likeKey]database.ChirpLike
+	filters       map[uuid.UUID]database.ChirpFilter
+	flags         map[uuid.UUID]database.ChirpFlag
+	tags          map[uuid.UUID]database.Tag
+	chirpTags     map[chirpTagKey]database.ChirpTag
+	mentions      map[mentionKey]database.ChirpMention
+	notifications map[uuid.UUID]database.Notification
+	webhookEvents map[uuid.UUID]database.WebhookEvent
+	subscriptions map[uuid.UUID]database.Subscription
+	endpoints     map[uuid.UUID]database.WebhookEndpoint
+	deliveries    map[uuid.UUID]database.WebhookDelivery
+}
+
+func NewMemory() *Memory {
+	m := &Memory{}
+	m.endpoints = map[uuid.UUID]database.WebhookEndpoint{}
+	m.deliveries = map[uuid.UUID]database.WebhookDelivery{}
+	m.clearUsers()
+	m.seedFilters()
+	m.tags = map[uuid.UUID]database.Tag{}
+	m.throttles = map[throttleKey]database.LoginThrottle{}
+	m.webhookEvents = map[uuid.UUID]database.WebhookEvent{}
+	return m
+}
+
+// clearUsers drops every user and, like the ON DELETE CASCADE foreign keys
+// in Postgres, everything that belongs to them.
+func (m *Memory) clearUsers() {
+	m.users = map[uuid.UUID]database.User{}
+	m.chirps = map[uuid.UUID]database.Chirp{}
+	m.refreshTokens = map[string]database.RefreshToken{}
+	m.sessions = map[uuid.UUID]database.Session{}
+	m.accessTokens = map[uuid.UUID]database.PersonalAccessToken{}
+	m.totp = map[uuid.UUID]database.TotpCredential{}
+	m.recoveryCodes = map[uuid.UUID]database.RecoveryCode{}
+	m.challenges = map[uuid.UUID]database.UsedChallengeToken{}
+	m.resetTokens = map[uuid.UUID]database.PasswordResetToken{}
+	m.emailTokens = map[uuid.UUID]database.EmailVerificationToken{}
+	m.follows = map[followKey]database.Follow{}
+	m.likes = map[likeKey]database.ChirpLike{}
+	m.flags = map[uuid.UUID]database.ChirpFlag{}
+	m.chirpTags = map[chirpTagKey]database.ChirpTag{}
+	m.mentions = map[mentionKey]database.ChirpMention{}
+	m.notifications = map[uuid.UUID]database.Notification{}
+	m.subscriptions = map[uuid.UUID]database.Subscription{}
+	m.clearUserEndpoints()
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func (m *Memory) Reset(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clearUsers()
+	return nil
+}
+
+func (m *Memory) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Email == arg.Email || user.Handle == arg.Handle {
+			return database.User{}, errUniqueViolation
+		}
+	}
+
+	createdAt := now()
+	user := database.User{
+		ID:             uuid.New(),
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		DisplayName:    arg.DisplayName,
+		Handle:         arg.Handle,
+	}
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *Memory) GetUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetUserByHandle(ctx context.Context, handle string) (database.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if user.Handle == handle {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) ListUsersByHandles(ctx context.Context, handles []string) ([]database.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := []database.User{}
+	for _, user := range m.users {
+		if slices.Contains(handles, user.Handle) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (m *Memory) UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return nil
+	}
+	user.HashedPassword = arg.HashedPassword
+	user.UpdatedAt = now()
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *Memory) SetVerifiedEmail(ctx context.Context, arg database.SetVerifiedEmailParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	for _, other := range m.users {
+		if other.ID != arg.ID && other.Email == arg.Email {
+			return database.User{}, errUniqueViolation
+		}
+	}
+
+	user.Email = arg.Email
+	user.EmailVerifiedAt = sql.NullTime{Time: now(), Valid: true}
+	user.UpdatedAt = now()
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *Memory) PatchUser(ctx context.Context, arg database.PatchUserParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	if arg.Handle.Valid {
+		for _, other := range m.users {
+			if other.ID != arg.ID && other.Handle == arg.Handle.String {
+				return database.User{}, errUniqueViolation
+			}
+		}
+		user.Handle = arg.Handle.String
+	}
+	if arg.DisplayName.Valid {
+		user.DisplayName = arg.DisplayName.String
+	}
+	if arg.Bio.Valid {
+		user.Bio = arg.Bio.String
+	}
+	if arg.Location.Valid {
+		user.Location = arg.Location.String
+	}
+	if arg.Website.Valid {
+		user.Website = arg.Website.String
+	}
+
+	user.UpdatedAt = now()
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *Memory) SetUserAvatar(ctx context.Context, arg database.SetUserAvatarParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	user.AvatarKey = arg.AvatarKey
+	user.UpdatedAt = now()
+	m.users[user.ID] = user
+	return user, nil
+}
+
+func (m *Memory) GetUserStats(ctx context.Context, userID uuid.UUID) (database.GetUserStatsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := database.GetUserStatsRow{}
+	for key := range m.follows {
+		if key.followeeID == userID {
+			stats.FollowerCount++
+		}
+		if key.followerID == userID {
+			stats.FollowingCount++
+		}
+	}
+	for _, chirp := range m.chirps {
+		if chirp.UserID == userID && !chirp.DeletedAt.Valid {
+			stats.ChirpCount++
+		}
+	}
+	return stats, nil
+}
+
+func (m *Memory) SetUserChirpyRed(ctx context.Context, arg database.SetUserChirpyRedParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[arg.ID]
+	if !ok {
+		return 0, nil
+	}
+	user.IsChirpyRed = arg.IsChirpyRed
+	user.UpdatedAt = now()
+	m.users[arg.ID] = user
+	return 1, nil
+}
+
+func (m *Memory) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createChirp(arg)
+}
+
+func (m *Memory) CreateChirpWithinRate(ctx context.Context, arg database.CreateChirpWithinRateParams) (database.Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	since := now().Add(-time.Hour)
+	posted := 0
+	for _, chirp := range m.chirps {
+		if chirp.UserID == arg.UserID && chirp.Kind != "rechirp" && chirp.CreatedAt.After(since) {
+			posted++
+		}
+	}
+	if posted >= int(arg.ChirpsPerHour) {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+
+	return m.createChirp(database.CreateChirpParams{
+		Body:            arg.Body,
+		UserID:          arg.UserID,
+		InReplyTo:       arg.InReplyTo,
+		Kind:            arg.Kind,
+		OriginalChirpID: arg.OriginalChirpID,
+	})
+}
+
+// createChirp inserts a chirp. The caller must hold the lock.
+func (m *Memory) createChirp(arg database.CreateChirpParams) (database.Chirp, error) {
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.Chirp{}, errForeignKeyViolation
+	}
+	for _, ref := range []uuid.NullUUID{arg.InReplyTo, arg.OriginalChirpID} {
+		if _, ok := m.chirps[ref.UUID]; ref.Valid && !ok {
+			return database.Chirp{}, errForeignKeyViolation
+		}
+	}
+	if arg.Kind == "rechirp" {
+		for _, chirp := range m.chirps {
+			if chirp.Kind == "rechirp" && matchLive(chirp) && chirp.UserID == arg.UserID && chirp.OriginalChirpID == arg.OriginalChirpID {
+				return database.Chirp{}, errUniqueViolation
+			}
+		}
+	}
+
+	createdAt := now()
+	chirp := database.Chirp{
+		ID:              uuid.New(),
+		CreatedAt:       createdAt,
+		UpdatedAt:       createdAt,
+		Body:            arg.Body,
+		UserID:          arg.UserID,
+		InReplyTo:       arg.InReplyTo,
+		Kind:            arg.Kind,
+		OriginalChirpID: arg.OriginalChirpID,
+	}
+	m.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (m *Memory) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	chirp, ok := m.chirps[id]
+	if !ok {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+func (m *Memory) ListChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	chirps := []database.Chirp{}
+	for _, chirp := range m.chirps {
+		if slices.Contains(ids, chirp.ID) {
+			chirps = append(chirps, chirp)
+		}
+	}
+	return chirps, nil
+}
+
+func (m *Memory) GetRechirp(ctx context.Context, arg database.GetRechirpParams) (database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, chirp := range m.chirps {
+		if chirp.Kind == "rechirp" && matchLive(chirp) && chirp.UserID == arg.UserID &&
+			chirp.OriginalChirpID.Valid && chirp.OriginalChirpID.UUID == arg.OriginalChirpID {
+			return chirp, nil
+		}
+	}
+	return database.Chirp{}, sql.ErrNoRows
+}
+
+func (m *Memory) ListChirpsAsc(ctx context.Context, arg database.ListChirpsAscParams) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.pageChirps(matchLive, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit, false), nil
+}
+
+func (m *Memory) ListChirpsDesc(ctx context.Context, arg database.ListChirpsDescParams) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.pageChirps(matchLive, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit, true), nil
+}
+
+func (m *Memory) ListChirpsByUserAsc(ctx context.Context, arg database.ListChirpsByUserAscParams) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.pageChirps(matchLiveByUser(arg.UserID), arg.CursorCreatedAt, arg.CursorID, arg.RowLimit, false), nil
+}
+
+func (m *Memory) ListChirpsByUserDesc(ctx context.Context, arg database.ListChirpsByUserDescParams) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.pageChirps(matchLiveByUser(arg.UserID), arg.CursorCreatedAt, arg.CursorID, arg.RowLimit, true), nil
+}
+
+func matchLive(chirp database.Chirp) bool {
+	return !chirp.DeletedAt.Valid
+}
+
+func matchLiveByUser(userID uuid.UUID) func(database.Chirp) bool {
+	return func(chirp database.Chirp) bool {
+		return matchLive(chirp) && chirp.UserID == userID
+	}
+}
+
+// pageChirps emulates the keyset queries on (created_at, id): it returns at
+// most limit matching chirps strictly after the cursor in the requested
+// direction. The caller must hold m.mu.
+func (m *Memory) pageChirps(match func(database.Chirp) bool, cursorCreatedAt time.Time, cursorID uuid.UUID, limit int32, desc bool) []database.Chirp {
+	chirps := []database.Chirp{}
+	for _, chirp := range m.chirps {
+		if !match(chirp) {
+			continue
+		}
+		cmp := compareKeyset(chirp.CreatedAt, chirp.ID, cursorCreatedAt, cursorID)
+		if (desc && cmp < 0) || (!desc && cmp > 0) {
+			chirps = append(chirps, chirp)
+		}
+	}
+
+	sort.Slice(chirps, func(i, j int) bool {
+		cmp := compareKeyset(chirps[i].CreatedAt, chirps[i].ID, chirps[j].CreatedAt, chirps[j].ID)
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	if int(limit) < len(chirps) {
+		chirps = chirps[:limit]
+	}
+	return chirps
+}
+
+// compareKeyset orders rows the way Postgres compares the row value
+// (created_at, id).
+func compareKeyset(aTime time.Time, aID uuid.UUID, bTime time.Time, bID uuid.UUID) int {
+	if aTime.Before(bTime) {
+		return -1
+	}
+	if aTime.After(bTime) {
+		return 1
+	}
+	return bytes.Compare(aID[:], bID[:])
+}
+
+func (m *Memory) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chirp, ok := m.chirps[id]
+	if !ok {
+		return nil
+	}
+	chirp.Body = ""
+	chirp.DeletedAt = sql.NullTime{Time: now(), Valid: true}
+	chirp.UpdatedAt = chirp.DeletedAt.Time
+	m.chirps[id] = chirp
+	return nil
+}
+
+func (m *Memory) UpdateChirpBody(ctx context.Context, arg database.UpdateChirpBodyParams) (database.Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chirp, ok := m.chirps[arg.ID]
+	if !ok || chirp.DeletedAt.Valid {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	chirp.Body = arg.Body
+	chirp.UpdatedAt = now()
+	m.chirps[arg.ID] = chirp
+	return chirp, nil
+}
+
+func (m *Memory) MakeRefreshToken(ctx context.Context, arg database.MakeRefreshTokenParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := m.sessions[arg.FamilyID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := m.refreshTokens[arg.Token]; ok {
+		return errUniqueViolation
+	}
+
+	createdAt := now()
+	m.refreshTokens[arg.Token] = database.RefreshToken{
+		Token:     arg.Token,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		UserID:    arg.UserID,
+		ExpiresAt: createdAt.Add(60 * 24 * time.Hour),
+		FamilyID:  arg.FamilyID,
+	}
+	return nil
+}
+
+func (m *Memory) CheckRefreshToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	refreshToken, ok := m.refreshTokens[token]
+	if !ok {
+		return database.RefreshToken{}, sql.ErrNoRows
+	}
+	return refreshToken, nil
+}
+
+func (m *Memory) GetUserFromRefreshToken(ctx context.Context, token string) (database.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	refreshToken, ok := m.refreshTokens[token]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	user, ok := m.users[refreshToken.UserID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (m *Memory) RevokeRefreshToken(ctx context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	refreshToken, ok := m.refreshTokens[token]
+	if !ok {
+		return nil
+	}
+	refreshToken.RevokedAt = sql.NullTime{Time: now(), Valid: true}
+	refreshToken.UpdatedAt = refreshToken.RevokedAt.Time
+	m.refreshTokens[token] = refreshToken
+	return nil
+}
+
+func (m *Memory) RotateRefreshToken(ctx context.Context, arg database.RotateRefreshTokenParams) (database.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	refreshToken, ok := m.refreshTokens[arg.Token]
+	rotatedAt := now()
+	if !ok || refreshToken.RevokedAt.Valid || !refreshToken.ExpiresAt.After(rotatedAt) {
+		return database.RefreshToken{}, sql.ErrNoRows
+	}
+	if _, ok := m.refreshTokens[arg.ReplacedBy.String]; ok || !arg.ReplacedBy.Valid {
+		return database.RefreshToken{}, errUniqueViolation
+	}
+
+	refreshToken.RevokedAt = sql.NullTime{Time: rotatedAt, Valid: true}
+	refreshToken.UpdatedAt = rotatedAt
+	refreshToken.ReplacedBy = arg.ReplacedBy
+	m.refreshTokens[arg.Token] = refreshToken
+	m.refreshTokens[arg.ReplacedBy.String] = database.RefreshToken{
+		Token:     arg.ReplacedBy.String,
+		CreatedAt: rotatedAt,
+		UpdatedAt: rotatedAt,
+		UserID:    refreshToken.UserID,
+		ExpiresAt: rotatedAt.Add(60 * 24 * time.Hour),
+		FamilyID:  refreshToken.FamilyID,
+	}
+	return refreshToken, nil
+}
+
+func (m *Memory) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.revokeTokens(func(token database.RefreshToken) bool {
+		return token.FamilyID == familyID
+	}), nil
+}
+
+func (m *Memory) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.revokeTokens(func(token database.RefreshToken) bool {
+		return token.UserID == userID
+	}), nil
+}
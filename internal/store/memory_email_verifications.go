@@ -0,0 +1,64 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+func (m *Memory) CreateEmailVerificationToken(ctx context.Context, arg database.CreateEmailVerificationTokenParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return errForeignKeyViolation
+	}
+	for _, token := range m.emailTokens {
+		if token.TokenHash == arg.TokenHash {
+			return errUniqueViolation
+		}
+	}
+
+	token := database.EmailVerificationToken{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UserID:    arg.UserID,
+		Email:     arg.Email,
+		TokenHash: arg.TokenHash,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	m.emailTokens[token.ID] = token
+	return nil
+}
+
+func (m *Memory) UseEmailVerificationToken(ctx context.Context, tokenHash string) (database.EmailVerificationToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := now()
+	for id, token := range m.emailTokens {
+		if token.TokenHash != tokenHash || token.UsedAt.Valid || !token.ExpiresAt.After(current) {
+			continue
+		}
+		token.UsedAt = sql.NullTime{Time: current, Valid: true}
+		m.emailTokens[id] = token
+		return token, nil
+	}
+	return database.EmailVerificationToken{}, sql.ErrNoRows
+}
+
+func (m *Memory) InvalidateEmailVerificationTokens(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usedAt := sql.NullTime{Time: now(), Valid: true}
+	for id, token := range m.emailTokens {
+		if token.UserID == userID && !token.UsedAt.Valid {
+			token.UsedAt = usedAt
+			m.emailTokens[id] = token
+		}
+	}
+	return nil
+}
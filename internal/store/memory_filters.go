@@ -0,0 +1,164 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// seedFilters installs the same banned terms as the 011_chirp_filters
+// migration.
+func (m *Memory) seedFilters() {
+	m.filters = map[uuid.UUID]database.ChirpFilter{}
+	for _, term := range []string{"kerfuffle", "sharbert", "fornax"} {
+		createdAt := now()
+		filter := database.ChirpFilter{
+			ID:        uuid.New(),
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+			Term:      term,
+			Action:    "mask",
+		}
+		m.filters[filter.ID] = filter
+	}
+}
+
+func (m *Memory) CreateChirpFilter(ctx context.Context, arg database.CreateChirpFilterParams) (database.ChirpFilter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, filter := range m.filters {
+		if filter.Term == arg.Term {
+			return database.ChirpFilter{}, errUniqueViolation
+		}
+	}
+
+	createdAt := now()
+	filter := database.ChirpFilter{
+		ID:        uuid.New(),
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		Term:      arg.Term,
+		Action:    arg.Action,
+	}
+	m.filters[filter.ID] = filter
+	return filter, nil
+}
+
+func (m *Memory) ListChirpFilters(ctx context.Context) ([]database.ChirpFilter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	filters := []database.ChirpFilter{}
+	for _, filter := range m.filters {
+		filters = append(filters, filter)
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return filters[i].Term < filters[j].Term
+	})
+	return filters, nil
+}
+
+func (m *Memory) GetChirpFilter(ctx context.Context, id uuid.UUID) (database.ChirpFilter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	filter, ok := m.filters[id]
+	if !ok {
+		return database.ChirpFilter{}, sql.ErrNoRows
+	}
+	return filter, nil
+}
+
+func (m *Memory) UpdateChirpFilter(ctx context.Context, arg database.UpdateChirpFilterParams) (database.ChirpFilter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	filter, ok := m.filters[arg.ID]
+	if !ok {
+		return database.ChirpFilter{}, sql.ErrNoRows
+	}
+	for _, other := range m.filters {
+		if other.ID != arg.ID && other.Term == arg.Term {
+			return database.ChirpFilter{}, errUniqueViolation
+		}
+	}
+
+	filter.Term = arg.Term
+	filter.Action = arg.Action
+	filter.UpdatedAt = now()
+	m.filters[filter.ID] = filter
+	return filter, nil
+}
+
+func (m *Memory) DeleteChirpFilter(ctx context.Context, id uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.filters[id]; !ok {
+		return 0, nil
+	}
+	delete(m.filters, id)
+
+	for flagID, flag := range m.flags {
+		if flag.FilterID.Valid && flag.FilterID.UUID == id {
+			flag.FilterID = uuid.NullUUID{}
+			m.flags[flagID] = flag
+		}
+	}
+	return 1, nil
+}
+
+func (m *Memory) CreateChirpFlag(ctx context.Context, arg database.CreateChirpFlagParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := m.filters[arg.FilterID.UUID]; arg.FilterID.Valid && !ok {
+		return errForeignKeyViolation
+	}
+
+	flag := database.ChirpFlag{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		ChirpID:   arg.ChirpID,
+		FilterID:  arg.FilterID,
+		Term:      arg.Term,
+	}
+	m.flags[flag.ID] = flag
+	return nil
+}
+
+func (m *Memory) ListUnresolvedChirpFlags(ctx context.Context) ([]database.ChirpFlag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	flags := []database.ChirpFlag{}
+	for _, flag := range m.flags {
+		if !flag.ResolvedAt.Valid {
+			flags = append(flags, flag)
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].CreatedAt.Before(flags[j].CreatedAt)
+	})
+	return flags, nil
+}
+
+func (m *Memory) ResolveChirpFlag(ctx context.Context, id uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	flag, ok := m.flags[id]
+	if !ok || flag.ResolvedAt.Valid {
+		return 0, nil
+	}
+	flag.ResolvedAt = sql.NullTime{Time: now(), Valid: true}
+	m.flags[id] = flag
+	return 1, nil
+}
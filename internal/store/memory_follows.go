@@ -0,0 +1,103 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+type followKey struct {
+	followerID uuid.UUID
+	followeeID uuid.UUID
+}
+
+func (m *Memory) FollowUser(ctx context.Context, arg database.FollowUserParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if arg.FollowerID == arg.FolloweeID {
+		return errCheckViolation
+	}
+	if _, ok := m.users[arg.FollowerID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := m.users[arg.FolloweeID]; !ok {
+		return errForeignKeyViolation
+	}
+
+	key := followKey{followerID: arg.FollowerID, followeeID: arg.FolloweeID}
+	if _, ok := m.follows[key]; ok {
+		return nil
+	}
+	m.follows[key] = database.Follow{
+		FollowerID: arg.FollowerID,
+		FolloweeID: arg.FolloweeID,
+		CreatedAt:  now(),
+	}
+	return nil
+}
+
+func (m *Memory) UnfollowUser(ctx context.Context, arg database.UnfollowUserParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.follows, followKey{followerID: arg.FollowerID, followeeID: arg.FolloweeID})
+	return nil
+}
+
+func (m *Memory) ListFollowers(ctx context.Context, arg database.ListFollowersParams) ([]database.Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	follows := []database.Follow{}
+	for _, follow := range m.follows {
+		if follow.FolloweeID == arg.FolloweeID &&
+			compareKeyset(follow.CreatedAt, follow.FollowerID, arg.CursorCreatedAt, arg.CursorID) < 0 {
+			follows = append(follows, follow)
+		}
+	}
+	return limitFollows(follows, arg.RowLimit, func(follow database.Follow) uuid.UUID {
+		return follow.FollowerID
+	}), nil
+}
+
+func (m *Memory) ListFollowing(ctx context.Context, arg database.ListFollowingParams) ([]database.Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	follows := []database.Follow{}
+	for _, follow := range m.follows {
+		if follow.FollowerID == arg.FollowerID &&
+			compareKeyset(follow.CreatedAt, follow.FolloweeID, arg.CursorCreatedAt, arg.CursorID) < 0 {
+			follows = append(follows, follow)
+		}
+	}
+	return limitFollows(follows, arg.RowLimit, func(follow database.Follow) uuid.UUID {
+		return follow.FolloweeID
+	}), nil
+}
+
+// limitFollows sorts follows newest first by (created_at, keyID) and keeps
+// at most limit of them.
+func limitFollows(follows []database.Follow, limit int32, keyID func(database.Follow) uuid.UUID) []database.Follow {
+	sort.Slice(follows, func(i, j int) bool {
+		return compareKeyset(follows[i].CreatedAt, keyID(follows[i]), follows[j].CreatedAt, keyID(follows[j])) > 0
+	})
+	if int(limit) < len(follows) {
+		follows = follows[:limit]
+	}
+	return follows
+}
+
+func (m *Memory) ListTimeline(ctx context.Context, arg database.ListTimelineParams) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	followed := func(chirp database.Chirp) bool {
+		_, ok := m.follows[followKey{followerID: arg.FollowerID, followeeID: chirp.UserID}]
+		return ok && matchLive(chirp)
+	}
+	return m.pageChirps(followed, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit, true), nil
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+type likeKey struct {
+	userID  uuid.UUID
+	chirpID uuid.UUID
+}
+
+func (m *Memory) LikeChirp(ctx context.Context, arg database.LikeChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return errForeignKeyViolation
+	}
+
+	key := likeKey{userID: arg.UserID, chirpID: arg.ChirpID}
+	if _, ok := m.likes[key]; ok {
+		return nil
+	}
+	m.likes[key] = database.ChirpLike{
+		UserID:    arg.UserID,
+		ChirpID:   arg.ChirpID,
+		CreatedAt: now(),
+	}
+	return nil
+}
+
+func (m *Memory) UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.likes, likeKey{userID: arg.UserID, chirpID: arg.ChirpID})
+	return nil
+}
+
+func (m *Memory) CountChirpLikes(ctx context.Context, chirpIds []uuid.UUID) ([]database.CountChirpLikesRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := map[uuid.UUID]int64{}
+	for key := range m.likes {
+		if slices.Contains(chirpIds, key.chirpID) {
+			counts[key.chirpID]++
+		}
+	}
+
+	rows := []database.CountChirpLikesRow{}
+	for chirpID, count := range counts {
+		rows = append(rows, database.CountChirpLikesRow{ChirpID: chirpID, LikeCount: count})
+	}
+	return rows, nil
+}
+
+func (m *Memory) ListLikedChirpIDs(ctx context.Context, arg database.ListLikedChirpIDsParams) ([]uuid.UUID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	liked := []uuid.UUID{}
+	for _, chirpID := range arg.ChirpIds {
+		if _, ok := m.likes[likeKey{userID: arg.UserID, chirpID: chirpID}]; ok && !slices.Contains(liked, chirpID) {
+			liked = append(liked, chirpID)
+		}
+	}
+	return liked, nil
+}
+
+func (m *Memory) ListLikesByUser(ctx context.Context, arg database.ListLikesByUserParams) ([]database.ListLikesByUserRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rows := []database.ListLikesByUserRow{}
+	for key, like := range m.likes {
+		chirp := m.chirps[key.chirpID]
+		if key.userID != arg.UserID || !matchLive(chirp) {
+			continue
+		}
+		if compareKeyset(like.CreatedAt, like.ChirpID, arg.CursorCreatedAt, arg.CursorID) < 0 {
+			rows = append(rows, database.ListLikesByUserRow{Chirp: chirp, LikedAt: like.CreatedAt})
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return compareKeyset(rows[i].LikedAt, rows[i].Chirp.ID, rows[j].LikedAt, rows[j].Chirp.ID) > 0
+	})
+	if int(arg.RowLimit) < len(rows) {
+		rows = rows[:arg.RowLimit]
+	}
+	return rows, nil
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"bytes"
+	"chirpy/internal/database"
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+type mentionKey struct {
+	chirpID     uuid.UUID
+	startOffset int32
+}
+
+func (m *Memory) CreateChirpMention(ctx context.Context, arg database.CreateChirpMentionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := m.users[arg.UserID]; !ok {
+		return errForeignKeyViolation
+	}
+
+	key := mentionKey{chirpID: arg.ChirpID, startOffset: arg.StartOffset}
+	if _, ok := m.mentions[key]; ok {
+		return nil
+	}
+	m.mentions[key] = database.ChirpMention{
+		ChirpID:     arg.ChirpID,
+		UserID:      arg.UserID,
+		StartOffset: arg.StartOffset,
+		EndOffset:   arg.EndOffset,
+	}
+	return nil
+}
+
+func (m *Memory) DeleteChirpMentions(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.mentions {
+		if key.chirpID == chirpID {
+			delete(m.mentions, key)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ListChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]database.ChirpMention, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rows := []database.ChirpMention{}
+	for _, mention := range m.mentions {
+		if slices.Contains(chirpIds, mention.ChirpID) {
+			rows = append(rows, mention)
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if cmp := bytes.Compare(rows[i].ChirpID[:], rows[j].ChirpID[:]); cmp != 0 {
+			return cmp < 0
+		}
+		return rows[i].StartOffset < rows[j].StartOffset
+	})
+	return rows, nil
+}
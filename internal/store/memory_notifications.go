@@ -0,0 +1,105 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+func (m *Memory) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, userID := range []uuid.UUID{arg.UserID, arg.ActorID} {
+		if _, ok := m.users[userID]; !ok {
+			return errForeignKeyViolation
+		}
+	}
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return errForeignKeyViolation
+	}
+	if arg.Kind != "mention" {
+		return errCheckViolation
+	}
+
+	notification := database.Notification{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UserID:    arg.UserID,
+		ActorID:   arg.ActorID,
+		Kind:      arg.Kind,
+		ChirpID:   arg.ChirpID,
+	}
+	m.notifications[notification.ID] = notification
+	return nil
+}
+
+func (m *Memory) ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	notifications := []database.Notification{}
+	for _, notification := range m.notifications {
+		if notification.UserID != arg.UserID || (arg.UnreadOnly && notification.ReadAt.Valid) {
+			continue
+		}
+		if compareKeyset(notification.CreatedAt, notification.ID, arg.CursorCreatedAt, arg.CursorID) < 0 {
+			notifications = append(notifications, notification)
+		}
+	}
+
+	sort.Slice(notifications, func(i, j int) bool {
+		return compareKeyset(notifications[i].CreatedAt, notifications[i].ID, notifications[j].CreatedAt, notifications[j].ID) > 0
+	})
+	if int(arg.RowLimit) < len(notifications) {
+		notifications = notifications[:arg.RowLimit]
+	}
+	return notifications, nil
+}
+
+func (m *Memory) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var count int64
+	for _, notification := range m.notifications {
+		if notification.UserID == userID && !notification.ReadAt.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *Memory) MarkNotificationRead(ctx context.Context, arg database.MarkNotificationReadParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notification, ok := m.notifications[arg.ID]
+	if !ok || notification.UserID != arg.UserID {
+		return 0, nil
+	}
+	if !notification.ReadAt.Valid {
+		notification.ReadAt = sql.NullTime{Time: now(), Valid: true}
+	}
+	m.notifications[arg.ID] = notification
+	return 1, nil
+}
+
+func (m *Memory) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var marked int64
+	readAt := sql.NullTime{Time: now(), Valid: true}
+	for id, notification := range m.notifications {
+		if notification.UserID == userID && !notification.ReadAt.Valid {
+			notification.ReadAt = readAt
+			m.notifications[id] = notification
+			marked++
+		}
+	}
+	return marked, nil
+}
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+func (m *Memory) CreateWebhookEndpoint(ctx context.Context, arg database.CreateWebhookEndpointParams) (database.WebhookEndpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID.UUID]; arg.UserID.Valid && !ok {
+		return database.WebhookEndpoint{}, errForeignKeyViolation
+	}
+
+	endpoint := database.WebhookEndpoint{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UserID:    arg.UserID,
+		Url:       arg.Url,
+		Secret:    arg.Secret,
+		Events:    slices.Clone(arg.Events),
+	}
+	m.endpoints[endpoint.ID] = endpoint
+	return endpoint, nil
+}
+
+func (m *Memory) GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (database.WebhookEndpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	endpoint, ok := m.endpoints[id]
+	if !ok {
+		return database.WebhookEndpoint{}, sql.ErrNoRows
+	}
+	return endpoint, nil
+}
+
+func (m *Memory) ListWebhookEndpoints(ctx context.Context, userID uuid.NullUUID) ([]database.WebhookEndpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedEndpoints(func(endpoint database.WebhookEndpoint) bool {
+		return endpoint.UserID == userID
+	}), nil
+}
+
+func (m *Memory) ListWebhookEndpointsForEvent(ctx context.Context, arg database.ListWebhookEndpointsForEventParams) ([]database.WebhookEndpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedEndpoints(func(endpoint database.WebhookEndpoint) bool {
+		owned := !endpoint.UserID.Valid || (arg.UserID.Valid && endpoint.UserID.UUID == arg.UserID.UUID)
+		return owned && slices.Contains(endpoint.Events, arg.EventType)
+	}), nil
+}
+
+// sortedEndpoints returns the endpoints that match, oldest first. The caller
+// must hold the lock.
+func (m *Memory) sortedEndpoints(match func(database.WebhookEndpoint) bool) []database.WebhookEndpoint {
+	endpoints := []database.WebhookEndpoint{}
+	for _, endpoint := range m.endpoints {
+		if match(endpoint) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return compareKeyset(endpoints[i].CreatedAt, endpoints[i].ID, endpoints[j].CreatedAt, endpoints[j].ID) < 0
+	})
+	return endpoints
+}
+
+func (m *Memory) DeleteWebhookEndpoint(ctx context.Context, id uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.endpoints[id]; !ok {
+		return 0, nil
+	}
+	delete(m.endpoints, id)
+	for deliveryID, delivery := range m.deliveries {
+		if delivery.EndpointID == id {
+			delete(m.deliveries, deliveryID)
+		}
+	}
+	return 1, nil
+}
+
+func (m *Memory) CreateWebhookDelivery(ctx context.Context, arg database.CreateWebhookDeliveryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.endpoints[arg.EndpointID]; !ok {
+		return errForeignKeyViolation
+	}
+
+	createdAt := now()
+	delivery := database.WebhookDelivery{
+		ID:            uuid.New(),
+		CreatedAt:     createdAt,
+		EndpointID:    arg.EndpointID,
+		EventID:       arg.EventID,
+		EventType:     arg.EventType,
+		Payload:       arg.Payload,
+		Status:        "pending",
+		NextAttemptAt: createdAt,
+	}
+	m.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+func (m *Memory) GetWebhookDelivery(ctx context.Context, id uuid.UUID) (database.WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	delivery, ok := m.deliveries[id]
+	if !ok {
+		return database.WebhookDelivery{}, sql.ErrNoRows
+	}
+	return delivery, nil
+}
+
+func (m *Memory) ClaimWebhookDeliveries(ctx context.Context, arg database.ClaimWebhookDeliveriesParams) ([]database.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	due := []database.WebhookDelivery{}
+	for _, delivery := range m.deliveries {
+		if delivery.Status == "pending" && !delivery.NextAttemptAt.After(arg.Now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if int(arg.RowLimit) < len(due) {
+		due = due[:arg.RowLimit]
+	}
+
+	for i := range due {
+		due[i].NextAttemptAt = arg.LeaseUntil
+		m.deliveries[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (m *Memory) RecordWebhookDeliveryAttempt(ctx context.Context, arg database.RecordWebhookDeliveryAttemptParams) (database.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch arg.Status {
+	case "pending", "succeeded", "dead":
+	default:
+		return database.WebhookDelivery{}, errCheckViolation
+	}
+
+	delivery, ok := m.deliveries[arg.ID]
+	if !ok {
+		return database.WebhookDelivery{}, sql.ErrNoRows
+	}
+	current := now()
+	delivery.Status = arg.Status
+	delivery.NextAttemptAt = arg.NextAttemptAt
+	delivery.ResponseStatus = arg.ResponseStatus
+	delivery.LastError = arg.LastError
+	delivery.Attempts++
+	delivery.LastAttemptAt = sql.NullTime{Time: current, Valid: true}
+	delivery.DeliveredAt = sql.NullTime{}
+	if arg.Status == "succeeded" {
+		delivery.DeliveredAt = sql.NullTime{Time: current, Valid: true}
+	}
+	m.deliveries[arg.ID] = delivery
+	return delivery, nil
+}
+
+func (m *Memory) ListWebhookDeliveries(ctx context.Context, arg database.ListWebhookDeliveriesParams) ([]database.WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deliveries := []database.WebhookDelivery{}
+	for _, delivery := range m.deliveries {
+		if delivery.EndpointID == arg.EndpointID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		a, b := deliveries[i], deliveries[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	if int(arg.RowLimit) < len(deliveries) {
+		deliveries = deliveries[:arg.RowLimit]
+	}
+	return deliveries, nil
+}
+
+func (m *Memory) RetryWebhookDelivery(ctx context.Context, id uuid.UUID) (database.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery, ok := m.deliveries[id]
+	if !ok || delivery.Status != "dead" {
+		return database.WebhookDelivery{}, sql.ErrNoRows
+	}
+	delivery.Status = "pending"
+	delivery.NextAttemptAt = now()
+	m.deliveries[id] = delivery
+	return delivery, nil
+}
+
+// clearUserEndpoints drops the endpoints that belong to a user, and their
+// deliveries, leaving the ones admins registered. The caller must hold the
+// lock.
+func (m *Memory) clearUserEndpoints() {
+	for id, endpoint := range m.endpoints {
+		if endpoint.UserID.Valid {
+			delete(m.endpoints, id)
+		}
+	}
+	for id, delivery := range m.deliveries {
+		if _, ok := m.endpoints[delivery.EndpointID]; !ok {
+			delete(m.deliveries, id)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+func (m *Memory) CreatePasswordResetToken(ctx context.Context, arg database.CreatePasswordResetTokenParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return errForeignKeyViolation
+	}
+	for _, token := range m.resetTokens {
+		if token.TokenHash == arg.TokenHash {
+			return errUniqueViolation
+		}
+	}
+
+	token := database.PasswordResetToken{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UserID:    arg.UserID,
+		TokenHash: arg.TokenHash,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	m.resetTokens[token.ID] = token
+	return nil
+}
+
+func (m *Memory) UsePasswordResetToken(ctx context.Context, tokenHash string) (database.PasswordResetToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := now()
+	for id, token := range m.resetTokens {
+		if token.TokenHash != tokenHash || token.UsedAt.Valid || !token.ExpiresAt.After(current) {
+			continue
+		}
+		token.UsedAt = sql.NullTime{Time: current, Valid: true}
+		m.resetTokens[id] = token
+		return token, nil
+	}
+	return database.PasswordResetToken{}, sql.ErrNoRows
+}
+
+func (m *Memory) InvalidatePasswordResetTokens(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	usedAt := sql.NullTime{Time: now(), Valid: true}
+	for id, token := range m.resetTokens {
+		if token.UserID == userID && !token.UsedAt.Valid {
+			token.UsedAt = usedAt
+			m.resetTokens[id] = token
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SearchChirps approximates the Postgres full-text query: every word in the
+// search must appear in the chirp (case-insensitively, without stemming),
+// rank is the number of matching words and matches are wrapped in <mark>.
+func (m *Memory) SearchChirps(ctx context.Context, arg database.SearchChirpsParams) ([]database.SearchChirpsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	terms := map[string]bool{}
+	for _, word := range searchWords(arg.Search) {
+		terms[word] = true
+	}
+
+	rows := []database.SearchChirpsRow{}
+	if len(terms) == 0 {
+		return rows, nil
+	}
+
+	for _, chirp := range m.chirps {
+		if !matchLive(chirp) ||
+			(arg.AuthorID.Valid && chirp.UserID != arg.AuthorID.UUID) ||
+			(arg.Since.Valid && chirp.CreatedAt.Before(arg.Since.Time)) ||
+			(arg.Until.Valid && !chirp.CreatedAt.Before(arg.Until.Time)) {
+			continue
+		}
+
+		found := map[string]bool{}
+		hits := 0
+		for _, word := range searchWords(chirp.Body) {
+			if terms[word] {
+				found[word] = true
+				hits++
+			}
+		}
+		if len(found) != len(terms) {
+			continue
+		}
+
+		rows = append(rows, database.SearchChirpsRow{
+			Chirp:     chirp,
+			Rank:      float32(hits),
+			Highlight: highlightWords(chirp.Body, terms),
+		})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Rank != rows[j].Rank {
+			return rows[i].Rank > rows[j].Rank
+		}
+		return compareKeyset(rows[i].Chirp.CreatedAt, rows[i].Chirp.ID, rows[j].Chirp.CreatedAt, rows[j].Chirp.ID) > 0
+	})
+	return pageRows(rows, arg.RowLimit, arg.RowOffset), nil
+}
+
+func (m *Memory) SearchUsers(ctx context.Context, arg database.SearchUsersParams) ([]database.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := []database.User{}
+	for _, user := range m.users {
+		if likePrefix(strings.ToLower(user.DisplayName), arg.Pattern) || likePrefix(user.Handle, arg.Pattern) {
+			users = append(users, user)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Handle < users[j].Handle
+	})
+	return pageRows(users, arg.RowLimit, arg.RowOffset), nil
+}
+
+func pageRows[T any](rows []T, limit, offset int32) []T {
+	if int(offset) >= len(rows) {
+		return []T{}
+	}
+	rows = rows[offset:]
+	if int(limit) < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// likePrefix evaluates value LIKE pattern for the patterns SearchUsers is
+// given: an escaped prefix followed by a single trailing %.
+func likePrefix(value, pattern string) bool {
+	prefix := strings.TrimSuffix(pattern, "%")
+	replacer := strings.NewReplacer(`\%`, "%", `\_`, "_", `\\`, `\`)
+	return strings.HasPrefix(value, replacer.Replace(prefix))
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func highlightWords(body string, terms map[string]bool) string {
+	var out strings.Builder
+	runes := []rune(body)
+	for i := 0; i < len(runes); {
+		end := i
+		for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+			end++
+		}
+		if end == i {
+			out.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+
+		word := string(runes[i:end])
+		if terms[strings.ToLower(word)] {
+			out.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			out.WriteString(html.EscapeString(word))
+		}
+		i = end
+	}
+	return out.String()
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"bytes"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+func (m *Memory) CreateSession(ctx context.Context, arg database.CreateSessionParams) (database.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.Session{}, errForeignKeyViolation
+	}
+
+	createdAt := now()
+	session := database.Session{
+		ID:         uuid.New(),
+		CreatedAt:  createdAt,
+		LastUsedAt: createdAt,
+		UserID:     arg.UserID,
+		UserAgent:  arg.UserAgent,
+		Ip:         arg.Ip,
+		DeviceName: arg.DeviceName,
+	}
+	m.sessions[session.ID] = session
+	return session, nil
+}
+
+func (m *Memory) TouchSession(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil
+	}
+	session.LastUsedAt = now()
+	m.sessions[id] = session
+	return nil
+}
+
+// ListActiveSessions returns the user's sessions that still hold a live
+// refresh token, most recently used first.
+func (m *Memory) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]database.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	live := map[uuid.UUID]bool{}
+	currentTime := now()
+	for _, token := range m.refreshTokens {
+		if !token.RevokedAt.Valid && token.ExpiresAt.After(currentTime) {
+			live[token.FamilyID] = true
+		}
+	}
+
+	sessions := []database.Session{}
+	for _, session := range m.sessions {
+		if session.UserID == userID && live[session.ID] {
+			sessions = append(sessions, session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return bytes.Compare(sessions[i].ID[:], sessions[j].ID[:]) > 0
+	})
+	return sessions, nil
+}
+
+func (m *Memory) RevokeSession(ctx context.Context, arg database.RevokeSessionParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.revokeTokens(func(token database.RefreshToken) bool {
+		return token.FamilyID == arg.FamilyID && token.UserID == arg.UserID
+	}), nil
+}
+
+func (m *Memory) RevokeOtherSessions(ctx context.Context, arg database.RevokeOtherSessionsParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.revokeTokens(func(token database.RefreshToken) bool {
+		return token.UserID == arg.UserID && token.FamilyID != arg.KeepSessionID
+	}), nil
+}
+
+// revokeTokens revokes every live refresh token that matches and returns
+// how many it revoked. The caller must hold m.mu.
+func (m *Memory) revokeTokens(match func(database.RefreshToken) bool) int64 {
+	var revoked int64
+	revokedAt := sql.NullTime{Time: now(), Valid: true}
+	for key, token := range m.refreshTokens {
+		if token.RevokedAt.Valid || !match(token) {
+			continue
+		}
+		token.RevokedAt = revokedAt
+		token.UpdatedAt = revokedAt.Time
+		m.refreshTokens[key] = token
+		revoked++
+	}
+	return revoked
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// subscriptions are keyed by user ID, which is unique in the table.
+
+func (m *Memory) GetSubscription(ctx context.Context, userID uuid.UUID) (database.Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subscription, ok := m.subscriptions[userID]
+	if !ok {
+		return database.Subscription{}, sql.ErrNoRows
+	}
+	return subscription, nil
+}
+
+func (m *Memory) StartSubscription(ctx context.Context, arg database.StartSubscriptionParams) (database.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.Subscription{}, errForeignKeyViolation
+	}
+
+	current := now()
+	subscription, ok := m.subscriptions[arg.UserID]
+	if !ok {
+		subscription = database.Subscription{
+			ID:               uuid.New(),
+			UserID:           arg.UserID,
+			StartedAt:        current,
+			CurrentPeriodEnd: arg.CurrentPeriodEnd,
+		}
+	}
+	if subscription.Status == "expired" {
+		subscription.StartedAt = current
+	}
+	if arg.CurrentPeriodEnd.After(subscription.CurrentPeriodEnd) {
+		subscription.CurrentPeriodEnd = arg.CurrentPeriodEnd
+	}
+	subscription.Plan = arg.Plan
+	subscription.Status = "active"
+	subscription.CancelledAt = sql.NullTime{}
+	subscription.GraceNoticeSentAt = sql.NullTime{}
+	subscription.UpdatedAt = current
+	m.subscriptions[arg.UserID] = subscription
+	return subscription, nil
+}
+
+func (m *Memory) CancelSubscription(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscription, ok := m.subscriptions[userID]
+	if !ok || (subscription.Status != "active" && subscription.Status != "past_due") {
+		return 0, nil
+	}
+	current := now()
+	subscription.Status = "cancelled"
+	subscription.CancelledAt = sql.NullTime{Time: current, Valid: true}
+	subscription.UpdatedAt = current
+	m.subscriptions[userID] = subscription
+	return 1, nil
+}
+
+func (m *Memory) EndSubscription(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	subscription, ok := m.subscriptions[userID]
+	if !ok || subscription.Status == "expired" {
+		return 0, nil
+	}
+	current := now()
+	subscription.Status = "expired"
+	if subscription.CurrentPeriodEnd.After(current) {
+		subscription.CurrentPeriodEnd = current
+	}
+	subscription.UpdatedAt = current
+	m.subscriptions[userID] = subscription
+	return 1, nil
+}
+
+func (m *Memory) StartSubscriptionGracePeriods(ctx context.Context, at time.Time) ([]database.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := now()
+	started := []database.Subscription{}
+	for userID, subscription := range m.subscriptions {
+		if subscription.Status != "active" || subscription.CurrentPeriodEnd.After(at) {
+			continue
+		}
+		subscription.Status = "past_due"
+		subscription.GraceNoticeSentAt = sql.NullTime{Time: current, Valid: true}
+		subscription.UpdatedAt = current
+		m.subscriptions[userID] = subscription
+		started = append(started, subscription)
+	}
+	return started, nil
+}
+
+func (m *Memory) ExpireSubscriptions(ctx context.Context, arg database.ExpireSubscriptionsParams) ([]uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := now()
+	expired := []uuid.UUID{}
+	for userID, subscription := range m.subscriptions {
+		lapsed := (subscription.Status == "cancelled" && !subscription.CurrentPeriodEnd.After(arg.Now)) ||
+			(subscription.Status == "past_due" && !subscription.CurrentPeriodEnd.After(arg.GraceCutoff))
+		if !lapsed {
+			continue
+		}
+		subscription.Status = "expired"
+		subscription.UpdatedAt = current
+		m.subscriptions[userID] = subscription
+
+		user := m.users[userID]
+		user.IsChirpyRed = false
+		user.UpdatedAt = current
+		m.users[userID] = user
+		expired = append(expired, userID)
+	}
+	return expired, nil
+}
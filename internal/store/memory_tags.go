@@ -0,0 +1,113 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+type chirpTagKey struct {
+	chirpID uuid.UUID
+	tagID   uuid.UUID
+}
+
+func (m *Memory) UpsertTag(ctx context.Context, name string) (database.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tag := range m.tags {
+		if tag.Name == name {
+			return tag, nil
+		}
+	}
+
+	tag := database.Tag{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		Name:      name,
+	}
+	m.tags[tag.ID] = tag
+	return tag, nil
+}
+
+func (m *Memory) TagChirp(ctx context.Context, arg database.TagChirpParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.chirps[arg.ChirpID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := m.tags[arg.TagID]; !ok {
+		return errForeignKeyViolation
+	}
+
+	key := chirpTagKey{chirpID: arg.ChirpID, tagID: arg.TagID}
+	if _, ok := m.chirpTags[key]; ok {
+		return nil
+	}
+	m.chirpTags[key] = database.ChirpTag{
+		ChirpID:   arg.ChirpID,
+		TagID:     arg.TagID,
+		CreatedAt: now(),
+	}
+	return nil
+}
+
+func (m *Memory) DeleteChirpTags(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.chirpTags {
+		if key.chirpID == chirpID {
+			delete(m.chirpTags, key)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ListChirpsByTag(ctx context.Context, arg database.ListChirpsByTagParams) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tagged := map[uuid.UUID]bool{}
+	for key := range m.chirpTags {
+		if m.tags[key.tagID].Name == arg.Tag {
+			tagged[key.chirpID] = true
+		}
+	}
+
+	hasTag := func(chirp database.Chirp) bool {
+		return matchLive(chirp) && tagged[chirp.ID]
+	}
+	return m.pageChirps(hasTag, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit, true), nil
+}
+
+func (m *Memory) ListTrendingTags(ctx context.Context, arg database.ListTrendingTagsParams) ([]database.ListTrendingTagsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := map[string]int64{}
+	for key, chirpTag := range m.chirpTags {
+		if chirpTag.CreatedAt.Before(arg.Since) || !matchLive(m.chirps[key.chirpID]) {
+			continue
+		}
+		counts[m.tags[key.tagID].Name]++
+	}
+
+	rows := []database.ListTrendingTagsRow{}
+	for name, count := range counts {
+		rows = append(rows, database.ListTrendingTagsRow{Name: name, ChirpCount: count})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].ChirpCount != rows[j].ChirpCount {
+			return rows[i].ChirpCount > rows[j].ChirpCount
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	if int(arg.RowLimit) < len(rows) {
+		rows = rows[:arg.RowLimit]
+	}
+	return rows, nil
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+func (m *Memory) ListReplies(ctx context.Context, arg database.ListRepliesParams) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	isReply := func(chirp database.Chirp) bool {
+		return chirp.InReplyTo.Valid && chirp.InReplyTo.UUID == arg.ChirpID
+	}
+	return m.pageChirps(isReply, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit, false), nil
+}
+
+func (m *Memory) GetChirpAncestors(ctx context.Context, chirpID uuid.UUID) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ancestors := []database.Chirp{}
+	chirp, ok := m.chirps[chirpID]
+	for ok && chirp.InReplyTo.Valid {
+		chirp, ok = m.chirps[chirp.InReplyTo.UUID]
+		if ok {
+			ancestors = append(ancestors, chirp)
+		}
+	}
+
+	// root first, like the recursive query ordered by depth
+	for i, j := 0, len(ancestors)-1; i < j; i, j = i+1, j-1 {
+		ancestors[i], ancestors[j] = ancestors[j], ancestors[i]
+	}
+	return ancestors, nil
+}
+
+func (m *Memory) GetChirpDescendants(ctx context.Context, arg database.GetChirpDescendantsParams) ([]database.Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	children := map[uuid.UUID][]database.Chirp{}
+	for _, chirp := range m.chirps {
+		if chirp.InReplyTo.Valid {
+			children[chirp.InReplyTo.UUID] = append(children[chirp.InReplyTo.UUID], chirp)
+		}
+	}
+
+	descendants := []database.Chirp{}
+	queue := []uuid.UUID{arg.ChirpID}
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+		for _, child := range children[parentID] {
+			descendants = append(descendants, child)
+			queue = append(queue, child.ID)
+		}
+	}
+
+	sort.Slice(descendants, func(i, j int) bool {
+		return compareKeyset(descendants[i].CreatedAt, descendants[i].ID, descendants[j].CreatedAt, descendants[j].ID) < 0
+	})
+	if int(arg.RowLimit) < len(descendants) {
+		descendants = descendants[:arg.RowLimit]
+	}
+	return descendants, nil
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+)
+
+type throttleKey struct {
+	kind    string
+	subject string
+}
+
+func (m *Memory) GetLoginThrottle(ctx context.Context, arg database.GetLoginThrottleParams) (database.LoginThrottle, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	throttle, ok := m.throttles[throttleKey{arg.Kind, arg.Subject}]
+	if !ok {
+		return database.LoginThrottle{}, sql.ErrNoRows
+	}
+	return throttle, nil
+}
+
+// RecordLoginAttempt counts an attempt and blocks the key for the matching
+// entry of BlockSeconds, unless the key is already blocked.
+func (m *Memory) RecordLoginAttempt(ctx context.Context, arg database.RecordLoginAttemptParams) (database.LoginThrottle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch arg.Kind {
+	case "account", "ip", "reset_email", "reset_ip":
+	default:
+		return database.LoginThrottle{}, errCheckViolation
+	}
+
+	attemptedAt := now()
+	key := throttleKey{arg.Kind, arg.Subject}
+	throttle, ok := m.throttles[key]
+	if !ok {
+		throttle = database.LoginThrottle{Kind: arg.Kind, Subject: arg.Subject}
+	}
+	if throttle.LockedUntil.Valid && throttle.LockedUntil.Time.After(attemptedAt) {
+		return database.LoginThrottle{}, sql.ErrNoRows
+	}
+	if throttle.LastFailureAt.Before(arg.ResetBefore) {
+		throttle.Failures = 1
+	} else {
+		throttle.Failures++
+	}
+	throttle.LastFailureAt = attemptedAt
+
+	block := arg.BlockSeconds[min(int(throttle.Failures), len(arg.BlockSeconds))-1]
+	throttle.LockedUntil = sql.NullTime{Time: attemptedAt.Add(time.Duration(block) * time.Second), Valid: true}
+	m.throttles[key] = throttle
+	return throttle, nil
+}
+
+func (m *Memory) ExtendLoginBlock(ctx context.Context, arg database.ExtendLoginBlockParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := throttleKey{arg.Kind, arg.Subject}
+	throttle, ok := m.throttles[key]
+	if !ok {
+		return nil
+	}
+	if !throttle.LockedUntil.Valid || throttle.LockedUntil.Time.Before(arg.LockedUntil.Time) {
+		throttle.LockedUntil = arg.LockedUntil
+	}
+	m.throttles[key] = throttle
+	return nil
+}
+
+func (m *Memory) RefundLoginAttempt(ctx context.Context, arg database.RefundLoginAttemptParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := throttleKey{arg.Kind, arg.Subject}
+	throttle, ok := m.throttles[key]
+	if !ok {
+		return nil
+	}
+	throttle.Failures = max(throttle.Failures-1, 0)
+	m.throttles[key] = throttle
+	return nil
+}
+
+func (m *Memory) ClearLoginThrottle(ctx context.Context, arg database.ClearLoginThrottleParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := throttleKey{arg.Kind, arg.Subject}
+	if _, ok := m.throttles[key]; !ok {
+		return 0, nil
+	}
+	delete(m.throttles, key)
+	return 1, nil
+}
+
+func (m *Memory) ListLoginLockouts(ctx context.Context) ([]database.LoginThrottle, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	current := now()
+	lockouts := []database.LoginThrottle{}
+	for _, throttle := range m.throttles {
+		if throttle.Kind != "account" && throttle.Kind != "ip" {
+			continue
+		}
+		if throttle.LockedUntil.Valid && throttle.LockedUntil.Time.After(current) {
+			lockouts = append(lockouts, throttle)
+		}
+	}
+
+	sort.Slice(lockouts, func(i, j int) bool {
+		a, b := lockouts[i], lockouts[j]
+		if !a.LockedUntil.Time.Equal(b.LockedUntil.Time) {
+			return a.LockedUntil.Time.After(b.LockedUntil.Time)
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Subject < b.Subject
+	})
+	return lockouts, nil
+}
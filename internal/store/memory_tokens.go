@@ -0,0 +1,93 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+func (m *Memory) CreatePersonalAccessToken(ctx context.Context, arg database.CreatePersonalAccessTokenParams) (database.PersonalAccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.PersonalAccessToken{}, errForeignKeyViolation
+	}
+	for _, token := range m.accessTokens {
+		if token.TokenHash == arg.TokenHash {
+			return database.PersonalAccessToken{}, errUniqueViolation
+		}
+	}
+
+	token := database.PersonalAccessToken{
+		ID:          uuid.New(),
+		CreatedAt:   now(),
+		UserID:      arg.UserID,
+		Name:        arg.Name,
+		TokenHash:   arg.TokenHash,
+		TokenPrefix: arg.TokenPrefix,
+		Scopes:      slices.Clone(arg.Scopes),
+		ExpiresAt:   arg.ExpiresAt,
+	}
+	m.accessTokens[token.ID] = token
+	return token, nil
+}
+
+func (m *Memory) GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (database.PersonalAccessToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, token := range m.accessTokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return database.PersonalAccessToken{}, sql.ErrNoRows
+}
+
+func (m *Memory) ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]database.PersonalAccessToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tokens := []database.PersonalAccessToken{}
+	for _, token := range m.accessTokens {
+		if token.UserID == userID && !token.RevokedAt.Valid {
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return compareKeyset(tokens[i].CreatedAt, tokens[i].ID, tokens[j].CreatedAt, tokens[j].ID) > 0
+	})
+	return tokens, nil
+}
+
+func (m *Memory) TouchPersonalAccessToken(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.accessTokens[id]
+	if !ok {
+		return nil
+	}
+	token.LastUsedAt = sql.NullTime{Time: now(), Valid: true}
+	m.accessTokens[id] = token
+	return nil
+}
+
+func (m *Memory) RevokePersonalAccessToken(ctx context.Context, arg database.RevokePersonalAccessTokenParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.accessTokens[arg.ID]
+	if !ok || token.UserID != arg.UserID || token.RevokedAt.Valid {
+		return 0, nil
+	}
+	token.RevokedAt = sql.NullTime{Time: now(), Valid: true}
+	m.accessTokens[arg.ID] = token
+	return 1, nil
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+func (m *Memory) UpsertTOTPCredential(ctx context.Context, arg database.UpsertTOTPCredentialParams) (database.TotpCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return database.TotpCredential{}, errForeignKeyViolation
+	}
+	if existing, ok := m.totp[arg.UserID]; ok && existing.EnabledAt.Valid {
+		return database.TotpCredential{}, sql.ErrNoRows
+	}
+
+	credential := database.TotpCredential{
+		UserID:    arg.UserID,
+		CreatedAt: now(),
+		Secret:    arg.Secret,
+	}
+	m.totp[arg.UserID] = credential
+	return credential, nil
+}
+
+func (m *Memory) GetTOTPCredential(ctx context.Context, userID uuid.UUID) (database.TotpCredential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	credential, ok := m.totp[userID]
+	if !ok {
+		return database.TotpCredential{}, sql.ErrNoRows
+	}
+	return credential, nil
+}
+
+func (m *Memory) EnableTOTPCredential(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credential, ok := m.totp[userID]
+	if !ok || credential.EnabledAt.Valid {
+		return 0, nil
+	}
+	credential.EnabledAt = sql.NullTime{Time: now(), Valid: true}
+	m.totp[userID] = credential
+	return 1, nil
+}
+
+func (m *Memory) UseTOTPStep(ctx context.Context, arg database.UseTOTPStepParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credential, ok := m.totp[arg.UserID]
+	if !ok || credential.LastUsedStep >= arg.Step {
+		return 0, nil
+	}
+	credential.LastUsedStep = arg.Step
+	m.totp[arg.UserID] = credential
+	return 1, nil
+}
+
+func (m *Memory) DeleteTOTPCredential(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.totp[userID]; !ok {
+		return 0, nil
+	}
+	delete(m.totp, userID)
+	return 1, nil
+}
+
+func (m *Memory) CreateRecoveryCode(ctx context.Context, arg database.CreateRecoveryCodeParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return errForeignKeyViolation
+	}
+	for _, code := range m.recoveryCodes {
+		if code.UserID == arg.UserID && code.CodeHash == arg.CodeHash {
+			return errUniqueViolation
+		}
+	}
+
+	code := database.RecoveryCode{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UserID:    arg.UserID,
+		CodeHash:  arg.CodeHash,
+	}
+	m.recoveryCodes[code.ID] = code
+	return nil
+}
+
+func (m *Memory) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, code := range m.recoveryCodes {
+		if code.UserID == userID {
+			delete(m.recoveryCodes, id)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) UseRecoveryCode(ctx context.Context, arg database.UseRecoveryCodeParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, code := range m.recoveryCodes {
+		if code.UserID == arg.UserID && code.CodeHash == arg.CodeHash && !code.UsedAt.Valid {
+			code.UsedAt = sql.NullTime{Time: now(), Valid: true}
+			m.recoveryCodes[id] = code
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (m *Memory) UseChallengeToken(ctx context.Context, arg database.UseChallengeTokenParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[arg.UserID]; !ok {
+		return 0, errForeignKeyViolation
+	}
+	usedAt := now()
+	for id, challenge := range m.challenges {
+		if challenge.ExpiresAt.Before(usedAt) {
+			delete(m.challenges, id)
+		}
+	}
+	if _, ok := m.challenges[arg.ID]; ok {
+		return 0, nil
+	}
+
+	m.challenges[arg.ID] = database.UsedChallengeToken{
+		ID:        arg.ID,
+		UserID:    arg.UserID,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	return 1, nil
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+func (m *Memory) RecordWebhookEvent(ctx context.Context, arg database.RecordWebhookEventParams) (database.WebhookEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// a redelivered event returns the row recorded the first time
+	for _, event := range m.webhookEvents {
+		if event.Source == arg.Source && event.EventID == arg.EventID {
+			return event, nil
+		}
+	}
+
+	event := database.WebhookEvent{
+		ID:         uuid.New(),
+		Source:     arg.Source,
+		EventID:    arg.EventID,
+		EventType:  arg.EventType,
+		Payload:    arg.Payload,
+		Status:     "pending",
+		ReceivedAt: now(),
+	}
+	m.webhookEvents[event.ID] = event
+	return event, nil
+}
+
+func (m *Memory) GetWebhookEvent(ctx context.Context, id uuid.UUID) (database.WebhookEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	event, ok := m.webhookEvents[id]
+	if !ok {
+		return database.WebhookEvent{}, sql.ErrNoRows
+	}
+	return event, nil
+}
+
+func (m *Memory) ClaimWebhookEvent(ctx context.Context, arg database.ClaimWebhookEventParams) (database.WebhookEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event, ok := m.webhookEvents[arg.ID]
+	if !ok || !slices.Contains(arg.Statuses, event.Status) {
+		return database.WebhookEvent{}, sql.ErrNoRows
+	}
+	event.Status = "processing"
+	m.webhookEvents[arg.ID] = event
+	return event, nil
+}
+
+func (m *Memory) FinishWebhookEvent(ctx context.Context, arg database.FinishWebhookEventParams) (database.WebhookEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch arg.Status {
+	case "pending", "processing", "processed", "ignored", "failed":
+	default:
+		return database.WebhookEvent{}, errCheckViolation
+	}
+
+	event, ok := m.webhookEvents[arg.ID]
+	if !ok {
+		return database.WebhookEvent{}, sql.ErrNoRows
+	}
+	event.Status = arg.Status
+	event.LastError = arg.LastError
+	event.Attempts++
+	event.ProcessedAt = sql.NullTime{Time: now(), Valid: true}
+	m.webhookEvents[arg.ID] = event
+	return event, nil
+}
+
+func (m *Memory) ListWebhookEvents(ctx context.Context, arg database.ListWebhookEventsParams) ([]database.WebhookEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := []database.WebhookEvent{}
+	for _, event := range m.webhookEvents {
+		if !arg.Status.Valid || event.Status == arg.Status.String {
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if !a.ReceivedAt.Equal(b.ReceivedAt) {
+			return a.ReceivedAt.After(b.ReceivedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	if int(arg.RowLimit) < len(events) {
+		events = events[:arg.RowLimit]
+	}
+	return events, nil
+}
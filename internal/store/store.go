@@ -0,0 +1,225 @@
+package store
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Store is the persistence layer used by the HTTP handlers. The sqlc
+// generated *database.Queries is the Postgres implementation and Memory
+// keeps everything in process.
+type Store interface {
+	UserStore
+	ChirpStore
+	RefreshTokenStore
+	SessionStore
+	PersonalAccessTokenStore
+	TwoFactorStore
+	LoginThrottleStore
+	PasswordResetStore
+	EmailVerificationStore
+	FollowStore
+	LikeStore
+	FilterStore
+	SearchStore
+	TagStore
+	MentionStore
+	NotificationStore
+	WebhookEventStore
+	SubscriptionStore
+	OutboundWebhookStore
+	Reset(ctx context.Context) error
+}
+
+type UserStore interface {
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error)
+	GetUser(ctx context.Context, id uuid.UUID) (database.User, error)
+	GetUserByEmail(ctx context.Context, email string) (database.User, error)
+	GetUserByHandle(ctx context.Context, handle string) (database.User, error)
+	ListUsersByHandles(ctx context.Context, handles []string) ([]database.User, error)
+	UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) error
+	SetVerifiedEmail(ctx context.Context, arg database.SetVerifiedEmailParams) (database.User, error)
+	PatchUser(ctx context.Context, arg database.PatchUserParams) (database.User, error)
+	SetUserAvatar(ctx context.Context, arg database.SetUserAvatarParams) (database.User, error)
+	GetUserStats(ctx context.Context, userID uuid.UUID) (database.GetUserStatsRow, error)
+	SetUserChirpyRed(ctx context.Context, arg database.SetUserChirpyRedParams) (int64, error)
+}
+
+type ChirpStore interface {
+	CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error)
+	CreateChirpWithinRate(ctx context.Context, arg database.CreateChirpWithinRateParams) (database.Chirp, error)
+	GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error)
+	ListChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]database.Chirp, error)
+	GetRechirp(ctx context.Context, arg database.GetRechirpParams) (database.Chirp, error)
+	ListChirpsAsc(ctx context.Context, arg database.ListChirpsAscParams) ([]database.Chirp, error)
+	ListChirpsDesc(ctx context.Context, arg database.ListChirpsDescParams) ([]database.Chirp, error)
+	ListChirpsByUserAsc(ctx context.Context, arg database.ListChirpsByUserAscParams) ([]database.Chirp, error)
+	ListChirpsByUserDesc(ctx context.Context, arg database.ListChirpsByUserDescParams) ([]database.Chirp, error)
+	DeleteChirp(ctx context.Context, id uuid.UUID) error
+	UpdateChirpBody(ctx context.Context, arg database.UpdateChirpBodyParams) (database.Chirp, error)
+	ListReplies(ctx context.Context, arg database.ListRepliesParams) ([]database.Chirp, error)
+	GetChirpAncestors(ctx context.Context, chirpID uuid.UUID) ([]database.Chirp, error)
+	GetChirpDescendants(ctx context.Context, arg database.GetChirpDescendantsParams) ([]database.Chirp, error)
+}
+
+type RefreshTokenStore interface {
+	MakeRefreshToken(ctx context.Context, arg database.MakeRefreshTokenParams) error
+	CheckRefreshToken(ctx context.Context, token string) (database.RefreshToken, error)
+	GetUserFromRefreshToken(ctx context.Context, token string) (database.User, error)
+	RevokeRefreshToken(ctx context.Context, token string) error
+	RotateRefreshToken(ctx context.Context, arg database.RotateRefreshTokenParams) (database.RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (int64, error)
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+type SessionStore interface {
+	CreateSession(ctx context.Context, arg database.CreateSessionParams) (database.Session, error)
+	TouchSession(ctx context.Context, id uuid.UUID) error
+	ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]database.Session, error)
+	RevokeSession(ctx context.Context, arg database.RevokeSessionParams) (int64, error)
+	RevokeOtherSessions(ctx context.Context, arg database.RevokeOtherSessionsParams) (int64, error)
+}
+
+type PersonalAccessTokenStore interface {
+	CreatePersonalAccessToken(ctx context.Context, arg database.CreatePersonalAccessTokenParams) (database.PersonalAccessToken, error)
+	GetPersonalAccessTokenByHash(ctx context.Context, tokenHash string) (database.PersonalAccessToken, error)
+	ListPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]database.PersonalAccessToken, error)
+	TouchPersonalAccessToken(ctx context.Context, id uuid.UUID) error
+	RevokePersonalAccessToken(ctx context.Context, arg database.RevokePersonalAccessTokenParams) (int64, error)
+}
+
+type TwoFactorStore interface {
+	UpsertTOTPCredential(ctx context.Context, arg database.UpsertTOTPCredentialParams) (database.TotpCredential, error)
+	GetTOTPCredential(ctx context.Context, userID uuid.UUID) (database.TotpCredential, error)
+	EnableTOTPCredential(ctx context.Context, userID uuid.UUID) (int64, error)
+	UseTOTPStep(ctx context.Context, arg database.UseTOTPStepParams) (int64, error)
+	DeleteTOTPCredential(ctx context.Context, userID uuid.UUID) (int64, error)
+	CreateRecoveryCode(ctx context.Context, arg database.CreateRecoveryCodeParams) error
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	UseRecoveryCode(ctx context.Context, arg database.UseRecoveryCodeParams) (int64, error)
+	UseChallengeToken(ctx context.Context, arg database.UseChallengeTokenParams) (int64, error)
+}
+
+type LoginThrottleStore interface {
+	GetLoginThrottle(ctx context.Context, arg database.GetLoginThrottleParams) (database.LoginThrottle, error)
+	RecordLoginAttempt(ctx context.Context, arg database.RecordLoginAttemptParams) (database.LoginThrottle, error)
+	ExtendLoginBlock(ctx context.Context, arg database.ExtendLoginBlockParams) error
+	RefundLoginAttempt(ctx context.Context, arg database.RefundLoginAttemptParams) error
+	ClearLoginThrottle(ctx context.Context, arg database.ClearLoginThrottleParams) (int64, error)
+	ListLoginLockouts(ctx context.Context) ([]database.LoginThrottle, error)
+}
+
+type PasswordResetStore interface {
+	CreatePasswordResetToken(ctx context.Context, arg database.CreatePasswordResetTokenParams) error
+	UsePasswordResetToken(ctx context.Context, tokenHash string) (database.PasswordResetToken, error)
+	InvalidatePasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+type EmailVerificationStore interface {
+	CreateEmailVerificationToken(ctx context.Context, arg database.CreateEmailVerificationTokenParams) error
+	UseEmailVerificationToken(ctx context.Context, tokenHash string) (database.EmailVerificationToken, error)
+	InvalidateEmailVerificationTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+type FollowStore interface {
+	FollowUser(ctx context.Context, arg database.FollowUserParams) error
+	UnfollowUser(ctx context.Context, arg database.UnfollowUserParams) error
+	ListFollowers(ctx context.Context, arg database.ListFollowersParams) ([]database.Follow, error)
+	ListFollowing(ctx context.Context, arg database.ListFollowingParams) ([]database.Follow, error)
+	ListTimeline(ctx context.Context, arg database.ListTimelineParams) ([]database.Chirp, error)
+}
+
+type LikeStore interface {
+	LikeChirp(ctx context.Context, arg database.LikeChirpParams) error
+	UnlikeChirp(ctx context.Context, arg database.UnlikeChirpParams) error
+	CountChirpLikes(ctx context.Context, chirpIds []uuid.UUID) ([]database.CountChirpLikesRow, error)
+	ListLikedChirpIDs(ctx context.Context, arg database.ListLikedChirpIDsParams) ([]uuid.UUID, error)
+	ListLikesByUser(ctx context.Context, arg database.ListLikesByUserParams) ([]database.ListLikesByUserRow, error)
+}
+
+type FilterStore interface {
+	CreateChirpFilter(ctx context.Context, arg database.CreateChirpFilterParams) (database.ChirpFilter, error)
+	ListChirpFilters(ctx context.Context) ([]database.ChirpFilter, error)
+	GetChirpFilter(ctx context.Context, id uuid.UUID) (database.ChirpFilter, error)
+	UpdateChirpFilter(ctx context.Context, arg database.UpdateChirpFilterParams) (database.ChirpFilter, error)
+	DeleteChirpFilter(ctx context.Context, id uuid.UUID) (int64, error)
+	CreateChirpFlag(ctx context.Context, arg database.CreateChirpFlagParams) error
+	ListUnresolvedChirpFlags(ctx context.Context) ([]database.ChirpFlag, error)
+	ResolveChirpFlag(ctx context.Context, id uuid.UUID) (int64, error)
+}
+
+type SearchStore interface {
+	SearchChirps(ctx context.Context, arg database.SearchChirpsParams) ([]database.SearchChirpsRow, error)
+	SearchUsers(ctx context.Context, arg database.SearchUsersParams) ([]database.User, error)
+}
+
+type TagStore interface {
+	UpsertTag(ctx context.Context, name string) (database.Tag, error)
+	TagChirp(ctx context.Context, arg database.TagChirpParams) error
+	DeleteChirpTags(ctx context.Context, chirpID uuid.UUID) error
+	ListChirpsByTag(ctx context.Context, arg database.ListChirpsByTagParams) ([]database.Chirp, error)
+	ListTrendingTags(ctx context.Context, arg database.ListTrendingTagsParams) ([]database.ListTrendingTagsRow, error)
+}
+
+type MentionStore interface {
+	CreateChirpMention(ctx context.Context, arg database.CreateChirpMentionParams) error
+	DeleteChirpMentions(ctx context.Context, chirpID uuid.UUID) error
+	ListChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]database.ChirpMention, error)
+}
+
+type NotificationStore interface {
+	CreateNotification(ctx context.Context, arg database.CreateNotificationParams) error
+	ListNotifications(ctx context.Context, arg database.ListNotificationsParams) ([]database.Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error)
+	MarkNotificationRead(ctx context.Context, arg database.MarkNotificationReadParams) (int64, error)
+	MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+type WebhookEventStore interface {
+	RecordWebhookEvent(ctx context.Context, arg database.RecordWebhookEventParams) (database.WebhookEvent, error)
+	GetWebhookEvent(ctx context.Context, id uuid.UUID) (database.WebhookEvent, error)
+	ClaimWebhookEvent(ctx context.Context, arg database.ClaimWebhookEventParams) (database.WebhookEvent, error)
+	FinishWebhookEvent(ctx context.Context, arg database.FinishWebhookEventParams) (database.WebhookEvent, error)
+	ListWebhookEvents(ctx context.Context, arg database.ListWebhookEventsParams) ([]database.WebhookEvent, error)
+}
+
+type SubscriptionStore interface {
+	GetSubscription(ctx context.Context, userID uuid.UUID) (database.Subscription, error)
+	StartSubscription(ctx context.Context, arg database.StartSubscriptionParams) (database.Subscription, error)
+	CancelSubscription(ctx context.Context, userID uuid.UUID) (int64, error)
+	EndSubscription(ctx context.Context, userID uuid.UUID) (int64, error)
+	StartSubscriptionGracePeriods(ctx context.Context, now time.Time) ([]database.Subscription, error)
+	ExpireSubscriptions(ctx context.Context, arg database.ExpireSubscriptionsParams) ([]uuid.UUID, error)
+}
+
+type OutboundWebhookStore interface {
+	CreateWebhookEndpoint(ctx context.Context, arg database.CreateWebhookEndpointParams) (database.WebhookEndpoint, error)
+	GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (database.WebhookEndpoint, error)
+	ListWebhookEndpoints(ctx context.Context, userID uuid.NullUUID) ([]database.WebhookEndpoint, error)
+	ListWebhookEndpointsForEvent(ctx context.Context, arg database.ListWebhookEndpointsForEventParams) ([]database.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, id uuid.UUID) (int64, error)
+	CreateWebhookDelivery(ctx context.Context, arg database.CreateWebhookDeliveryParams) error
+	GetWebhookDelivery(ctx context.Context, id uuid.UUID) (database.WebhookDelivery, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg database.ClaimWebhookDeliveriesParams) ([]database.WebhookDelivery, error)
+	RecordWebhookDeliveryAttempt(ctx context.Context, arg database.RecordWebhookDeliveryAttemptParams) (database.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, arg database.ListWebhookDeliveriesParams) ([]database.WebhookDelivery, error)
+	RetryWebhookDelivery(ctx context.Context, id uuid.UUID) (database.WebhookDelivery, error)
+}
+
+var _ Store = (*database.Queries)(nil)
+var _ Store = (*Memory)(nil)
+
+// IsUniqueViolation reports whether err is a unique constraint violation
+// from either store.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return errors.Is(err, errUniqueViolation)
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"net/http"
+)
+
+// getJWKS publishes the public keys that verify Chirpy access tokens so
+// other services can check them without being able to mint them.
+func getJWKS(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJson(writer, 200, ApiCfg.Keys.JWKS())
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWKS(t *testing.T) {
+	mux := newTestMux(t)
+	_, signingKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApiCfg.Keys, err = auth.NewKeySet("", signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	token := user["token"].(string)
+	if rec := doRequest(t, mux, "GET", "/api/timeline", token, nil); rec.Code != 200 {
+		t.Fatalf("timeline with an EdDSA token: status %d", rec.Code)
+	}
+
+	rec := doRequest(t, mux, "GET", "/.well-known/jwks.json", "", nil)
+	if rec.Code != 200 {
+		t.Fatalf("jwks: status %d", rec.Code)
+	}
+	jwks := auth.JWKS{}
+	decodeResponse(t, rec, &jwks)
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Algorithm != "EdDSA" || jwks.Keys[0].Use != "sig" {
+		t.Fatalf("unexpected jwks %+v", jwks)
+	}
+
+	// verify the access token the way another service would, using only
+	// the published key
+	published := jwks.Keys[0]
+	x, err := base64.RawURLEncoding.DecodeString(published.X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if token.Header["kid"] != published.KeyID {
+			t.Errorf("token kid %v does not match %s", token.Header["kid"], published.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	}, jwt.WithValidMethods([]string{"EdDSA"}))
+	if err != nil || !parsed.Valid {
+		t.Errorf("unable to verify token with the published key: %v", err)
+	}
+}
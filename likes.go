@@ -0,0 +1,109 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func likeChirp(writer http.ResponseWriter, request *http.Request) {
+	userID, chirpID, ok := likeParams(writer, request)
+	if !ok {
+		return
+	}
+
+	params := database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}
+	if err := ApiCfg.Store.LikeChirp(request.Context(), params); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
+
+func unlikeChirp(writer http.ResponseWriter, request *http.Request) {
+	userID, chirpID, ok := likeParams(writer, request)
+	if !ok {
+		return
+	}
+
+	params := database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	}
+	if err := ApiCfg.Store.UnlikeChirp(request.Context(), params); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
+
+// likeParams authenticates the user and resolves the chirp from the URL,
+// writing the error response itself when either fails.
+func likeParams(writer http.ResponseWriter, request *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		respondWithJsonError(writer, "Chirp not found", 404)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	// liking a rechirp likes the chirp it reposts
+	chirp, err := getOriginalChirp(request.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithJsonError(writer, "Chirp not found", 404)
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	return userID, chirp.ID, true
+}
+
+func getUserLikes(writer http.ResponseWriter, request *http.Request) {
+	userID, page, ok := userListParams(writer, request)
+	if !ok {
+		return
+	}
+
+	likes, err := ApiCfg.Store.ListLikesByUser(request.Context(), database.ListLikesByUserParams{
+		UserID:          userID,
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		RowLimit:        page.Limit + 1,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	likes, next := trimPage(likes, page.Limit, func(like database.ListLikesByUserRow) pageCursor {
+		return pageCursor{CreatedAt: like.LikedAt, ID: like.Chirp.ID}
+	})
+
+	chirps := []database.Chirp{}
+	for _, like := range likes {
+		chirps = append(chirps, like.Chirp)
+	}
+	chirpsSlice, err := makeChirpsSlice(request, chirps)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	for i, like := range likes {
+		chirpsSlice[i]["liked_at"] = like.LikedAt.String()
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{
+		"chirps":      chirpsSlice,
+		"next_cursor": next,
+	})
+}
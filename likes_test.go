@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestLikes(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	jesse := createTestUser(t, mux, "jesse@breakingbad.com")
+	jesseToken := jesse["token"].(string)
+
+	chirp := postChirp(t, mux, walt["token"].(string), map[string]interface{}{"body": "Say my name"})
+	likePath := "/api/chirps/" + chirp["id"].(string) + "/like"
+
+	for i := 0; i < 2; i++ {
+		if rec := doRequest(t, mux, "POST", likePath, jesseToken, nil); rec.Code != 204 {
+			t.Fatalf("like: expected 204, got %d", rec.Code)
+		}
+	}
+
+	rec := doRequest(t, mux, "GET", "/api/chirps/"+chirp["id"].(string), jesseToken, nil)
+	liked := map[string]interface{}{}
+	decodeResponse(t, rec, &liked)
+	if liked["like_count"] != float64(1) || liked["liked_by_me"] != true {
+		t.Errorf("expected one like by the requester, got like_count=%v liked_by_me=%v", liked["like_count"], liked["liked_by_me"])
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/chirps/"+chirp["id"].(string), "", nil)
+	anonymous := map[string]interface{}{}
+	decodeResponse(t, rec, &anonymous)
+	if _, ok := anonymous["liked_by_me"]; ok {
+		t.Errorf("liked_by_me should be omitted for anonymous requests")
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/users/"+jesse["id"].(string)+"/likes", "", nil)
+	page := chirpPage{}
+	decodeResponse(t, rec, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0]["id"] != chirp["id"] {
+		t.Errorf("unexpected liked chirps: %v", page.Chirps)
+	}
+
+	if rec := doRequest(t, mux, "DELETE", likePath, jesseToken, nil); rec.Code != 204 {
+		t.Fatalf("unlike: expected 204, got %d", rec.Code)
+	}
+	rec = doRequest(t, mux, "GET", "/api/chirps/"+chirp["id"].(string), jesseToken, nil)
+	decodeResponse(t, rec, &liked)
+	if liked["like_count"] != float64(0) || liked["liked_by_me"] != false {
+		t.Errorf("expected no likes after unlike, got like_count=%v liked_by_me=%v", liked["like_count"], liked["liked_by_me"])
+	}
+}
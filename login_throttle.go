@@ -0,0 +1,269 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Failed logins are counted per account and per client IP. After a few free
+// attempts each further failure blocks the key for twice as long as the
+// last, and enough failures lock it out entirely. Counts start over once a
+// key has gone loginFailureWindow without a failure. Attempts are counted
+// before the password is checked and taken back if it was right.
+const (
+	throttleKindAccount    = "account"
+	throttleKindIP         = "ip"
+	throttleKindResetEmail = "reset_email"
+	throttleKindResetIP    = "reset_ip"
+	loginFailureWindow     = time.Hour
+	loginBackoffBase       = time.Second
+	loginLockoutDuration   = 15 * time.Minute
+	maxLoginBackoffShift   = 20
+	maxThrottleSubject     = 320
+)
+
+type loginThrottlePolicy struct {
+	freeAttempts int32
+	lockoutAfter int32
+}
+
+// IPs get more room than accounts since many users can share one address.
+// Password reset requests share the table; every request counts, so an
+// inbox can't be flooded and a pending reset can't be kept cancelled.
+var loginThrottlePolicies = map[string]loginThrottlePolicy{
+	throttleKindAccount:    {freeAttempts: 3, lockoutAfter: 10},
+	throttleKindIP:         {freeAttempts: 20, lockoutAfter: 100},
+	throttleKindResetEmail: {freeAttempts: 3, lockoutAfter: 5},
+	throttleKindResetIP:    {freeAttempts: 10, lockoutAfter: 30},
+}
+
+// dummyPasswordHash is checked against when the email is unknown, so those
+// logins take as long as a wrong password does.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("not a real password")
+	if err != nil {
+		log.Printf("unable to create dummy password hash: %s", err)
+	}
+	return hash
+})
+
+// loginSubjects returns the throttle keys a login attempt counts against.
+func loginSubjects(request *http.Request, email string) []database.GetLoginThrottleParams {
+	return []database.GetLoginThrottleParams{
+		{Kind: throttleKindAccount, Subject: normalizeThrottleSubject(throttleKindAccount, email)},
+		{Kind: throttleKindIP, Subject: normalizeThrottleSubject(throttleKindIP, clientIP(request))},
+	}
+}
+
+// resetSubjects returns the throttle keys a password reset request counts
+// against.
+func resetSubjects(request *http.Request, email string) []database.GetLoginThrottleParams {
+	return []database.GetLoginThrottleParams{
+		{Kind: throttleKindResetEmail, Subject: normalizeThrottleSubject(throttleKindResetEmail, email)},
+		{Kind: throttleKindResetIP, Subject: normalizeThrottleSubject(throttleKindResetIP, clientIP(request))},
+	}
+}
+
+func normalizeThrottleSubject(kind, subject string) string {
+	subject = strings.TrimSpace(subject)
+	if kind == throttleKindAccount || kind == throttleKindResetEmail {
+		subject = strings.ToLower(subject)
+	}
+	return truncate(subject, maxThrottleSubject)
+}
+
+// loginBlock is how long a key is blocked after its nth counted attempt.
+func (policy loginThrottlePolicy) loginBlock(attempts int32) time.Duration {
+	if attempts <= policy.freeAttempts {
+		return 0
+	}
+	if attempts >= policy.lockoutAfter {
+		return loginLockoutDuration
+	}
+	// capped well past the lockout duration, so the shift can't overflow
+	shift := min(attempts-policy.freeAttempts-1, maxLoginBackoffShift)
+	return min(loginBackoffBase<<shift, loginLockoutDuration)
+}
+
+// blockSeconds is loginBlock for every attempt up to the lockout, the
+// schedule RecordLoginAttempt applies.
+func (policy loginThrottlePolicy) blockSeconds() []int32 {
+	blocks := make([]int32, policy.lockoutAfter)
+	for i := range blocks {
+		blocks[i] = int32(policy.loginBlock(int32(i+1)) / time.Second)
+	}
+	return blocks
+}
+
+// loginAttempt is a login attempt that was counted against the account and
+// the client before the credentials were checked. Counting and checking
+// the block happen in one statement, so concurrent guesses can't all get
+// past a block that hasn't been written yet.
+type loginAttempt struct {
+	request   *http.Request
+	throttles []database.LoginThrottle
+}
+
+// beginLoginAttempt counts an attempt, responding with 429 if the account
+// or the client is currently blocked.
+func beginLoginAttempt(writer http.ResponseWriter, request *http.Request, email string) (*loginAttempt, bool) {
+	return countAttempt(writer, request, loginSubjects(request, email), "Too many failed login attempts, try again later")
+}
+
+// countAttempt counts an attempt against every key, responding with 429
+// and blockedMessage if one of them is currently blocked.
+func countAttempt(writer http.ResponseWriter, request *http.Request, keys []database.GetLoginThrottleParams, blockedMessage string) (*loginAttempt, bool) {
+	attempt := &loginAttempt{request: request}
+	resetBefore := time.Now().UTC().Add(-loginFailureWindow)
+	for _, key := range keys {
+		throttle, err := ApiCfg.Store.RecordLoginAttempt(request.Context(), database.RecordLoginAttemptParams{
+			Kind:         key.Kind,
+			Subject:      key.Subject,
+			BlockSeconds: loginThrottlePolicies[key.Kind].blockSeconds(),
+			ResetBefore:  resetBefore,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			attempt.refund()
+			respondBlocked(writer, request, key, blockedMessage)
+			return nil, false
+		}
+		if err != nil {
+			attempt.refund()
+			respondWithJsonError(writer, "Something went wrong", 500)
+			return nil, false
+		}
+		attempt.throttles = append(attempt.throttles, throttle)
+	}
+	return attempt, true
+}
+
+func respondBlocked(writer http.ResponseWriter, request *http.Request, key database.GetLoginThrottleParams, message string) {
+	throttle, err := ApiCfg.Store.GetLoginThrottle(request.Context(), key)
+	if err == nil && throttle.LockedUntil.Valid {
+		if wait := time.Until(throttle.LockedUntil.Time); wait > 0 {
+			writer.Header().Set("Retry-After", fmt.Sprint(int(wait.Seconds())+1))
+		}
+	}
+	respondWithJsonError(writer, message, 429)
+}
+
+// failed leaves the attempt counted and restarts its block from now, so
+// the time spent checking the password doesn't eat into it. userID is
+// uuid.Nil when the email doesn't belong to anyone.
+func (attempt *loginAttempt) failed(userID uuid.UUID) {
+	for _, throttle := range attempt.throttles {
+		policy := loginThrottlePolicies[throttle.Kind]
+		if throttle.Failures == policy.lockoutAfter {
+			logSecurityEvent("login_lockout", userID, "kind", throttle.Kind, "subject", throttle.Subject, "failures", throttle.Failures)
+		}
+
+		block := policy.loginBlock(throttle.Failures)
+		if block == 0 {
+			continue
+		}
+		err := ApiCfg.Store.ExtendLoginBlock(attempt.request.Context(), database.ExtendLoginBlockParams{
+			LockedUntil: sql.NullTime{Time: time.Now().UTC().Add(block), Valid: true},
+			Kind:        throttle.Kind,
+			Subject:     throttle.Subject,
+		})
+		if err != nil {
+			log.Printf("unable to block %s %s: %s", throttle.Kind, throttle.Subject, err)
+		}
+	}
+}
+
+// refund takes the attempt back when the credentials were right or could
+// not be checked, since only failures count. A block the attempt started
+// stays, so logging in to an account of one's own doesn't lift it.
+func (attempt *loginAttempt) refund() {
+	for _, throttle := range attempt.throttles {
+		err := ApiCfg.Store.RefundLoginAttempt(attempt.request.Context(), database.RefundLoginAttemptParams{
+			Kind:    throttle.Kind,
+			Subject: throttle.Subject,
+		})
+		if err != nil {
+			log.Printf("unable to refund login attempt for %s %s: %s", throttle.Kind, throttle.Subject, err)
+		}
+	}
+	attempt.throttles = nil
+}
+
+// clearAccountThrottle forgets an account's failures after a successful
+// login. The client's count is kept, so an attacker can't reset it by
+// logging in to an account of their own.
+func clearAccountThrottle(ctx context.Context, email string) {
+	_, err := ApiCfg.Store.ClearLoginThrottle(ctx, database.ClearLoginThrottleParams{
+		Kind:    throttleKindAccount,
+		Subject: normalizeThrottleSubject(throttleKindAccount, email),
+	})
+	if err != nil {
+		log.Printf("unable to clear login throttle for %s: %s", email, err)
+	}
+}
+
+func getLockouts(writer http.ResponseWriter, request *http.Request) {
+	if !isAdmin(request) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	lockouts, err := ApiCfg.Store.ListLoginLockouts(request.Context())
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	lockoutsSlice := []map[string]interface{}{}
+	for _, lockout := range lockouts {
+		lockoutsSlice = append(lockoutsSlice, map[string]interface{}{
+			"kind":            lockout.Kind,
+			"subject":         lockout.Subject,
+			"failures":        lockout.Failures,
+			"last_failure_at": lockout.LastFailureAt.String(),
+			"locked_until":    lockout.LockedUntil.Time.String(),
+		})
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{"lockouts": lockoutsSlice})
+}
+
+func clearLockout(writer http.ResponseWriter, request *http.Request) {
+	if !isAdmin(request) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	// password reset throttles aren't login lockouts, so they can't be
+	// cleared here
+	kind := request.PathValue("kind")
+	if kind != throttleKindAccount && kind != throttleKindIP {
+		respondWithJsonError(writer, "Lockout not found", 404)
+		return
+	}
+
+	cleared, err := ApiCfg.Store.ClearLoginThrottle(request.Context(), database.ClearLoginThrottleParams{
+		Kind:    kind,
+		Subject: normalizeThrottleSubject(kind, request.PathValue("subject")),
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if cleared == 0 {
+		respondWithJsonError(writer, "Lockout not found", 404)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoginUnknownEmail(t *testing.T) {
+	mux := newTestMux(t)
+	createTestUser(t, mux, "walt@breakingbad.com")
+
+	unknown := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "nobody@breakingbad.com", Password: "hunter2"})
+	wrong := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "hunter3"})
+	if unknown.Code != 401 || wrong.Code != 401 {
+		t.Fatalf("expected 401 for both, got %d and %d", unknown.Code, wrong.Code)
+	}
+	if unknown.Body.String() != wrong.Body.String() {
+		t.Errorf("unknown email and wrong password responses differ: %q vs %q", unknown.Body.String(), wrong.Body.String())
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	mux := newTestMux(t)
+	createTestUser(t, mux, "walt@breakingbad.com")
+	wrong := userRequest{Email: "walt@breakingbad.com", Password: "hunter3"}
+	right := userRequest{Email: "walt@breakingbad.com", Password: "hunter2"}
+
+	// the free attempts fail normally, then the account is blocked
+	for i := int32(0); i <= loginThrottlePolicies[throttleKindAccount].freeAttempts; i++ {
+		if rec := doRequest(t, mux, "POST", "/api/login", "", wrong); rec.Code != 401 {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+	rec := doRequest(t, mux, "POST", "/api/login", "", right)
+	if rec.Code != 429 {
+		t.Fatalf("login while blocked: expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected a Retry-After header")
+	}
+
+	// emails are matched case-insensitively
+	if rec := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "WALT@breakingbad.com", Password: "hunter2"}); rec.Code != 429 {
+		t.Errorf("login with different case: expected 429, got %d", rec.Code)
+	}
+
+	if rec := doRequest(t, mux, "GET", "/admin/lockouts", "", nil); rec.Code != 401 {
+		t.Errorf("lockouts without admin key: expected 401, got %d", rec.Code)
+	}
+	rec = doAdminRequest(t, mux, "GET", "/admin/lockouts", nil)
+	if rec.Code != 200 {
+		t.Fatalf("list lockouts: status %d", rec.Code)
+	}
+	lockoutResp := struct {
+		Lockouts []map[string]interface{} `json:"lockouts"`
+	}{}
+	decodeResponse(t, rec, &lockoutResp)
+	if len(lockoutResp.Lockouts) != 1 || lockoutResp.Lockouts[0]["subject"] != "walt@breakingbad.com" {
+		t.Fatalf("expected the account to be listed, got %v", lockoutResp.Lockouts)
+	}
+
+	if rec := doAdminRequest(t, mux, "DELETE", "/admin/lockouts/account/walt@breakingbad.com", nil); rec.Code != 204 {
+		t.Fatalf("clear lockout: status %d", rec.Code)
+	}
+	if rec := doAdminRequest(t, mux, "DELETE", "/admin/lockouts/account/walt@breakingbad.com", nil); rec.Code != 404 {
+		t.Errorf("clear lockout twice: expected 404, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/login", "", right); rec.Code != 200 {
+		t.Errorf("login after clearing: expected 200, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: " walt@breakingbad.com ", Password: "hunter2"}); rec.Code != 200 {
+		t.Errorf("login with surrounding spaces: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestLoginLockoutUnknownEmail(t *testing.T) {
+	mux := newTestMux(t)
+	unknown := userRequest{Email: "nobody@breakingbad.com", Password: "hunter2"}
+
+	// unknown accounts are throttled exactly like real ones
+	for i := int32(0); i <= loginThrottlePolicies[throttleKindAccount].freeAttempts; i++ {
+		if rec := doRequest(t, mux, "POST", "/api/login", "", unknown); rec.Code != 401 {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := doRequest(t, mux, "POST", "/api/login", "", unknown); rec.Code != 429 {
+		t.Errorf("expected 429 once blocked, got %d", rec.Code)
+	}
+}
+
+func TestLoginBlockSchedule(t *testing.T) {
+	for kind, policy := range loginThrottlePolicies {
+		previous := time.Duration(0)
+		for attempts := int32(1); attempts <= policy.lockoutAfter+5; attempts++ {
+			block := policy.loginBlock(attempts)
+			if block < previous || block > loginLockoutDuration {
+				t.Fatalf("%s: attempt %d blocks for %s after %s", kind, attempts, block, previous)
+			}
+			previous = block
+		}
+		if previous != loginLockoutDuration {
+			t.Errorf("%s: expected a lockout, got %s", kind, previous)
+		}
+	}
+}
+
+func TestLoginConcurrentGuesses(t *testing.T) {
+	mux := newTestMux(t)
+	createTestUser(t, mux, "walt@breakingbad.com")
+	wrong := userRequest{Email: "walt@breakingbad.com", Password: "hunter3"}
+
+	free := int(loginThrottlePolicies[throttleKindAccount].freeAttempts)
+	for i := 0; i < free; i++ {
+		doRequest(t, mux, "POST", "/api/login", "", wrong)
+	}
+
+	// a burst of guesses can't get past a block that isn't written yet
+	codes := make(chan int, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- doRequest(t, mux, "POST", "/api/login", "", wrong).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	checked := 0
+	for code := range codes {
+		if code == 401 {
+			checked++
+		} else if code != 429 {
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+	if checked != 1 {
+		t.Errorf("expected one guess to be checked, got %d", checked)
+	}
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
+	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"chirpy/internal/mail"
+	"chirpy/internal/plans"
+	"chirpy/internal/storage"
+	"chirpy/internal/store"
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -13,19 +21,86 @@ import (
 
 func main() {
 	godotenv.Load()
-	dbURL := os.Getenv("DB_URL")
 
-	db, err := sql.Open("postgres", dbURL)
-	if err != nil {
-		fmt.Printf("Error: %s", err)
-	}
+	switch os.Getenv("STORE") {
+	case "memory":
+		ApiCfg.Store = store.NewMemory()
+	default:
+		dbURL := os.Getenv("DB_URL")
 
-	dbQueries := database.New(db)
-	ApiCfg.Queries = *dbQueries
+		db, err := sql.Open("postgres", dbURL)
+		if err != nil {
+			fmt.Printf("Error: %s", err)
+		}
+
+		ApiCfg.Store = database.New(db)
+	}
 
 	ApiCfg.Platform = os.Getenv("PLATFORM")
 	ApiCfg.Secret = os.Getenv("SECRET")
-	ApiCfg.PolkaKey = os.Getenv("POLKA_KEY")
+	// several secrets can be configured while Polka rotates its secret
+	ApiCfg.PolkaWebhook = auth.NewWebhookVerifier(strings.Split(os.Getenv("POLKA_WEBHOOK_SECRETS"), ","), auth.DefaultWebhookTolerance)
+	ApiCfg.AdminKey = os.Getenv("ADMIN_KEY")
+	ApiCfg.BaseURL = strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+	if ApiCfg.BaseURL == "" {
+		ApiCfg.BaseURL = "http://localhost:8080"
+	}
+
+	// tokens are signed with JWT_SIGNING_KEY if set, otherwise with SECRET;
+	// SECRET is not accepted for tokens once a signing key is set
+	verificationKeys := []string{}
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFICATION_KEYS"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			verificationKeys = append(verificationKeys, path)
+		}
+	}
+	keys, err := auth.LoadKeySet(ApiCfg.Secret, os.Getenv("JWT_SIGNING_KEY"), verificationKeys)
+	if err != nil {
+		log.Fatalf("unable to load JWT keys: %s", err)
+	}
+	ApiCfg.Keys = keys
+
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "chirpy@localhost"
+	}
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		ApiCfg.Mailer = mail.NewSMTPMailer(os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), mailFrom)
+	case "file":
+		fileMailer, err := mail.NewFileMailer(os.Getenv("MAIL_DIR"), mailFrom)
+		if err != nil {
+			log.Fatalf("unable to create mail directory: %s", err)
+		}
+		ApiCfg.Mailer = fileMailer
+	default:
+		ApiCfg.Mailer = mail.NewLogMailer(os.Stdout, mailFrom)
+	}
+
+	// uploads such as avatars are served from /media
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	localStorage, err := storage.NewLocal(mediaDir, ApiCfg.BaseURL+"/media")
+	if err != nil {
+		log.Fatalf("unable to create media directory: %s", err)
+	}
+	ApiCfg.Storage = localStorage
+
+	// plan limits can be changed without a release through PLANS_FILE
+	ApiCfg.Plans = plans.Default()
+	if plansFile := os.Getenv("PLANS_FILE"); plansFile != "" {
+		ApiCfg.Plans, err = plans.Load(plansFile)
+		if err != nil {
+			log.Fatalf("unable to load plans: %s", err)
+		}
+	}
+
+	// only for development, where endpoints usually run on localhost
+	if os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true" {
+		webhookClient = newWebhookClient(true)
+	}
 
 	fmt.Println("hi")
 	ApiCfg.fileserverHits.Store(0)
@@ -35,24 +110,93 @@ func main() {
 		Handler: mux,
 	}
 
+	registerRoutes(mux)
+	go refreshTrending(context.Background(), trendingInterval)
+	go runSubscriptionJobs(context.Background(), subscriptionInterval)
+	go runWebhookDeliveries(context.Background(), webhookInterval)
+
+	server.ListenAndServe()
+}
+
+func registerRoutes(mux *http.ServeMux) {
 	handler := http.StripPrefix("/app", http.FileServer(http.Dir(".")))
 
 	mux.Handle("/app/", ApiCfg.middlewareMetricsInc(handler))
 	mux.HandleFunc("GET /api/healthz", healthz)
+	if media, ok := ApiCfg.Storage.(http.Handler); ok {
+		mux.Handle("GET /media/", http.StripPrefix("/media", media))
+	}
+	mux.HandleFunc("GET /.well-known/jwks.json", getJWKS)
 	mux.HandleFunc("GET /admin/metrics", ApiCfg.metrics)
 	mux.HandleFunc("POST /admin/reset", ApiCfg.reset)
 	mux.HandleFunc("POST /api/users", createUser)
-	mux.HandleFunc("POST /api/chirps", createChirp)
+	mux.HandleFunc("POST /api/chirps", requireScope(auth.ScopeChirpsWrite, createChirp))
 	mux.HandleFunc("GET /api/chirps", getChirps)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", getChirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/replies", getReplies)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", getThread)
 	mux.HandleFunc("POST /api/login", login)
+	mux.HandleFunc("POST /api/login/2fa", loginTwoFactor)
+	mux.HandleFunc("POST /api/password/forgot", forgotPassword)
+	mux.HandleFunc("POST /api/password/reset", resetPassword)
+	mux.HandleFunc("GET /api/email/verify", verifyEmail)
+	mux.HandleFunc("POST /api/email/verify/resend", requireScope(auth.ScopeAccountWrite, resendEmailVerification))
 	mux.HandleFunc("POST /api/refresh", refresh)
 	mux.HandleFunc("POST /api/revoke", revoke)
-	mux.HandleFunc("PUT /api/users", updateUser)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", deleteChirp)
-	mux.HandleFunc("POST /api/polka/webhooks", upgradeUser)
-
-	server.ListenAndServe()
+	mux.HandleFunc("GET /api/sessions", requireScope(auth.ScopeAccountRead, getSessions))
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", requireScope(auth.ScopeAccountWrite, deleteSession))
+	mux.HandleFunc("POST /api/sessions/revoke-others", requireScope(auth.ScopeAccountWrite, revokeOtherSessions))
+	mux.HandleFunc("GET /api/tokens", requireScope(auth.ScopeAccountRead, getPersonalAccessTokens))
+	mux.HandleFunc("POST /api/tokens", requireScope(auth.ScopeAccountWrite, createPersonalAccessToken))
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", requireScope(auth.ScopeAccountWrite, revokePersonalAccessToken))
+	mux.HandleFunc("POST /api/users/2fa/setup", requireScope(auth.ScopeAccountWrite, setupTwoFactor))
+	mux.HandleFunc("POST /api/users/2fa/verify", requireScope(auth.ScopeAccountWrite, verifyTwoFactor))
+	mux.HandleFunc("DELETE /api/users/2fa", requireScope(auth.ScopeAccountWrite, disableTwoFactor))
+	mux.HandleFunc("PUT /api/users", requireScope(auth.ScopeAccountWrite, updateUser))
+	mux.HandleFunc("PATCH /api/users", requireScope(auth.ScopeAccountWrite, patchUser))
+	mux.HandleFunc("POST /api/users/avatar", requireScope(auth.ScopeAccountWrite, uploadAvatar))
+	mux.HandleFunc("DELETE /api/users/avatar", requireScope(auth.ScopeAccountWrite, deleteAvatar))
+	mux.HandleFunc("GET /api/webhooks", requireScope(auth.ScopeAccountRead, webhookOwner(false, getWebhookEndpoints)))
+	mux.HandleFunc("POST /api/webhooks", requireScope(auth.ScopeAccountWrite, webhookOwner(false, createWebhookEndpoint)))
+	mux.HandleFunc("DELETE /api/webhooks/{endpointID}", requireScope(auth.ScopeAccountWrite, webhookOwner(false, deleteWebhookEndpoint)))
+	mux.HandleFunc("GET /api/webhooks/{endpointID}/deliveries", requireScope(auth.ScopeAccountRead, webhookOwner(false, getWebhookDeliveries)))
+	mux.HandleFunc("POST /api/webhooks/deliveries/{deliveryID}/retry", requireScope(auth.ScopeAccountWrite, webhookOwner(false, retryWebhookDelivery)))
+	mux.HandleFunc("GET /api/users/{userID}", getUserProfile)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", requireScope(auth.ScopeChirpsWrite, editChirp))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", requireScope(auth.ScopeChirpsWrite, deleteChirp))
+	mux.HandleFunc("POST /api/polka/webhooks", polkaWebhook)
+	mux.HandleFunc("POST /api/users/{userID}/follow", requireScope(auth.ScopeFollowsWrite, followUser))
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", requireScope(auth.ScopeFollowsWrite, unfollowUser))
+	mux.HandleFunc("GET /api/users/{userID}/followers", getFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", getFollowing)
+	mux.HandleFunc("GET /api/timeline", requireScope(auth.ScopeChirpsRead, getTimeline))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", requireScope(auth.ScopeChirpsWrite, likeChirp))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", requireScope(auth.ScopeChirpsWrite, unlikeChirp))
+	mux.HandleFunc("GET /api/users/{userID}/likes", getUserLikes)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", requireScope(auth.ScopeChirpsWrite, createRechirp))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", requireScope(auth.ScopeChirpsWrite, deleteRechirp))
+	mux.HandleFunc("GET /api/search", searchChirps)
+	mux.HandleFunc("GET /api/search/users", requireScope(auth.ScopeChirpsRead, searchUsers))
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", getTagChirps)
+	mux.HandleFunc("GET /api/trending", getTrending)
+	mux.HandleFunc("GET /api/notifications", requireScope(auth.ScopeNotificationsRead, getNotifications))
+	mux.HandleFunc("POST /api/notifications/read", requireScope(auth.ScopeNotificationsWrite, markAllNotificationsRead))
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", requireScope(auth.ScopeNotificationsWrite, markNotificationRead))
+	mux.HandleFunc("GET /admin/filters", getFilters)
+	mux.HandleFunc("POST /admin/filters", createFilter)
+	mux.HandleFunc("PUT /admin/filters/{filterID}", updateFilter)
+	mux.HandleFunc("DELETE /admin/filters/{filterID}", deleteFilter)
+	mux.HandleFunc("GET /admin/flags", getFlags)
+	mux.HandleFunc("POST /admin/flags/{flagID}/resolve", resolveFlag)
+	mux.HandleFunc("GET /admin/lockouts", getLockouts)
+	mux.HandleFunc("DELETE /admin/lockouts/{kind}/{subject}", clearLockout)
+	mux.HandleFunc("GET /admin/webhooks/events", getWebhookEvents)
+	mux.HandleFunc("POST /admin/webhooks/events/{eventID}/replay", replayWebhookEvent)
+	mux.HandleFunc("GET /admin/webhooks/endpoints", webhookOwner(true, getWebhookEndpoints))
+	mux.HandleFunc("POST /admin/webhooks/endpoints", webhookOwner(true, createWebhookEndpoint))
+	mux.HandleFunc("DELETE /admin/webhooks/endpoints/{endpointID}", webhookOwner(true, deleteWebhookEndpoint))
+	mux.HandleFunc("GET /admin/webhooks/endpoints/{endpointID}/deliveries", webhookOwner(true, getWebhookDeliveries))
+	mux.HandleFunc("POST /admin/webhooks/deliveries/{deliveryID}/retry", webhookOwner(true, retryWebhookDelivery))
 }
 
 func healthz(writer http.ResponseWriter, request *http.Request) {
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const notificationKindMention = "mention"
+
+// mentionChirp resolves the @handles in a new chirp's body, records a
+// mention for each one that belongs to a user and notifies those users,
+// except the ones in notified. Unknown handles stay plain text. Failures are
+// logged rather than failing the chirp, which has already been created.
+func mentionChirp(ctx context.Context, chirp database.Chirp, notified map[uuid.UUID]bool) {
+	mentions := entities.Mentions(chirp.Body)
+	if len(mentions) == 0 {
+		return
+	}
+
+	handles := []string{}
+	for _, mention := range mentions {
+		handles = append(handles, mention.Handle)
+	}
+	users, err := ApiCfg.Store.ListUsersByHandles(ctx, handles)
+	if err != nil {
+		log.Printf("unable to resolve mentions in chirp %s: %s", chirp.ID, err)
+		return
+	}
+	userIDs := map[string]uuid.UUID{}
+	for _, user := range users {
+		userIDs[user.Handle] = user.ID
+	}
+
+	if notified == nil {
+		notified = map[uuid.UUID]bool{}
+	}
+	for _, mention := range mentions {
+		userID, ok := userIDs[mention.Handle]
+		if !ok {
+			continue
+		}
+
+		params := database.CreateChirpMentionParams{
+			ChirpID:     chirp.ID,
+			UserID:      userID,
+			StartOffset: int32(mention.Start),
+			EndOffset:   int32(mention.End),
+		}
+		if err := ApiCfg.Store.CreateChirpMention(ctx, params); err != nil {
+			log.Printf("unable to save mention of %q in chirp %s: %s", mention.Handle, chirp.ID, err)
+			continue
+		}
+
+		// one notification per user, and none for mentioning yourself
+		if userID == chirp.UserID || notified[userID] {
+			continue
+		}
+		notified[userID] = true
+
+		notification := database.CreateNotificationParams{
+			UserID:  userID,
+			ActorID: chirp.UserID,
+			Kind:    notificationKindMention,
+			ChirpID: chirp.ID,
+		}
+		if err := ApiCfg.Store.CreateNotification(ctx, notification); err != nil {
+			log.Printf("unable to notify %s of chirp %s: %s", userID, chirp.ID, err)
+		}
+	}
+}
+
+// loadChirpEntities returns the hashtags and resolved mentions of each chirp
+// keyed by chirp ID, ready to be added to the chirp JSON. Tombstones have no
+// entities. A mention's handle is read from the body at its offsets, so it
+// is the handle as written even if the user has changed it since.
+func loadChirpEntities(request *http.Request, chirps []database.Chirp, chirpIDs []uuid.UUID) (map[uuid.UUID]map[string]interface{}, error) {
+	mentionRows, err := ApiCfg.Store.ListChirpMentions(request.Context(), chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	mentions := map[uuid.UUID][]database.ChirpMention{}
+	for _, row := range mentionRows {
+		mentions[row.ChirpID] = append(mentions[row.ChirpID], row)
+	}
+
+	chirpEntities := map[uuid.UUID]map[string]interface{}{}
+	for _, chirp := range chirps {
+		hashtagsSlice := []map[string]interface{}{}
+		mentionsSlice := []map[string]interface{}{}
+		if !chirp.DeletedAt.Valid {
+			for _, hashtag := range entities.Hashtags(chirp.Body) {
+				hashtagsSlice = append(hashtagsSlice, map[string]interface{}{
+					"tag":   hashtag.Tag,
+					"start": hashtag.Start,
+					"end":   hashtag.End,
+				})
+			}
+			body := []rune(chirp.Body)
+			for _, mention := range mentions[chirp.ID] {
+				if mention.StartOffset < 0 || int(mention.EndOffset) > len(body) || mention.StartOffset >= mention.EndOffset {
+					continue
+				}
+				mentionsSlice = append(mentionsSlice, map[string]interface{}{
+					"user_id": mention.UserID.String(),
+					"handle":  strings.ToLower(string(body[mention.StartOffset+1 : mention.EndOffset])),
+					"start":   mention.StartOffset,
+					"end":     mention.EndOffset,
+				})
+			}
+		}
+
+		chirpEntities[chirp.ID] = map[string]interface{}{
+			"hashtags": hashtagsSlice,
+			"mentions": mentionsSlice,
+		}
+	}
+	return chirpEntities, nil
+}
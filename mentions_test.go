@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// createTestUserWithHandle registers a user with the given handle and logs
+// them in, returning the login response.
+func createTestUserWithHandle(t *testing.T, mux *http.ServeMux, email, handle string) map[string]interface{} {
+	t.Helper()
+	credentials := userRequest{Email: email, Password: "hunter2", Handle: handle}
+	if rec := doRequest(t, mux, "POST", "/api/users", "", credentials); rec.Code != 201 {
+		t.Fatalf("create user: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	verifyTestEmail(t, mux, email)
+
+	rec := doRequest(t, mux, "POST", "/api/login", "", credentials)
+	if rec.Code != 200 {
+		t.Fatalf("login: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	loginResp := map[string]interface{}{}
+	decodeResponse(t, rec, &loginResp)
+	return loginResp
+}
+
+func TestHandles(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUserWithHandle(t, mux, "walt@breakingbad.com", "@Heisenberg")
+	if walt["handle"] != "heisenberg" {
+		t.Errorf("expected normalized handle, got %v", walt["handle"])
+	}
+
+	generated := createTestUser(t, mux, "jesse@breakingbad.com")
+	if handle, _ := generated["handle"].(string); !strings.HasPrefix(handle, "user_") || len(handle) != 15 {
+		t.Errorf("expected a generated handle, got %v", generated["handle"])
+	}
+
+	taken := userRequest{Email: "saul@bettercall.com", Password: "hunter2", Handle: "heisenberg"}
+	if rec := doRequest(t, mux, "POST", "/api/users", "", taken); rec.Code != 409 {
+		t.Errorf("taken handle: expected 409, got %d", rec.Code)
+	}
+	invalid := userRequest{Email: "saul@bettercall.com", Password: "hunter2", Handle: "no spaces"}
+	if rec := doRequest(t, mux, "POST", "/api/users", "", invalid); rec.Code != 400 {
+		t.Errorf("invalid handle: expected 400, got %d", rec.Code)
+	}
+}
+
+type notificationPage struct {
+	Notifications []map[string]interface{} `json:"notifications"`
+	UnreadCount   int                      `json:"unread_count"`
+	NextCursor    *string                  `json:"next_cursor"`
+}
+
+func TestMentionsAndNotifications(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUserWithHandle(t, mux, "walt@breakingbad.com", "heisenberg")
+	jesse := createTestUserWithHandle(t, mux, "jesse@breakingbad.com", "cap_n_cook")
+	waltToken := walt["token"].(string)
+	jesseToken := jesse["token"].(string)
+
+	chirp := postChirp(t, mux, waltToken, map[string]interface{}{"body": "@Cap_n_Cook @nobody_here @cap_n_cook @heisenberg #cook"})
+	chirpEntities := chirp["entities"].(map[string]interface{})
+	mentions := chirpEntities["mentions"].([]interface{})
+	if len(mentions) != 3 {
+		t.Fatalf("expected 3 resolved mentions, got %v", mentions)
+	}
+	first := mentions[0].(map[string]interface{})
+	if first["user_id"] != jesse["id"] || first["handle"] != "cap_n_cook" || first["start"] != float64(0) || first["end"] != float64(11) {
+		t.Errorf("unexpected first mention %v", first)
+	}
+	if hashtags := chirpEntities["hashtags"].([]interface{}); len(hashtags) != 1 {
+		t.Errorf("expected one hashtag entity, got %v", hashtags)
+	}
+
+	// a renamed user's mentions keep the handle that matches their offsets
+	newHandle := "pinkman"
+	if rec := doRequest(t, mux, "PATCH", "/api/users", jesseToken, patchUserRequest{Handle: &newHandle}); rec.Code != 200 {
+		t.Fatalf("rename: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	rec := doRequest(t, mux, "GET", "/api/chirps/"+chirp["id"].(string), "", nil)
+	fetched := map[string]interface{}{}
+	decodeResponse(t, rec, &fetched)
+	first = fetched["entities"].(map[string]interface{})["mentions"].([]interface{})[0].(map[string]interface{})
+	if first["user_id"] != jesse["id"] || first["handle"] != "cap_n_cook" {
+		t.Errorf("expected the handle as written after a rename, got %v", first)
+	}
+
+	postChirp(t, mux, waltToken, map[string]interface{}{"body": "Yo @pinkman"})
+
+	rec = doRequest(t, mux, "GET", "/api/notifications?limit=1", jesseToken, nil)
+	if rec.Code != 200 {
+		t.Fatalf("notifications: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	page := notificationPage{}
+	decodeResponse(t, rec, &page)
+	if page.UnreadCount != 2 || len(page.Notifications) != 1 || page.NextCursor == nil {
+		t.Fatalf("unexpected notification page %+v", page)
+	}
+	latest := page.Notifications[0]
+	if latest["kind"] != "mention" || latest["actor_id"] != walt["id"] || latest["read"] != false {
+		t.Errorf("unexpected notification %v", latest)
+	}
+	if embedded := latest["chirp"].(map[string]interface{}); embedded["body"] != "Yo @pinkman" {
+		t.Errorf("unexpected embedded chirp %v", embedded)
+	}
+
+	// walt mentioned himself, which does not notify
+	decodeResponse(t, doRequest(t, mux, "GET", "/api/notifications", waltToken, nil), &page)
+	if len(page.Notifications) != 0 {
+		t.Errorf("expected no self-mention notifications, got %v", page.Notifications)
+	}
+
+	path := "/api/notifications/" + latest["id"].(string) + "/read"
+	if rec := doRequest(t, mux, "POST", path, waltToken, nil); rec.Code != 404 {
+		t.Errorf("mark another user's notification: expected 404, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", path, jesseToken, nil); rec.Code != 204 {
+		t.Errorf("mark read: expected 204, got %d", rec.Code)
+	}
+
+	decodeResponse(t, doRequest(t, mux, "GET", "/api/notifications?unread=true", jesseToken, nil), &page)
+	if page.UnreadCount != 1 || len(page.Notifications) != 1 || page.Notifications[0]["id"] == latest["id"] {
+		t.Errorf("unexpected unread page %+v", page)
+	}
+
+	if rec := doRequest(t, mux, "POST", "/api/notifications/read", jesseToken, nil); rec.Code != 204 {
+		t.Errorf("mark all read: expected 204, got %d", rec.Code)
+	}
+	decodeResponse(t, doRequest(t, mux, "GET", "/api/notifications", jesseToken, nil), &page)
+	if page.UnreadCount != 0 || len(page.Notifications) != 2 {
+		t.Errorf("expected two read notifications, got %+v", page)
+	}
+}
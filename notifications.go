@@ -0,0 +1,143 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func getNotifications(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	query := request.URL.Query()
+	page, err := parsePageRequest(query.Get("limit"), query.Get("cursor"), "desc")
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return
+	}
+
+	notifications, err := ApiCfg.Store.ListNotifications(request.Context(), database.ListNotificationsParams{
+		UserID:          userID,
+		UnreadOnly:      query.Get("unread") == "true",
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		RowLimit:        page.Limit + 1,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	notifications, next := trimPage(notifications, page.Limit, func(notification database.Notification) pageCursor {
+		return pageCursor{CreatedAt: notification.CreatedAt, ID: notification.ID}
+	})
+
+	unread, err := ApiCfg.Store.CountUnreadNotifications(request.Context(), userID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// embed the chirps the notifications point at
+	chirpIDs := []uuid.UUID{}
+	for _, notification := range notifications {
+		chirpIDs = append(chirpIDs, notification.ChirpID)
+	}
+	chirps := []database.Chirp{}
+	if len(chirpIDs) > 0 {
+		chirps, err = ApiCfg.Store.ListChirpsByIDs(request.Context(), chirpIDs)
+		if err != nil {
+			respondWithJsonError(writer, "Something went wrong", 500)
+			return
+		}
+	}
+	chirpsSlice, err := makeChirpsSlice(request, chirps)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	renderedChirps := map[uuid.UUID]map[string]interface{}{}
+	for i, chirp := range chirps {
+		renderedChirps[chirp.ID] = chirpsSlice[i]
+	}
+
+	notificationsSlice := []map[string]interface{}{}
+	for _, notification := range notifications {
+		notificationMap := makeNotificationMap(notification)
+		notificationMap["chirp"] = renderedChirps[notification.ChirpID]
+		notificationsSlice = append(notificationsSlice, notificationMap)
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{
+		"notifications": notificationsSlice,
+		"unread_count":  unread,
+		"next_cursor":   next,
+	})
+}
+
+func makeNotificationMap(notification database.Notification) map[string]interface{} {
+	notificationMap := map[string]interface{}{
+		"id":         notification.ID.String(),
+		"created_at": notification.CreatedAt.String(),
+		"kind":       notification.Kind,
+		"actor_id":   notification.ActorID.String(),
+		"chirp_id":   notification.ChirpID.String(),
+		"read":       notification.ReadAt.Valid,
+		"read_at":    nil,
+	}
+
+	if notification.ReadAt.Valid {
+		notificationMap["read_at"] = notification.ReadAt.Time.String()
+	}
+
+	return notificationMap
+}
+
+func markNotificationRead(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	notificationID, err := uuid.Parse(request.PathValue("notificationID"))
+	if err != nil {
+		respondWithJsonError(writer, "Notification not found", 404)
+		return
+	}
+
+	// marking an already read notification keeps its original read_at
+	marked, err := ApiCfg.Store.MarkNotificationRead(request.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if marked == 0 {
+		respondWithJsonError(writer, "Notification not found", 404)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
+
+func markAllNotificationsRead(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	if _, err := ApiCfg.Store.MarkAllNotificationsRead(request.Context(), userID); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
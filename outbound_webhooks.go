@@ -0,0 +1,561 @@
+package main
+
+import (
+	"bytes"
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webhookSignatureHeader = "Chirpy-Signature"
+
+	webhookEventChirpCreated = "chirp.created"
+	webhookEventChirpUpdated = "chirp.updated"
+	webhookEventChirpDeleted = "chirp.deleted"
+	webhookEventUserUpdated  = "user.updated"
+
+	// a delivery that fails webhookMaxAttempts times is dead; it stays in
+	// the log and can be retried by hand, which sends it once more
+	webhookMaxAttempts = 10
+	webhookRetryBase   = time.Minute
+	webhookRetryMax    = 6 * time.Hour
+
+	// webhookLease keeps a claimed delivery from being sent twice while it
+	// is in flight. Deliveries that can't be sent before it runs out are
+	// left for the next claim.
+	webhookLease       = 2 * time.Minute
+	webhookBatchSize   = 50
+	webhookInterval    = 5 * time.Second
+	webhookTimeout     = 10 * time.Second
+	maxWebhookURL      = 2048
+	maxUserWebhooks    = 10
+	maxWebhookErrorLen = 500
+)
+
+var webhookEventTypes = []string{
+	webhookEventChirpCreated,
+	webhookEventChirpUpdated,
+	webhookEventChirpDeleted,
+	webhookEventUserUpdated,
+}
+
+// webhookClock is the time deliveries are claimed and retried at; tests
+// replace it.
+var webhookClock = time.Now
+
+var errPrivateWebhookAddress = errors.New("webhook address is not public")
+
+// webhookClient sends deliveries. It refuses to connect to loopback and
+// private addresses, so endpoints can't be used to reach internal services.
+var webhookClient = newWebhookClient(false)
+
+func newWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !allowPrivate {
+		// checked on the resolved address, so DNS can't point around it
+		dialer.Control = func(network, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !publicAddress(ip) {
+				return errPrivateWebhookAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		// a redirect counts as a failed delivery rather than being followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// publishWebhookEvent queues a delivery of the event to every endpoint that
+// subscribed to it: the owner's own endpoints and the ones admins
+// registered. Failures are logged rather than failing the request that
+// caused the event.
+func publishWebhookEvent(ctx context.Context, eventType string, ownerID uuid.UUID, data map[string]interface{}) {
+	endpoints, err := ApiCfg.Store.ListWebhookEndpointsForEvent(ctx, database.ListWebhookEndpointsForEventParams{
+		EventType: eventType,
+		UserID:    uuid.NullUUID{UUID: ownerID, Valid: true},
+	})
+	if err != nil {
+		log.Printf("unable to find webhook endpoints for %s: %s", eventType, err)
+		return
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+
+	eventID := uuid.New()
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         eventID,
+		"type":       eventType,
+		"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+		"data":       data,
+	})
+	if err != nil {
+		log.Printf("unable to encode webhook event %s: %s", eventType, err)
+		return
+	}
+
+	for _, endpoint := range endpoints {
+		err := ApiCfg.Store.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			EndpointID: endpoint.ID,
+			EventID:    eventID,
+			EventType:  eventType,
+			Payload:    string(payload),
+		})
+		if err != nil {
+			log.Printf("unable to queue webhook %s for endpoint %s: %s", eventType, endpoint.ID, err)
+		}
+	}
+}
+
+// publishChirpDeleted queues chirp.deleted with the chirp as a tombstone.
+func publishChirpDeleted(ctx context.Context, chirp database.Chirp) {
+	tombstone := makeChirpMap(chirp)
+	tombstone["body"] = ""
+	tombstone["deleted"] = true
+	publishWebhookEvent(ctx, webhookEventChirpDeleted, chirp.UserID, tombstone)
+}
+
+// publishUserUpdated queues user.updated with the user's public fields
+// only. Admin endpoints receive it for every user, so nothing private goes
+// out with it.
+func publishUserUpdated(ctx context.Context, user database.User) {
+	publishWebhookEvent(ctx, webhookEventUserUpdated, user.ID, makePublicUserMap(user))
+}
+
+// deliverWebhooks sends every delivery that is due. Each endpoint gets its
+// deliveries in order, and endpoints are sent to concurrently so a slow one
+// only holds up its own.
+func deliverWebhooks(ctx context.Context) error {
+	now := webhookClock().UTC()
+	leaseUntil := now.Add(webhookLease)
+	deliveries, err := ApiCfg.Store.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+		LeaseUntil: leaseUntil,
+		Now:        now,
+		RowLimit:   webhookBatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	byEndpoint := map[uuid.UUID][]database.WebhookDelivery{}
+	for _, delivery := range deliveries {
+		byEndpoint[delivery.EndpointID] = append(byEndpoint[delivery.EndpointID], delivery)
+	}
+
+	var wg sync.WaitGroup
+	for _, queue := range byEndpoint {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, delivery := range queue {
+				// a delivery that might still be in flight when the lease
+				// ends could be claimed and sent again, so it waits instead
+				if webhookClock().Add(webhookTimeout).After(leaseUntil) {
+					return
+				}
+				deliverWebhook(ctx, delivery)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func deliverWebhook(ctx context.Context, delivery database.WebhookDelivery) {
+	endpoint, err := ApiCfg.Store.GetWebhookEndpoint(ctx, delivery.EndpointID)
+	if err != nil {
+		log.Printf("unable to load webhook endpoint %s: %s", delivery.EndpointID, err)
+		return
+	}
+
+	responseStatus, sendErr := sendWebhook(ctx, endpoint, delivery)
+
+	// earlier deliveries in the batch may have taken a while, so the retry
+	// is scheduled from when this one finished
+	now := webhookClock().UTC()
+	params := database.RecordWebhookDeliveryAttemptParams{
+		ID:             delivery.ID,
+		Status:         "succeeded",
+		NextAttemptAt:  now,
+		ResponseStatus: responseStatus,
+	}
+	if sendErr != nil {
+		message := sendErr.Error()
+		if len(message) > maxWebhookErrorLen {
+			message = message[:maxWebhookErrorLen]
+		}
+		params.LastError = sql.NullString{String: message, Valid: true}
+
+		attempts := delivery.Attempts + 1
+		if attempts >= webhookMaxAttempts {
+			params.Status = "dead"
+		} else {
+			params.Status = "pending"
+			params.NextAttemptAt = now.Add(webhookRetryDelay(attempts))
+		}
+	}
+
+	if _, err := ApiCfg.Store.RecordWebhookDeliveryAttempt(ctx, params); err != nil {
+		log.Printf("unable to record webhook delivery %s: %s", delivery.ID, err)
+	}
+}
+
+// sendWebhook posts the delivery's payload, signed with the endpoint's
+// secret. Anything but a 2xx response is an error.
+func sendWebhook(ctx context.Context, endpoint database.WebhookEndpoint, delivery database.WebhookDelivery) (sql.NullInt32, error) {
+	body := []byte(delivery.Payload)
+	request, err := http.NewRequestWithContext(ctx, "POST", endpoint.Url, bytes.NewReader(body))
+	if err != nil {
+		return sql.NullInt32{}, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Chirpy-Webhooks")
+	request.Header.Set("Chirpy-Event", delivery.EventType)
+	request.Header.Set("Chirpy-Delivery", delivery.ID.String())
+	request.Header.Set(webhookSignatureHeader, auth.SignWebhook(endpoint.Secret, time.Now(), body))
+
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		return sql.NullInt32{}, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	status := sql.NullInt32{Int32: int32(response.StatusCode), Valid: true}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return status, fmt.Errorf("endpoint responded with %s", response.Status)
+	}
+	return status, nil
+}
+
+// webhookRetryDelay doubles the wait after every failed attempt.
+func webhookRetryDelay(attempts int32) time.Duration {
+	delay := webhookRetryBase
+	for i := int32(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookRetryMax {
+			return webhookRetryMax
+		}
+	}
+	return delay
+}
+
+// runWebhookDeliveries sends due deliveries every interval until ctx is
+// cancelled.
+func runWebhookDeliveries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := deliverWebhooks(ctx); err != nil {
+			log.Printf("unable to deliver webhooks: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type webhookEndpointRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// webhookOwner wraps the endpoint management handlers. Admin routes manage
+// the endpoints without an owner, which receive every event; the others
+// manage the authenticated user's own endpoints.
+func webhookOwner(admin bool, handler func(http.ResponseWriter, *http.Request, uuid.NullUUID)) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if admin {
+			if !isAdmin(request) {
+				respondWithJsonError(writer, "Unauthorized", 401)
+				return
+			}
+			handler(writer, request, uuid.NullUUID{})
+			return
+		}
+
+		userID, err := authenticatedUserID(request)
+		if err != nil {
+			respondWithJsonError(writer, "Unauthorized", 401)
+			return
+		}
+		handler(writer, request, uuid.NullUUID{UUID: userID, Valid: true})
+	}
+}
+
+func createWebhookEndpoint(writer http.ResponseWriter, request *http.Request, owner uuid.NullUUID) {
+	endpointReq := webhookEndpointRequest{}
+	if err := json.NewDecoder(request.Body).Decode(&endpointReq); err != nil {
+		respondWithJsonError(writer, "Invalid request body", 400)
+		return
+	}
+
+	parsed, err := url.Parse(endpointReq.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(endpointReq.URL) > maxWebhookURL {
+		respondWithJsonError(writer, "url must be an http or https URL", 400)
+		return
+	}
+
+	events := []string{}
+	for _, event := range endpointReq.Events {
+		if !slices.Contains(webhookEventTypes, event) {
+			respondWithJsonError(writer, fmt.Sprintf("Unknown event %q", event), 400)
+			return
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		respondWithJsonError(writer, "At least one event is required", 400)
+		return
+	}
+
+	if owner.Valid {
+		existing, err := ApiCfg.Store.ListWebhookEndpoints(request.Context(), owner)
+		if err != nil {
+			respondWithJsonError(writer, "Something went wrong", 500)
+			return
+		}
+		if len(existing) >= maxUserWebhooks {
+			respondWithJsonError(writer, fmt.Sprintf("No more than %d webhook endpoints are allowed", maxUserWebhooks), 400)
+			return
+		}
+	}
+
+	// the secret is kept in plain text since every delivery is signed with it
+	secret, err := auth.MakeOneTimeToken()
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	endpoint, err := ApiCfg.Store.CreateWebhookEndpoint(request.Context(), database.CreateWebhookEndpointParams{
+		UserID: owner,
+		Url:    endpointReq.URL,
+		Secret: "whsec_" + secret,
+		Events: events,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// the secret is only shown once
+	endpointMap := makeWebhookEndpointMap(endpoint)
+	endpointMap["secret"] = endpoint.Secret
+	respondWithJson(writer, 201, endpointMap)
+}
+
+func getWebhookEndpoints(writer http.ResponseWriter, request *http.Request, owner uuid.NullUUID) {
+	endpoints, err := ApiCfg.Store.ListWebhookEndpoints(request.Context(), owner)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	endpointsSlice := []map[string]interface{}{}
+	for _, endpoint := range endpoints {
+		endpointsSlice = append(endpointsSlice, makeWebhookEndpointMap(endpoint))
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{"endpoints": endpointsSlice})
+}
+
+func deleteWebhookEndpoint(writer http.ResponseWriter, request *http.Request, owner uuid.NullUUID) {
+	endpoint, ok := ownedWebhookEndpoint(writer, request, owner)
+	if !ok {
+		return
+	}
+
+	if _, err := ApiCfg.Store.DeleteWebhookEndpoint(request.Context(), endpoint.ID); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
+
+// getWebhookDeliveries is the delivery log of an endpoint, newest first.
+func getWebhookDeliveries(writer http.ResponseWriter, request *http.Request, owner uuid.NullUUID) {
+	endpoint, ok := ownedWebhookEndpoint(writer, request, owner)
+	if !ok {
+		return
+	}
+
+	limit, err := parseLimit(request.URL.Query().Get("limit"))
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return
+	}
+
+	deliveries, err := ApiCfg.Store.ListWebhookDeliveries(request.Context(), database.ListWebhookDeliveriesParams{
+		EndpointID: endpoint.ID,
+		RowLimit:   limit,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	deliveriesSlice := []map[string]interface{}{}
+	for _, delivery := range deliveries {
+		deliveriesSlice = append(deliveriesSlice, makeWebhookDeliveryMap(delivery))
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{"deliveries": deliveriesSlice})
+}
+
+// retryWebhookDelivery queues a dead delivery to be sent once more. Its
+// earlier attempts still count, so if that send fails it is dead again.
+func retryWebhookDelivery(writer http.ResponseWriter, request *http.Request, owner uuid.NullUUID) {
+	deliveryID, err := uuid.Parse(request.PathValue("deliveryID"))
+	if err != nil {
+		respondWithJsonError(writer, "Delivery not found", 404)
+		return
+	}
+
+	delivery, err := ApiCfg.Store.GetWebhookDelivery(request.Context(), deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Delivery not found", 404)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// admins can retry any delivery, users only those of their endpoints
+	if owner.Valid {
+		endpoint, err := ApiCfg.Store.GetWebhookEndpoint(request.Context(), delivery.EndpointID)
+		if err != nil || endpoint.UserID != owner {
+			respondWithJsonError(writer, "Delivery not found", 404)
+			return
+		}
+	}
+
+	retried, err := ApiCfg.Store.RetryWebhookDelivery(request.Context(), deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Only dead deliveries can be retried", 409)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	respondWithJson(writer, 200, makeWebhookDeliveryMap(retried))
+}
+
+// ownedWebhookEndpoint loads the endpoint in the path, responding with 404
+// if it doesn't exist or belongs to someone else.
+func ownedWebhookEndpoint(writer http.ResponseWriter, request *http.Request, owner uuid.NullUUID) (database.WebhookEndpoint, bool) {
+	endpointID, err := uuid.Parse(request.PathValue("endpointID"))
+	if err != nil {
+		respondWithJsonError(writer, "Webhook endpoint not found", 404)
+		return database.WebhookEndpoint{}, false
+	}
+
+	endpoint, err := ApiCfg.Store.GetWebhookEndpoint(request.Context(), endpointID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && endpoint.UserID != owner) {
+		respondWithJsonError(writer, "Webhook endpoint not found", 404)
+		return database.WebhookEndpoint{}, false
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return database.WebhookEndpoint{}, false
+	}
+	return endpoint, true
+}
+
+func makeWebhookEndpointMap(endpoint database.WebhookEndpoint) map[string]interface{} {
+	endpointMap := map[string]interface{}{
+		"id":         endpoint.ID,
+		"created_at": endpoint.CreatedAt.String(),
+		"url":        endpoint.Url,
+		"events":     endpoint.Events,
+		"user_id":    nil,
+	}
+	if endpoint.UserID.Valid {
+		endpointMap["user_id"] = endpoint.UserID.UUID
+	}
+	return endpointMap
+}
+
+func makeWebhookDeliveryMap(delivery database.WebhookDelivery) map[string]interface{} {
+	deliveryMap := map[string]interface{}{
+		"id":              delivery.ID,
+		"created_at":      delivery.CreatedAt.String(),
+		"endpoint_id":     delivery.EndpointID,
+		"event_id":        delivery.EventID,
+		"event_type":      delivery.EventType,
+		"payload":         json.RawMessage(delivery.Payload),
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": nil,
+		"last_attempt_at": nil,
+		"response_status": nil,
+		"last_error":      nil,
+		"delivered_at":    nil,
+	}
+	if delivery.Status == "pending" {
+		deliveryMap["next_attempt_at"] = delivery.NextAttemptAt.String()
+	}
+	if delivery.LastAttemptAt.Valid {
+		deliveryMap["last_attempt_at"] = delivery.LastAttemptAt.Time.String()
+	}
+	if delivery.ResponseStatus.Valid {
+		deliveryMap["response_status"] = delivery.ResponseStatus.Int32
+	}
+	if delivery.LastError.Valid {
+		deliveryMap["last_error"] = delivery.LastError.String
+	}
+	if delivery.DeliveredAt.Valid {
+		deliveryMap["delivered_at"] = delivery.DeliveredAt.Time.String()
+	}
+	return deliveryMap
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the deliveries it receives and answers with
+// status.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookReceiver(t *testing.T) (*webhookReceiver, *httptest.Server) {
+	t.Helper()
+	receiver := &webhookReceiver{status: 204}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, request)
+		receiver.bodies = append(receiver.bodies, body)
+		writer.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server
+}
+
+func (receiver *webhookReceiver) respondWith(status int) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	receiver.status = status
+}
+
+func (receiver *webhookReceiver) received() ([]*http.Request, [][]byte) {
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	return receiver.requests, receiver.bodies
+}
+
+// createdWebhook decodes the endpoint from a create webhook response.
+func createdWebhook(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	if rec.Code != 201 {
+		t.Fatalf("create webhook: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	endpoint := map[string]interface{}{}
+	decodeResponse(t, rec, &endpoint)
+	return endpoint
+}
+
+// webhookDeliveries decodes the deliveries from a delivery log response.
+func webhookDeliveries(t *testing.T, rec *httptest.ResponseRecorder) []map[string]interface{} {
+	t.Helper()
+	if rec.Code != 200 {
+		t.Fatalf("list deliveries: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	page := struct {
+		Deliveries []map[string]interface{} `json:"deliveries"`
+	}{}
+	decodeResponse(t, rec, &page)
+	return page.Deliveries
+}
+
+func TestOutboundWebhookDelivery(t *testing.T) {
+	mux := newTestMux(t)
+	receiver, server := newWebhookReceiver(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	token := user["token"].(string)
+	other := createTestUser(t, mux, "jesse@breakingbad.com")
+
+	endpoint := createdWebhook(t, doRequest(t, mux, "POST", "/api/webhooks", token, webhookEndpointRequest{URL: server.URL, Events: []string{"chirp.created", "chirp.deleted"}}))
+	secret, _ := endpoint["secret"].(string)
+	if !strings.HasPrefix(secret, "whsec_") {
+		t.Fatalf("expected a signing secret, got %v", endpoint)
+	}
+	rec := doRequest(t, mux, "GET", "/api/webhooks", token, nil)
+	if strings.Contains(rec.Body.String(), secret) {
+		t.Errorf("listing endpoints leaked the secret: %s", rec.Body.String())
+	}
+
+	chirp := postChirp(t, mux, token, map[string]interface{}{"body": "say my name"})
+	postChirp(t, mux, other["token"].(string), map[string]interface{}{"body": "yeah science"})
+	if rec := doRequest(t, mux, "DELETE", "/api/chirps/"+chirp["id"].(string), token, nil); rec.Code != 204 {
+		t.Fatalf("delete chirp: status %d", rec.Code)
+	}
+
+	if err := deliverWebhooks(context.Background()); err != nil {
+		t.Fatalf("deliver webhooks: %v", err)
+	}
+
+	requests, bodies := receiver.received()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 deliveries of the user's own events, got %d", len(requests))
+	}
+	for i, request := range requests {
+		signature := request.Header.Get(webhookSignatureHeader)
+		timestamp, _ := strings.CutPrefix(strings.Split(signature, ",")[0], "t=")
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || signature != auth.SignWebhook(secret, time.Unix(unix, 0), bodies[i]) {
+			t.Errorf("delivery %d has an invalid signature %q", i, signature)
+		}
+		if !strings.Contains(string(bodies[i]), chirp["id"].(string)) {
+			t.Errorf("delivery %d is about the wrong chirp: %s", i, bodies[i])
+		}
+	}
+	if requests[0].Header.Get("Chirpy-Event") == requests[1].Header.Get("Chirpy-Event") {
+		t.Errorf("expected a chirp.created and a chirp.deleted delivery")
+	}
+
+	deliveries := webhookDeliveries(t, doRequest(t, mux, "GET", "/api/webhooks/"+endpoint["id"].(string)+"/deliveries", token, nil))
+	if len(deliveries) != 2 {
+		t.Fatalf("expected 2 deliveries in the log, got %d", len(deliveries))
+	}
+	for _, delivery := range deliveries {
+		if delivery["status"] != "succeeded" || delivery["response_status"] != float64(204) || delivery["delivered_at"] == nil {
+			t.Errorf("unexpected delivery %v", delivery)
+		}
+	}
+
+	// endpoints and their logs are private to their owner
+	otherToken := other["token"].(string)
+	if rec := doRequest(t, mux, "GET", "/api/webhooks/"+endpoint["id"].(string)+"/deliveries", otherToken, nil); rec.Code != 404 {
+		t.Errorf("another user's delivery log: expected 404, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "DELETE", "/api/webhooks/"+endpoint["id"].(string), otherToken, nil); rec.Code != 404 {
+		t.Errorf("delete another user's endpoint: expected 404, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "DELETE", "/api/webhooks/"+endpoint["id"].(string), token, nil); rec.Code != 204 {
+		t.Errorf("delete endpoint: expected 204, got %d", rec.Code)
+	}
+}
+
+func TestOutboundWebhookSlowEndpoint(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-release
+		writer.WriteHeader(204)
+	}))
+	t.Cleanup(slow.Close)
+	var once sync.Once
+	releaseSlow := func() { once.Do(func() { close(release) }) }
+	t.Cleanup(releaseSlow)
+
+	reached := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		reached <- struct{}{}
+		writer.WriteHeader(204)
+	}))
+	t.Cleanup(fast.Close)
+
+	createdWebhook(t, doAdminRequest(t, mux, "POST", "/admin/webhooks/endpoints", webhookEndpointRequest{URL: slow.URL, Events: []string{"chirp.created"}}))
+	createdWebhook(t, doRequest(t, mux, "POST", "/api/webhooks", user["token"].(string), webhookEndpointRequest{URL: fast.URL, Events: []string{"chirp.created"}}))
+	postChirp(t, mux, user["token"].(string), map[string]interface{}{"body": "say my name"})
+
+	done := make(chan error, 1)
+	go func() { done <- deliverWebhooks(context.Background()) }()
+
+	// the fast endpoint doesn't wait for the slow one
+	select {
+	case <-reached:
+	case <-time.After(5 * time.Second):
+		t.Errorf("delivery to the fast endpoint waited for the slow one")
+	}
+	releaseSlow()
+	if err := <-done; err != nil {
+		t.Fatalf("deliver webhooks: %v", err)
+	}
+}
+
+func TestOutboundWebhookRetries(t *testing.T) {
+	mux := newTestMux(t)
+	receiver, server := newWebhookReceiver(t)
+	receiver.respondWith(500)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+
+	// admin endpoints receive events of every user
+	endpoint := createdWebhook(t, doAdminRequest(t, mux, "POST", "/admin/webhooks/endpoints", webhookEndpointRequest{URL: server.URL, Events: []string{"user.updated"}}))
+	logPath := "/admin/webhooks/endpoints/" + endpoint["id"].(string) + "/deliveries"
+	bio := "chemistry teacher"
+	if rec := doRequest(t, mux, "PATCH", "/api/users", user["token"].(string), patchUserRequest{Bio: &bio}); rec.Code != 200 {
+		t.Fatalf("patch user: status %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	now := time.Now().UTC()
+	webhookClock = func() time.Time { return now }
+	t.Cleanup(func() { webhookClock = time.Now })
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		if err := deliverWebhooks(context.Background()); err != nil {
+			t.Fatalf("deliver webhooks: %v", err)
+		}
+		// nothing is sent again before the backoff has passed
+		if err := deliverWebhooks(context.Background()); err != nil {
+			t.Fatalf("deliver webhooks: %v", err)
+		}
+		if requests, _ := receiver.received(); len(requests) != attempt {
+			t.Fatalf("attempt %d: expected %d requests, got %d", attempt, attempt, len(requests))
+		}
+		now = now.Add(webhookRetryMax)
+	}
+
+	// admin endpoints see every user, so only public fields are sent
+	_, bodies := receiver.received()
+	event := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	if err := json.Unmarshal(bodies[0], &event); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+	if event.Data["bio"] != bio {
+		t.Errorf("expected the updated bio, got %v", event.Data)
+	}
+	for _, key := range []string{"email", "email_verified", "subscription", "entitlements", "pending_email"} {
+		if _, ok := event.Data[key]; ok {
+			t.Errorf("user.updated includes private field %q", key)
+		}
+	}
+
+	deliveries := webhookDeliveries(t, doAdminRequest(t, mux, "GET", logPath, nil))
+	if len(deliveries) != 1 || deliveries[0]["status"] != "dead" || deliveries[0]["response_status"] != float64(500) {
+		t.Fatalf("expected a dead delivery, got %v", deliveries)
+	}
+	deliveryID := deliveries[0]["id"].(string)
+
+	// users can't retry deliveries to an admin endpoint
+	if rec := doRequest(t, mux, "POST", "/api/webhooks/deliveries/"+deliveryID+"/retry", user["token"].(string), nil); rec.Code != 404 {
+		t.Errorf("retry by user: expected 404, got %d", rec.Code)
+	}
+
+	receiver.respondWith(200)
+	if rec := doAdminRequest(t, mux, "POST", "/admin/webhooks/deliveries/"+deliveryID+"/retry", nil); rec.Code != 200 {
+		t.Fatalf("retry: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := doAdminRequest(t, mux, "POST", "/admin/webhooks/deliveries/"+deliveryID+"/retry", nil); rec.Code != 409 {
+		t.Errorf("retry of a pending delivery: expected 409, got %d", rec.Code)
+	}
+	if err := deliverWebhooks(context.Background()); err != nil {
+		t.Fatalf("deliver webhooks: %v", err)
+	}
+
+	// the attempts before the retry still count
+	deliveries = webhookDeliveries(t, doAdminRequest(t, mux, "GET", logPath, nil))
+	if deliveries[0]["status"] != "succeeded" || deliveries[0]["attempts"] != float64(webhookMaxAttempts+1) {
+		t.Errorf("expected the retried delivery to succeed, got %v", deliveries[0])
+	}
+}
+
+func TestWebhookEndpointValidation(t *testing.T) {
+	mux := newTestMux(t)
+	token := createTestUser(t, mux, "walt@breakingbad.com")["token"].(string)
+
+	for _, payload := range []map[string]interface{}{
+		{"url": "ftp://example.com/hook", "events": []string{"chirp.created"}},
+		{"url": "https://", "events": []string{"chirp.created"}},
+		{"url": "https://example.com/hook", "events": []string{}},
+		{"url": "https://example.com/hook", "events": []string{"chirp.liked"}},
+	} {
+		if rec := doRequest(t, mux, "POST", "/api/webhooks", token, payload); rec.Code != 400 {
+			t.Errorf("%v: expected 400, got %d", payload, rec.Code)
+		}
+	}
+
+	if rec := doRequest(t, mux, "GET", "/admin/webhooks/endpoints", token, nil); rec.Code != 401 {
+		t.Errorf("admin endpoints as a user: expected 401, got %d", rec.Code)
+	}
+}
+
+func TestWebhookClientBlocksPrivateAddresses(t *testing.T) {
+	_, server := newWebhookReceiver(t)
+
+	response, err := newWebhookClient(false).Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err == nil {
+		response.Body.Close()
+		t.Fatal("expected the request to a loopback address to be refused")
+	}
+	if !strings.Contains(err.Error(), errPrivateWebhookAddress.Error()) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageCursor is the keyset position of the last row on a page. Clients only
+// ever see it as an opaque string.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (cursor pageCursor) String() string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseCursor(encoded string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	createdAtString, idString, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return pageCursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtString)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	id, err := uuid.Parse(idString)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("invalid cursor")
+	}
+
+	return pageCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// firstPageCursor returns a cursor that sorts before (or after, when desc is
+// set) every row, so the first page can use the same keyset query as the rest.
+func firstPageCursor(desc bool) pageCursor {
+	if desc {
+		return pageCursor{
+			CreatedAt: time.Date(9999, time.December, 31, 23, 59, 59, 0, time.UTC),
+			ID:        uuid.Max,
+		}
+	}
+	return pageCursor{
+		CreatedAt: time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		ID:        uuid.Nil,
+	}
+}
+
+// pageRequest holds the validated limit and cursor query parameters.
+type pageRequest struct {
+	Limit  int32
+	Cursor pageCursor
+	Desc   bool
+}
+
+// parsePageRequest reads the limit and cursor query parameters. sortReq is
+// the value of the sort parameter, or "desc" for endpoints that are always
+// newest-first.
+func parsePageRequest(limitReq, cursorReq, sortReq string) (pageRequest, error) {
+	page := pageRequest{Limit: defaultPageLimit}
+
+	switch sortReq {
+	case "", "asc":
+	case "desc":
+		page.Desc = true
+	default:
+		return pageRequest{}, fmt.Errorf("sort must be asc or desc")
+	}
+
+	limit, err := parseLimit(limitReq)
+	if err != nil {
+		return pageRequest{}, err
+	}
+	page.Limit = limit
+
+	if cursorReq == "" {
+		page.Cursor = firstPageCursor(page.Desc)
+		return page, nil
+	}
+
+	cursor, err := parseCursor(cursorReq)
+	if err != nil {
+		return pageRequest{}, err
+	}
+	page.Cursor = cursor
+	return page, nil
+}
+
+// parseLimit reads the limit query parameter, defaulting to
+// defaultPageLimit and capping it at maxPageLimit.
+func parseLimit(limitReq string) (int32, error) {
+	if limitReq == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(limitReq)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return int32(limit), nil
+}
+
+// Ranked results such as search cannot use a keyset, so their cursors wrap
+// a plain row offset instead.
+func offsetCursor(offset int32) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset|" + strconv.Itoa(int(offset))))
+}
+
+func parseOffsetCursor(encoded string) (int32, error) {
+	if encoded == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+
+	offsetString, ok := strings.CutPrefix(string(raw), "offset|")
+	if !ok {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+
+	offset, err := strconv.ParseInt(offsetString, 10, 32)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return int32(offset), nil
+}
+
+// trimPage cuts rows, which were fetched with one extra row to detect
+// whether another page exists, down to the page size. It returns the cursor
+// for the next page, or nil when rows is the last page.
+func trimPage[T any](rows []T, limit int32, cursorOf func(T) pageCursor) ([]T, interface{}) {
+	if len(rows) <= int(limit) {
+		return rows, nil
+	}
+	rows = rows[:limit]
+	return rows, cursorOf(rows[len(rows)-1]).String()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/mail"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	passwordResetTTL  = time.Hour
+	maxPasswordLength = 72
+)
+
+type passwordResetRequest struct {
+	Email    string `json:"email"`
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// validatePassword returns a message describing what is wrong with a new
+// password, or "" if it is acceptable. bcrypt ignores everything past 72
+// bytes, so longer passwords are refused rather than silently truncated.
+func validatePassword(password string) string {
+	if password == "" {
+		return "Password is required"
+	}
+	if len(password) > maxPasswordLength {
+		return "Password must be at most 72 bytes"
+	}
+	return ""
+}
+
+func decodePasswordResetRequest(writer http.ResponseWriter, request *http.Request) (passwordResetRequest, bool) {
+	defer request.Body.Close()
+	resetReq := passwordResetRequest{}
+
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return resetReq, false
+	}
+	if err := json.Unmarshal(body, &resetReq); err != nil {
+		respondWithJsonError(writer, "Invalid request body", 400)
+		return resetReq, false
+	}
+
+	return resetReq, true
+}
+
+// forgotPassword emails a reset token to the address if it belongs to a
+// user. The response is the same either way. Both cases do the same single
+// lookup before responding, and the token and email are made in the
+// background, so the timing doesn't show who has an account either.
+// Requests are throttled per address and client.
+func forgotPassword(writer http.ResponseWriter, request *http.Request) {
+	resetReq, ok := decodePasswordResetRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if _, ok := countAttempt(writer, request, resetSubjects(request, resetReq.Email), "Too many password reset requests, try again later"); !ok {
+		return
+	}
+
+	user, err := ApiCfg.Store.GetUserByEmail(request.Context(), resetReq.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	writer.WriteHeader(202)
+	if err == nil {
+		go sendPasswordReset(context.WithoutCancel(request.Context()), user)
+	}
+}
+
+// sendPasswordReset replaces the user's reset token with a new one and
+// mails it to them. Failures are only logged since the response has
+// already been sent.
+func sendPasswordReset(ctx context.Context, user database.User) {
+	token, err := auth.MakeOneTimeToken()
+	if err != nil {
+		log.Printf("unable to create password reset token for %s: %s", user.ID, err)
+		return
+	}
+
+	// only the newest token works
+	if err := ApiCfg.Store.InvalidatePasswordResetTokens(ctx, user.ID); err != nil {
+		log.Printf("unable to invalidate reset tokens for %s: %s", user.ID, err)
+		return
+	}
+	err = ApiCfg.Store.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().UTC().Add(passwordResetTTL),
+	})
+	if err != nil {
+		log.Printf("unable to create password reset token for %s: %s", user.ID, err)
+		return
+	}
+
+	err = ApiCfg.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Chirpy account.\n\n"+
+			"Your reset token is:\n\n    %s\n\n"+
+			"Send it to /api/password/reset with your new password within the next hour. "+
+			"If you didn't ask for this you can ignore this email.\n", token),
+	})
+	if err != nil {
+		log.Printf("unable to send password reset email to %s: %s", user.ID, err)
+	}
+	logSecurityEvent("password_reset_requested", user.ID)
+}
+
+// resetPassword sets a new password with a token from forgotPassword and
+// signs the user out everywhere.
+func resetPassword(writer http.ResponseWriter, request *http.Request) {
+	resetReq, ok := decodePasswordResetRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if msg := validatePassword(resetReq.Password); msg != "" {
+		respondWithJsonError(writer, msg, 400)
+		return
+	}
+
+	resetToken, err := ApiCfg.Store.UsePasswordResetToken(request.Context(), auth.HashToken(resetReq.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Invalid or expired reset token", 400)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(resetReq.Password)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	err = ApiCfg.Store.UpdateUserPassword(request.Context(), database.UpdateUserPasswordParams{
+		ID:             resetToken.UserID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	if err := ApiCfg.Store.InvalidatePasswordResetTokens(request.Context(), resetToken.UserID); err != nil {
+		log.Printf("unable to invalidate reset tokens for %s: %s", resetToken.UserID, err)
+	}
+	revoked, err := ApiCfg.Store.RevokeUserRefreshTokens(request.Context(), resetToken.UserID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// whoever was guessing the old password is no longer a reason to keep
+	// the owner locked out
+	if user, err := ApiCfg.Store.GetUser(request.Context(), resetToken.UserID); err == nil {
+		clearAccountThrottle(request.Context(), user.Email)
+	}
+	logSecurityEvent("password_reset", resetToken.UserID, "revoked", revoked)
+
+	writer.WriteHeader(204)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+var resetTokenPattern = regexp.MustCompile(`(?m)^    ([0-9a-f]{64})$`)
+
+// requestPasswordReset asks for a reset email and returns the token in it.
+func requestPasswordReset(t *testing.T, mux *http.ServeMux, email string) string {
+	t.Helper()
+	before := len(sentMail(t))
+	if rec := doRequest(t, mux, "POST", "/api/password/forgot", "", passwordResetRequest{Email: email}); rec.Code != 202 {
+		t.Fatalf("forgot password: status %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	messages := waitForMail(t, before+1)
+	latest := messages[len(messages)-1]
+	if latest.To != email {
+		t.Fatalf("reset email went to %q, expected %q", latest.To, email)
+	}
+	match := resetTokenPattern.FindStringSubmatch(latest.Body)
+	if match == nil {
+		t.Fatalf("no reset token in email %q", latest.Body)
+	}
+	return match[1]
+}
+
+func TestPasswordReset(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+
+	stale := requestPasswordReset(t, mux, "walt@breakingbad.com")
+	token := requestPasswordReset(t, mux, "walt@breakingbad.com")
+
+	// only the newest token works
+	if rec := doRequest(t, mux, "POST", "/api/password/reset", "", passwordResetRequest{Token: stale, Password: "heisenberg"}); rec.Code != 400 {
+		t.Errorf("reset with superseded token: expected 400, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/password/reset", "", passwordResetRequest{Token: token, Password: ""}); rec.Code != 400 {
+		t.Errorf("reset with empty password: expected 400, got %d", rec.Code)
+	}
+
+	if rec := doRequest(t, mux, "POST", "/api/password/reset", "", passwordResetRequest{Token: token, Password: "heisenberg"}); rec.Code != 204 {
+		t.Fatalf("reset: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, mux, "POST", "/api/password/reset", "", passwordResetRequest{Token: token, Password: "again"}); rec.Code != 400 {
+		t.Errorf("reusing a reset token: expected 400, got %d", rec.Code)
+	}
+
+	// every existing session is signed out
+	if rec := doRequest(t, mux, "POST", "/api/refresh", user["refresh_token"].(string), nil); rec.Code != 401 {
+		t.Errorf("refresh after reset: expected 401, got %d", rec.Code)
+	}
+
+	if rec := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "hunter2"}); rec.Code != 401 {
+		t.Errorf("login with old password: expected 401, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "heisenberg"}); rec.Code != 200 {
+		t.Errorf("login with new password: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	mux := newTestMux(t)
+
+	if rec := doRequest(t, mux, "POST", "/api/password/forgot", "", passwordResetRequest{Email: "nobody@breakingbad.com"}); rec.Code != 202 {
+		t.Errorf("forgot password for unknown email: expected 202, got %d", rec.Code)
+	}
+	// nothing is started in the background for an unknown address
+	if messages := sentMail(t); len(messages) != 0 {
+		t.Errorf("expected no email for an unknown address, got %v", messages)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/password/reset", "", passwordResetRequest{Token: "nope", Password: "heisenberg"}); rec.Code != 400 {
+		t.Errorf("reset with unknown token: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestForgotPasswordThrottle(t *testing.T) {
+	mux := newTestMux(t)
+	createTestUser(t, mux, "walt@breakingbad.com")
+	before := len(sentMail(t))
+
+	// known and unknown addresses are throttled alike
+	policy := loginThrottlePolicies[throttleKindResetEmail]
+	for _, email := range []string{"walt@breakingbad.com", "nobody@breakingbad.com"} {
+		for i := int32(0); i <= policy.freeAttempts; i++ {
+			if rec := doRequest(t, mux, "POST", "/api/password/forgot", "", passwordResetRequest{Email: email}); rec.Code != 202 {
+				t.Fatalf("%s request %d: expected 202, got %d", email, i+1, rec.Code)
+			}
+		}
+		rec := doRequest(t, mux, "POST", "/api/password/forgot", "", passwordResetRequest{Email: email})
+		if rec.Code != 429 || rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: expected 429 with Retry-After, got %d", email, rec.Code)
+		}
+	}
+
+	// reset throttles aren't login lockouts
+	rec := doAdminRequest(t, mux, "GET", "/admin/lockouts", nil)
+	lockoutResp := struct {
+		Lockouts []map[string]interface{} `json:"lockouts"`
+	}{}
+	decodeResponse(t, rec, &lockoutResp)
+	if len(lockoutResp.Lockouts) != 0 {
+		t.Errorf("expected no login lockouts, got %v", lockoutResp.Lockouts)
+	}
+	if rec := doAdminRequest(t, mux, "DELETE", "/admin/lockouts/reset_email/walt@breakingbad.com", nil); rec.Code != 404 {
+		t.Errorf("clear reset throttle: expected 404, got %d", rec.Code)
+	}
+
+	if sent := len(waitForMail(t, before+int(policy.freeAttempts)+1)) - before; sent != int(policy.freeAttempts)+1 {
+		t.Errorf("expected %d reset emails, got %d", policy.freeAttempts+1, sent)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const polkaSource = "polka"
+
+// polkaEventTypes are the Polka events we act on. Other event types are
+// recorded and ignored.
+var polkaEventTypes = map[string]bool{
+	"user.upgraded":          true,
+	"user.downgraded":        true,
+	"subscription.renewed":   true,
+	"subscription.cancelled": true,
+}
+
+var (
+	errUnhandledPolkaEvent = errors.New("unhandled event type")
+	errPolkaInvalidUser    = errors.New("invalid user_id")
+	errPolkaUserNotFound   = errors.New("user not found")
+)
+
+type polkaEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  struct {
+		UserID string `json:"user_id"`
+	} `json:"data"`
+}
+
+func polkaWebhook(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	// read request body, the signature covers the raw bytes
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// check the signature
+	signedAt, err := ApiCfg.PolkaWebhook.Verify(request.Header, body)
+	if err != nil {
+		log.Printf("rejected polka webhook from %s: %s", clientIP(request), err)
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	// unmarshal body json
+	event := polkaEvent{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		respondWithJsonError(writer, "Invalid event", 400)
+		return
+	}
+
+	// events without an id are deduplicated on their signed timestamp and
+	// body, so a captured request can't be replayed but the same change sent
+	// again later (upgrade, downgrade, upgrade) is still applied
+	eventID := event.ID
+	if eventID == "" {
+		sum := sha256.Sum256([]byte(strconv.FormatInt(signedAt.Unix(), 10) + "." + string(body)))
+		eventID = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	recorded, err := ApiCfg.Store.RecordWebhookEvent(request.Context(), database.RecordWebhookEventParams{
+		Source:    polkaSource,
+		EventID:   eventID,
+		EventType: event.Event,
+		Payload:   string(body),
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// only one delivery of an event gets to apply it; a redelivery of an
+	// event we already handled changes nothing
+	claimed, err := ApiCfg.Store.ClaimWebhookEvent(request.Context(), database.ClaimWebhookEventParams{
+		ID:       recorded.ID,
+		Statuses: []string{"pending", "failed"},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithUnclaimedEvent(writer, request, recorded.ID)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	applyErr := applyPolkaEvent(request.Context(), claimed)
+	if _, err := finishPolkaEvent(request.Context(), claimed, applyErr); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if applyErr != nil && !errors.Is(applyErr, errUnhandledPolkaEvent) {
+		respondWithPolkaError(writer, applyErr)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
+
+// respondWithUnclaimedEvent answers a delivery of an event that another
+// request has claimed. Once that one is done the event is acknowledged;
+// while it is still running Polka is asked to try again later, in case it
+// fails.
+func respondWithUnclaimedEvent(writer http.ResponseWriter, request *http.Request, id uuid.UUID) {
+	event, err := ApiCfg.Store.GetWebhookEvent(request.Context(), id)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if event.Status == "processed" || event.Status == "ignored" {
+		writer.WriteHeader(204)
+		return
+	}
+	respondWithJsonError(writer, "Event is already being processed", 409)
+}
+
+// finishPolkaEvent stores the outcome of applying an event.
+func finishPolkaEvent(ctx context.Context, recorded database.WebhookEvent, applyErr error) (database.WebhookEvent, error) {
+	params := database.FinishWebhookEventParams{ID: recorded.ID, Status: "processed"}
+	switch {
+	case errors.Is(applyErr, errUnhandledPolkaEvent):
+		params.Status = "ignored"
+	case applyErr != nil:
+		params.Status = "failed"
+		params.LastError = sql.NullString{String: applyErr.Error(), Valid: true}
+	}
+	return ApiCfg.Store.FinishWebhookEvent(ctx, params)
+}
+
+// applyPolkaEvent updates the user's subscription for the event. Renewals
+// extend the current period, so the caller must have claimed the event to
+// make sure it is applied only once.
+func applyPolkaEvent(ctx context.Context, recorded database.WebhookEvent) error {
+	event := polkaEvent{}
+	if err := json.Unmarshal([]byte(recorded.Payload), &event); err != nil {
+		return err
+	}
+
+	if !polkaEventTypes[recorded.EventType] {
+		return errUnhandledPolkaEvent
+	}
+
+	userID, err := uuid.Parse(event.Data.UserID)
+	if err != nil {
+		return errPolkaInvalidUser
+	}
+	if _, err := ApiCfg.Store.GetUser(ctx, userID); errors.Is(err, sql.ErrNoRows) {
+		return errPolkaUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	switch recorded.EventType {
+	case "user.upgraded":
+		return startSubscription(ctx, userID, false)
+	case "subscription.renewed":
+		return startSubscription(ctx, userID, true)
+	case "subscription.cancelled":
+		// the user keeps Chirpy Red until the period they paid for ends
+		_, err := ApiCfg.Store.CancelSubscription(ctx, userID)
+		return err
+	default:
+		return endSubscription(ctx, userID)
+	}
+}
+
+func respondWithPolkaError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errPolkaInvalidUser):
+		respondWithJsonError(writer, "Invalid user_id", 400)
+	case errors.Is(err, errPolkaUserNotFound):
+		respondWithJsonError(writer, "User not found", 404)
+	default:
+		respondWithJsonError(writer, "Something went wrong", 500)
+	}
+}
+
+func makeWebhookEventMap(event database.WebhookEvent) map[string]interface{} {
+	eventMap := map[string]interface{}{
+		"id":           event.ID,
+		"source":       event.Source,
+		"event_id":     event.EventID,
+		"event_type":   event.EventType,
+		"payload":      json.RawMessage(event.Payload),
+		"status":       event.Status,
+		"attempts":     event.Attempts,
+		"last_error":   nil,
+		"received_at":  event.ReceivedAt.String(),
+		"processed_at": nil,
+	}
+	if event.LastError.Valid {
+		eventMap["last_error"] = event.LastError.String
+	}
+	if event.ProcessedAt.Valid {
+		eventMap["processed_at"] = event.ProcessedAt.Time.String()
+	}
+	return eventMap
+}
+
+func getWebhookEvents(writer http.ResponseWriter, request *http.Request) {
+	if !isAdmin(request) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	query := request.URL.Query()
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return
+	}
+
+	status := sql.NullString{}
+	if query.Get("status") != "" {
+		status = sql.NullString{String: query.Get("status"), Valid: true}
+	}
+
+	events, err := ApiCfg.Store.ListWebhookEvents(request.Context(), database.ListWebhookEventsParams{
+		Status:   status,
+		RowLimit: limit,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	eventsSlice := []map[string]interface{}{}
+	for _, event := range events {
+		eventsSlice = append(eventsSlice, makeWebhookEventMap(event))
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{"events": eventsSlice})
+}
+
+// replayWebhookEvent processes a failed event again, for example after the
+// user it refers to has been restored.
+func replayWebhookEvent(writer http.ResponseWriter, request *http.Request) {
+	if !isAdmin(request) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	eventID, err := uuid.Parse(request.PathValue("eventID"))
+	if err != nil {
+		respondWithJsonError(writer, "Event not found", 404)
+		return
+	}
+
+	event, err := ApiCfg.Store.GetWebhookEvent(request.Context(), eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Event not found", 404)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if event.Status != "failed" {
+		respondWithJsonError(writer, "Only failed events can be replayed", 409)
+		return
+	}
+	claimed, err := ApiCfg.Store.ClaimWebhookEvent(request.Context(), database.ClaimWebhookEventParams{
+		ID:       event.ID,
+		Statuses: []string{"failed"},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Only failed events can be replayed", 409)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// the outcome, including a repeated failure, is in the returned event
+	replayed, err := finishPolkaEvent(request.Context(), claimed, applyPolkaEvent(request.Context(), claimed))
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	respondWithJson(writer, 200, makeWebhookEventMap(replayed))
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/store"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sendPolkaWebhook posts body to the Polka webhook endpoint with the given
+// signature header, if any.
+func sendPolkaWebhook(t *testing.T, mux *http.ServeMux, signature string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest("POST", "/api/polka/webhooks", bytes.NewReader(body))
+	if signature != "" {
+		request.Header.Set(auth.WebhookSignatureHeader, signature)
+	}
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestPolkaWebhookSignature(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	body := []byte(`{"event":"user.upgraded","data":{"user_id":"` + user["id"].(string) + `"}}`)
+
+	// the old static api key is no longer accepted
+	request := httptest.NewRequest("POST", "/api/polka/webhooks", bytes.NewReader(body))
+	request.Header.Set("Authorization", "ApiKey test-polka-secret")
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	if recorder.Code != 401 {
+		t.Errorf("api key: expected 401, got %d", recorder.Code)
+	}
+
+	rejected := map[string]string{
+		"wrong secret": auth.SignWebhook("leaked", time.Now(), body),
+		"stale":        auth.SignWebhook("test-polka-secret", time.Now().Add(-time.Hour), body),
+		"other body":   auth.SignWebhook("test-polka-secret", time.Now(), []byte(`{}`)),
+	}
+	for name, signature := range rejected {
+		if rec := sendPolkaWebhook(t, mux, signature, body); rec.Code != 401 {
+			t.Errorf("%s: expected 401, got %d", name, rec.Code)
+		}
+	}
+
+	if rec := sendPolkaWebhook(t, mux, auth.SignWebhook("test-polka-secret", time.Now(), body), body); rec.Code != 204 {
+		t.Fatalf("signed webhook: expected 204, got %d, body %s", rec.Code, rec.Body.String())
+	}
+	login := map[string]interface{}{}
+	decodeResponse(t, doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "hunter2"}), &login)
+	if login["is_chirpy_red"] != true {
+		t.Errorf("user was not upgraded: %v", login)
+	}
+}
+
+func TestPolkaEventLifecycle(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	userID := user["id"].(string)
+
+	send := func(id, event, userID string) int {
+		body := []byte(`{"id":"` + id + `","event":"` + event + `","data":{"user_id":"` + userID + `"}}`)
+		return sendPolkaWebhook(t, mux, auth.SignWebhook("test-polka-secret", time.Now(), body), body).Code
+	}
+	isChirpyRed := func() bool {
+		login := map[string]interface{}{}
+		decodeResponse(t, doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "hunter2"}), &login)
+		return login["is_chirpy_red"] == true
+	}
+
+	steps := []struct {
+		id, event string
+		status    int
+		red       bool
+	}{
+		{"evt_1", "user.upgraded", 204, true},
+		// a cancelled subscription lasts until the end of its period
+		{"evt_2", "subscription.cancelled", 204, true},
+		{"evt_3", "subscription.renewed", 204, true},
+		{"evt_4", "user.downgraded", 204, false},
+		// a redelivered upgrade must not undo the downgrade that followed it
+		{"evt_1", "user.upgraded", 204, false},
+		{"evt_5", "user.renamed", 204, false},
+	}
+	for _, step := range steps {
+		if status := send(step.id, step.event, userID); status != step.status {
+			t.Fatalf("%s %s: expected %d, got %d", step.id, step.event, step.status, status)
+		}
+		if red := isChirpyRed(); red != step.red {
+			t.Errorf("after %s %s: expected is_chirpy_red %v, got %v", step.id, step.event, step.red, red)
+		}
+	}
+
+	// an event for an unknown user fails and can be replayed
+	if status := send("evt_6", "user.upgraded", "3311741c-680c-4546-99f3-fc9efac2036c"); status != 404 {
+		t.Errorf("unknown user: expected 404, got %d", status)
+	}
+
+	if rec := doRequest(t, mux, "GET", "/admin/webhooks/events", "", nil); rec.Code != 401 {
+		t.Errorf("list events without admin key: expected 401, got %d", rec.Code)
+	}
+	rec := doAdminRequest(t, mux, "GET", "/admin/webhooks/events", nil)
+	listed := struct {
+		Events []map[string]interface{} `json:"events"`
+	}{}
+	decodeResponse(t, rec, &listed)
+	if len(listed.Events) != 6 {
+		t.Fatalf("expected 6 recorded events, got %d", len(listed.Events))
+	}
+
+	rec = doAdminRequest(t, mux, "GET", "/admin/webhooks/events?status=failed", nil)
+	decodeResponse(t, rec, &listed)
+	if len(listed.Events) != 1 || listed.Events[0]["event_id"] != "evt_6" || listed.Events[0]["last_error"] != "user not found" {
+		t.Fatalf("unexpected failed events: %v", listed.Events)
+	}
+	failedID := listed.Events[0]["id"].(string)
+
+	rec = doAdminRequest(t, mux, "POST", "/admin/webhooks/events/"+failedID+"/replay", nil)
+	replayed := map[string]interface{}{}
+	decodeResponse(t, rec, &replayed)
+	if rec.Code != 200 || replayed["status"] != "failed" || replayed["attempts"] != float64(2) {
+		t.Errorf("unexpected replay result: %d %v", rec.Code, replayed)
+	}
+
+	rec = doAdminRequest(t, mux, "GET", "/admin/webhooks/events?status=processed", nil)
+	decodeResponse(t, rec, &listed)
+	processedID := listed.Events[0]["id"].(string)
+	if rec := doAdminRequest(t, mux, "POST", "/admin/webhooks/events/"+processedID+"/replay", nil); rec.Code != 409 {
+		t.Errorf("replay of a processed event: expected 409, got %d", rec.Code)
+	}
+}
+
+func TestPolkaEventsWithoutID(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	userID := user["id"].(string)
+
+	body := func(event string) []byte {
+		return []byte(`{"event":"` + event + `","data":{"user_id":"` + userID + `"}}`)
+	}
+	isChirpyRed := func() bool {
+		login := map[string]interface{}{}
+		decodeResponse(t, doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "hunter2"}), &login)
+		return login["is_chirpy_red"] == true
+	}
+
+	// the same upgrade sent again later is a new event, not a redelivery
+	start := time.Now()
+	steps := []struct {
+		event string
+		red   bool
+	}{
+		{"user.upgraded", true},
+		{"user.downgraded", false},
+		{"user.upgraded", true},
+	}
+	for i, step := range steps {
+		signature := auth.SignWebhook("test-polka-secret", start.Add(time.Duration(i)*time.Second), body(step.event))
+		if rec := sendPolkaWebhook(t, mux, signature, body(step.event)); rec.Code != 204 {
+			t.Fatalf("step %d %s: expected 204, got %d", i+1, step.event, rec.Code)
+		}
+		if red := isChirpyRed(); red != step.red {
+			t.Errorf("after step %d %s: expected is_chirpy_red %v, got %v", i+1, step.event, step.red, red)
+		}
+	}
+
+	// a replay of a captured request is still a duplicate
+	downgrade := auth.SignWebhook("test-polka-secret", start.Add(time.Second), body("user.downgraded"))
+	if rec := sendPolkaWebhook(t, mux, downgrade, body("user.downgraded")); rec.Code != 204 {
+		t.Fatalf("replay: expected 204, got %d", rec.Code)
+	}
+	if !isChirpyRed() {
+		t.Errorf("a replayed downgrade was applied again")
+	}
+
+	listed := struct {
+		Events []map[string]interface{} `json:"events"`
+	}{}
+	decodeResponse(t, doAdminRequest(t, mux, "GET", "/admin/webhooks/events", nil), &listed)
+	if len(listed.Events) != 3 {
+		t.Errorf("expected 3 recorded events, got %d", len(listed.Events))
+	}
+}
+
+// slowSubscriptionStore holds up subscription reads, so deliveries racing
+// each other overlap while an event is being applied.
+type slowSubscriptionStore struct {
+	store.Store
+}
+
+func (s slowSubscriptionStore) GetSubscription(ctx context.Context, userID uuid.UUID) (database.Subscription, error) {
+	time.Sleep(20 * time.Millisecond)
+	return s.Store.GetSubscription(ctx, userID)
+}
+
+func TestPolkaConcurrentDuplicates(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	userID := user["id"].(string)
+
+	send := func(id, event string) int {
+		body := []byte(`{"id":"` + id + `","event":"` + event + `","data":{"user_id":"` + userID + `"}}`)
+		return sendPolkaWebhook(t, mux, auth.SignWebhook("test-polka-secret", time.Now(), body), body).Code
+	}
+	if status := send("evt_1", "user.upgraded"); status != 204 {
+		t.Fatalf("upgrade: expected 204, got %d", status)
+	}
+
+	// deliveries of the same renewal racing each other extend the period once
+	ApiCfg.Store = slowSubscriptionStore{ApiCfg.Store}
+	statuses := make(chan int, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(statuses); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- send("evt_2", "subscription.renewed")
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	for status := range statuses {
+		if status != 204 && status != 409 {
+			t.Errorf("duplicate renewal: expected 204 or 409, got %d", status)
+		}
+	}
+
+	subscription, err := ApiCfg.Store.GetSubscription(context.Background(), uuid.MustParse(userID))
+	if err != nil {
+		t.Fatalf("get subscription: %v", err)
+	}
+	if limit := time.Now().Add(2*subscriptionPeriod + time.Minute); subscription.CurrentPeriodEnd.After(limit) {
+		t.Errorf("renewal was applied more than once: period ends %s", subscription.CurrentPeriodEnd)
+	}
+
+	listed := struct {
+		Events []map[string]interface{} `json:"events"`
+	}{}
+	decodeResponse(t, doAdminRequest(t, mux, "GET", "/admin/webhooks/events?status=processed", nil), &listed)
+	for _, event := range listed.Events {
+		if event["attempts"] != float64(1) {
+			t.Errorf("event %v was processed %v times", event["event_id"], event["attempts"])
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"chirpy/internal/avatar"
+	"chirpy/internal/database"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxBioLength      = 160
+	maxLocationLength = 30
+	maxWebsiteLength  = 100
+	maxAvatarBytes    = 5 << 20
+)
+
+// validateProfile checks the profile fields of a PATCH /api/users request
+// and copies them into params.
+func validateProfile(writer http.ResponseWriter, patchReq patchUserRequest, params *database.PatchUserParams) bool {
+	if patchReq.Bio != nil {
+		bio := strings.TrimSpace(*patchReq.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			respondWithJsonError(writer, "Bio is too long", 400)
+			return false
+		}
+		params.Bio = sql.NullString{String: bio, Valid: true}
+	}
+
+	if patchReq.Location != nil {
+		location := strings.TrimSpace(*patchReq.Location)
+		if utf8.RuneCountInString(location) > maxLocationLength {
+			respondWithJsonError(writer, "Location is too long", 400)
+			return false
+		}
+		params.Location = sql.NullString{String: location, Valid: true}
+	}
+
+	// an empty website clears it
+	if patchReq.Website != nil {
+		website := strings.TrimSpace(*patchReq.Website)
+		if website != "" {
+			parsed, err := url.Parse(website)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(website) > maxWebsiteLength {
+				respondWithJsonError(writer, "Website must be an http or https URL of at most 100 characters", 400)
+				return false
+			}
+		}
+		params.Website = sql.NullString{String: website, Valid: true}
+	}
+
+	return true
+}
+
+// getUserProfile is the public view of a user: no email, plus counts.
+func getUserProfile(writer http.ResponseWriter, request *http.Request) {
+	userID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		respondWithJsonError(writer, "User not found", 404)
+		return
+	}
+
+	user, err := ApiCfg.Store.GetUser(request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "User not found", 404)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	stats, err := ApiCfg.Store.GetUserStats(request.Context(), userID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	profile := makePublicUserMap(user)
+	profile["follower_count"] = stats.FollowerCount
+	profile["following_count"] = stats.FollowingCount
+	profile["chirp_count"] = stats.ChirpCount
+
+	respondWithJson(writer, 200, profile)
+}
+
+func uploadAvatar(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	request.Body = http.MaxBytesReader(writer, request.Body, maxAvatarBytes)
+	file, _, err := request.FormFile("avatar")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithJsonError(writer, "Avatar must be at most 5 MB", 413)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Expected a multipart form with an avatar file", 400)
+		return
+	}
+	defer file.Close()
+
+	processed, err := avatar.Process(file)
+	if errors.Is(err, avatar.ErrUnsupportedFormat) || errors.Is(err, avatar.ErrTooLarge) {
+		respondWithJsonError(writer, "Avatar must be a PNG, JPEG or GIF of at most 4096x4096 pixels", 400)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// a new key for every upload, so clients never see a cached old avatar
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	key := "avatars/" + userID.String() + "-" + hex.EncodeToString(suffix) + ".png"
+	if err := ApiCfg.Storage.Put(request.Context(), key, bytes.NewReader(processed)); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	previous, err := ApiCfg.Store.GetUser(request.Context(), userID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	user, err := ApiCfg.Store.SetUserAvatar(request.Context(), database.SetUserAvatarParams{
+		ID:        userID,
+		AvatarKey: sql.NullString{String: key, Valid: true},
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	removeAvatar(request, previous.AvatarKey)
+
+	publishUserUpdated(request.Context(), user)
+	respondWithJson(writer, 200, makeUserMap(request.Context(), user))
+}
+
+func deleteAvatar(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	previous, err := ApiCfg.Store.GetUser(request.Context(), userID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	user, err := ApiCfg.Store.SetUserAvatar(request.Context(), database.SetUserAvatarParams{ID: userID})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	removeAvatar(request, previous.AvatarKey)
+	publishUserUpdated(request.Context(), user)
+
+	writer.WriteHeader(204)
+}
+
+// removeAvatar deletes a replaced avatar file. A failure only leaves an
+// orphaned file behind, so it is logged rather than reported.
+func removeAvatar(request *http.Request, key sql.NullString) {
+	if !key.Valid {
+		return
+	}
+	if err := ApiCfg.Storage.Delete(request.Context(), key.String); err != nil {
+		log.Printf("unable to delete avatar %s: %s", key.String, err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUserProfile(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	jesse := createTestUser(t, mux, "jesse@breakingbad.com")
+	waltID := walt["id"].(string)
+
+	rec := doRequest(t, mux, "PATCH", "/api/users", walt["token"].(string), map[string]string{
+		"bio":      "  Chemistry teacher  ",
+		"location": "Albuquerque",
+		"website":  "https://example.com/heisenberg",
+	})
+	if rec.Code != 200 {
+		t.Fatalf("patch profile: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	doRequest(t, mux, "POST", "/api/users/"+waltID+"/follow", jesse["token"].(string), nil)
+	doRequest(t, mux, "POST", "/api/chirps", walt["token"].(string), map[string]string{"body": "Say my name"})
+
+	rec = doRequest(t, mux, "GET", "/api/users/"+waltID, "", nil)
+	if rec.Code != 200 {
+		t.Fatalf("get profile: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	profile := map[string]interface{}{}
+	decodeResponse(t, rec, &profile)
+	for _, private := range []string{"email", "email_verified", "subscription", "entitlements"} {
+		if _, ok := profile[private]; ok {
+			t.Errorf("public profile exposes %s: %v", private, profile)
+		}
+	}
+	if profile["bio"] != "Chemistry teacher" || profile["location"] != "Albuquerque" || profile["website"] != "https://example.com/heisenberg" {
+		t.Errorf("unexpected profile fields: %v", profile)
+	}
+	if profile["follower_count"] != float64(1) || profile["following_count"] != float64(0) || profile["chirp_count"] != float64(1) {
+		t.Errorf("unexpected profile counts: %v", profile)
+	}
+
+	if rec := doRequest(t, mux, "GET", "/api/users/00000000-0000-0000-0000-000000000000", "", nil); rec.Code != 404 {
+		t.Errorf("unknown user: expected 404, got %d", rec.Code)
+	}
+
+	cases := []struct {
+		name    string
+		payload map[string]string
+	}{
+		{"long bio", map[string]string{"bio": strings.Repeat("a", 161)}},
+		{"long location", map[string]string{"location": strings.Repeat("a", 31)}},
+		{"website scheme", map[string]string{"website": "javascript:alert(1)"}},
+		{"website without host", map[string]string{"website": "https://"}},
+	}
+	for _, tc := range cases {
+		if rec := doRequest(t, mux, "PATCH", "/api/users", walt["token"].(string), tc.payload); rec.Code != 400 {
+			t.Errorf("%s: expected 400, got %d", tc.name, rec.Code)
+		}
+	}
+}
+
+func uploadTestAvatar(t *testing.T, mux *http.ServeMux, token string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, err := form.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatalf("unable to create form file: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/api/users/avatar", body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAvatarUpload(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	token := user["token"].(string)
+
+	img := image.NewRGBA(image.Rect(0, 0, 600, 400))
+	for x := 0; x < 600; x++ {
+		for y := 0; y < 400; y++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	encoded := &bytes.Buffer{}
+	if err := png.Encode(encoded, img); err != nil {
+		t.Fatalf("unable to encode test image: %v", err)
+	}
+
+	if rec := uploadTestAvatar(t, mux, token, []byte("not an image")); rec.Code != 400 {
+		t.Errorf("upload of a non-image: expected 400, got %d", rec.Code)
+	}
+
+	rec := uploadTestAvatar(t, mux, token, encoded.Bytes())
+	if rec.Code != 200 {
+		t.Fatalf("upload avatar: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	updated := map[string]interface{}{}
+	decodeResponse(t, rec, &updated)
+	avatarURL, _ := updated["avatar_url"].(string)
+	if !strings.HasPrefix(avatarURL, "http://localhost:8080/media/avatars/") {
+		t.Fatalf("unexpected avatar url %q", avatarURL)
+	}
+
+	// the stored avatar is served as a square PNG
+	rec = doRequest(t, mux, "GET", strings.TrimPrefix(avatarURL, "http://localhost:8080"), "", nil)
+	if rec.Code != 200 {
+		t.Fatalf("get avatar: status %d", rec.Code)
+	}
+	served, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatalf("served avatar is not a PNG: %v", err)
+	}
+	if bounds := served.Bounds(); bounds.Dx() != 256 || bounds.Dy() != 256 {
+		t.Errorf("expected a 256x256 avatar, got %v", bounds)
+	}
+
+	if rec := doRequest(t, mux, "DELETE", "/api/users/avatar", token, nil); rec.Code != 204 {
+		t.Fatalf("delete avatar: expected 204, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "GET", strings.TrimPrefix(avatarURL, "http://localhost:8080"), "", nil); rec.Code != 404 {
+		t.Errorf("deleted avatar is still served: %d", rec.Code)
+	}
+}
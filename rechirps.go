@@ -0,0 +1,105 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/store"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func createRechirp(writer http.ResponseWriter, request *http.Request) {
+	userID, original, ok := rechirpParams(writer, request)
+	if !ok {
+		return
+	}
+	if !requireVerifiedEmail(writer, request, userID) {
+		return
+	}
+
+	if original.DeletedAt.Valid {
+		respondWithJsonError(writer, "Cannot rechirp a deleted chirp", 400)
+		return
+	}
+
+	_, err := ApiCfg.Store.GetRechirp(request.Context(), database.GetRechirpParams{
+		UserID:          userID,
+		OriginalChirpID: original.ID,
+	})
+	if err == nil {
+		respondWithJsonError(writer, "Chirp already rechirped", 409)
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// a concurrent rechirp can still win between the check and the insert
+	chirpData, err := ApiCfg.Store.CreateChirp(request.Context(), database.CreateChirpParams{
+		UserID:          userID,
+		Kind:            chirpKindRechirp,
+		OriginalChirpID: uuid.NullUUID{UUID: original.ID, Valid: true},
+	})
+	if store.IsUniqueViolation(err) {
+		respondWithJsonError(writer, "Chirp already rechirped", 409)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	publishWebhookEvent(request.Context(), webhookEventChirpCreated, chirpData.UserID, makeChirpMap(chirpData))
+
+	respondWithChirp(writer, request, 201, chirpData)
+}
+
+func deleteRechirp(writer http.ResponseWriter, request *http.Request) {
+	userID, original, ok := rechirpParams(writer, request)
+	if !ok {
+		return
+	}
+
+	rechirp, err := ApiCfg.Store.GetRechirp(request.Context(), database.GetRechirpParams{
+		UserID:          userID,
+		OriginalChirpID: original.ID,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Rechirp not found", 404)
+		return
+	}
+
+	if err := ApiCfg.Store.DeleteChirp(request.Context(), rechirp.ID); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	publishChirpDeleted(request.Context(), rechirp)
+
+	writer.WriteHeader(204)
+}
+
+// rechirpParams authenticates the user and resolves the chirp being
+// rechirped from the URL. Rechirping a rechirp targets the original chirp.
+func rechirpParams(writer http.ResponseWriter, request *http.Request) (uuid.UUID, database.Chirp, bool) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return uuid.UUID{}, database.Chirp{}, false
+	}
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		respondWithJsonError(writer, "Chirp not found", 404)
+		return uuid.UUID{}, database.Chirp{}, false
+	}
+
+	original, err := getOriginalChirp(request.Context(), chirpID)
+	if err != nil {
+		respondWithJsonError(writer, "Chirp not found", 404)
+		return uuid.UUID{}, database.Chirp{}, false
+	}
+
+	return userID, original, true
+}
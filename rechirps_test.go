@@ -0,0 +1,107 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/store"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRechirpsAndQuotes(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	jesse := createTestUser(t, mux, "jesse@breakingbad.com")
+	waltToken := walt["token"].(string)
+	jesseToken := jesse["token"].(string)
+
+	original := postChirp(t, mux, waltToken, map[string]interface{}{"body": "I am the one who knocks"})
+	rechirpPath := "/api/chirps/" + original["id"].(string) + "/rechirp"
+
+	if rec := doRequest(t, mux, "POST", rechirpPath, jesseToken, nil); rec.Code != 201 {
+		t.Fatalf("rechirp: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, mux, "POST", rechirpPath, jesseToken, nil); rec.Code != 409 {
+		t.Errorf("second rechirp: expected 409, got %d", rec.Code)
+	}
+
+	quote := postChirp(t, mux, jesseToken, map[string]interface{}{"body": "yeah science", "quote_of": original["id"]})
+	if quote["kind"] != "quote" || quote["original_chirp_id"] != original["id"] {
+		t.Errorf("unexpected quote: %v", quote)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/chirps", jesseToken, map[string]interface{}{"quote_of": original["id"]}); rec.Code != 400 {
+		t.Errorf("quote without body: expected 400, got %d", rec.Code)
+	}
+
+	if rec := doRequest(t, mux, "DELETE", "/api/chirps/"+original["id"].(string), waltToken, nil); rec.Code != 204 {
+		t.Fatalf("delete original: status %d", rec.Code)
+	}
+
+	rec := doRequest(t, mux, "GET", "/api/chirps?author_id="+jesse["id"].(string), "", nil)
+	chirps := []map[string]interface{}{}
+	decodeResponse(t, rec, &chirps)
+	if len(chirps) != 2 {
+		t.Fatalf("expected the rechirp and the quote, got %d chirps", len(chirps))
+	}
+	for _, chirp := range chirps {
+		embedded, ok := chirp["original_chirp"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("%v chirp has no embedded original", chirp["kind"])
+		}
+		if embedded["deleted"] != true || embedded["body"] != "" {
+			t.Errorf("%v chirp should embed a tombstone, got %v", chirp["kind"], embedded)
+		}
+	}
+
+	if rec := doRequest(t, mux, "DELETE", rechirpPath, jesseToken, nil); rec.Code != 204 {
+		t.Errorf("undo rechirp: expected 204, got %d", rec.Code)
+	}
+}
+
+// slowRechirpStore holds up the duplicate check, so rechirps racing each
+// other all get past it before any of them is inserted.
+type slowRechirpStore struct {
+	store.Store
+}
+
+func (s slowRechirpStore) GetRechirp(ctx context.Context, arg database.GetRechirpParams) (database.Chirp, error) {
+	chirp, err := s.Store.GetRechirp(ctx, arg)
+	time.Sleep(20 * time.Millisecond)
+	return chirp, err
+}
+
+func TestConcurrentRechirps(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	jesse := createTestUser(t, mux, "jesse@breakingbad.com")
+	original := postChirp(t, mux, walt["token"].(string), map[string]interface{}{"body": "I am the one who knocks"})
+	rechirpPath := "/api/chirps/" + original["id"].(string) + "/rechirp"
+
+	ApiCfg.Store = slowRechirpStore{ApiCfg.Store}
+	statuses := make(chan int, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(statuses); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- doRequest(t, mux, "POST", rechirpPath, jesse["token"].(string), nil).Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	created := 0
+	for status := range statuses {
+		switch status {
+		case 201:
+			created++
+		case 409:
+		default:
+			t.Errorf("concurrent rechirp: expected 201 or 409, got %d", status)
+		}
+	}
+	if created != 1 {
+		t.Errorf("expected exactly one rechirp, got %d", created)
+	}
+}
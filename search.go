@@ -0,0 +1,167 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxSearchQueryLength = 200
+
+func searchChirps(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	search := strings.TrimSpace(query.Get("q"))
+	if search == "" || len(search) > maxSearchQueryLength {
+		respondWithJsonError(writer, "q must be between 1 and 200 characters", 400)
+		return
+	}
+
+	limit, offset, ok := searchPageParams(writer, request)
+	if !ok {
+		return
+	}
+
+	params := database.SearchChirpsParams{
+		Search:    search,
+		RowLimit:  limit + 1,
+		RowOffset: offset,
+	}
+
+	if authorID := query.Get("author_id"); authorID != "" {
+		authorUUID, err := uuid.Parse(authorID)
+		if err != nil {
+			respondWithJsonError(writer, "Invalid author_id", 400)
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: authorUUID, Valid: true}
+	}
+
+	var err error
+	if params.Since, err = parseTimeParam(query.Get("since")); err != nil {
+		respondWithJsonError(writer, "since must be an RFC 3339 timestamp", 400)
+		return
+	}
+	if params.Until, err = parseTimeParam(query.Get("until")); err != nil {
+		respondWithJsonError(writer, "until must be an RFC 3339 timestamp", 400)
+		return
+	}
+
+	results, err := ApiCfg.Store.SearchChirps(request.Context(), params)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	var next interface{}
+	if len(results) > int(limit) {
+		results = results[:limit]
+		next = offsetCursor(offset + limit)
+	}
+
+	chirps := []database.Chirp{}
+	for _, result := range results {
+		chirps = append(chirps, result.Chirp)
+	}
+	chirpsSlice, err := makeChirpsSlice(request, chirps)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	for i, result := range results {
+		chirpsSlice[i]["rank"] = result.Rank
+		chirpsSlice[i]["highlight"] = result.Highlight
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{
+		"chirps":      chirpsSlice,
+		"next_cursor": next,
+	})
+}
+
+func searchUsers(writer http.ResponseWriter, request *http.Request) {
+	if _, err := authenticatedUserID(request); err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	search := strings.ToLower(strings.TrimSpace(request.URL.Query().Get("q")))
+	if search == "" || len(search) > maxSearchQueryLength {
+		respondWithJsonError(writer, "q must be between 1 and 200 characters", 400)
+		return
+	}
+
+	limit, offset, ok := searchPageParams(writer, request)
+	if !ok {
+		return
+	}
+
+	users, err := ApiCfg.Store.SearchUsers(request.Context(), database.SearchUsersParams{
+		Pattern:   escapeLike(search) + "%",
+		RowLimit:  limit + 1,
+		RowOffset: offset,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	var next interface{}
+	if len(users) > int(limit) {
+		users = users[:limit]
+		next = offsetCursor(offset + limit)
+	}
+
+	usersSlice := []map[string]interface{}{}
+	for _, user := range users {
+		usersSlice = append(usersSlice, map[string]interface{}{
+			"id":           user.ID.String(),
+			"handle":       user.Handle,
+			"display_name": user.DisplayName,
+		})
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{
+		"users":       usersSlice,
+		"next_cursor": next,
+	})
+}
+
+// searchPageParams reads limit and an offset cursor for ranked search
+// results, writing the error response itself when either is invalid.
+func searchPageParams(writer http.ResponseWriter, request *http.Request) (int32, int32, bool) {
+	query := request.URL.Query()
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return 0, 0, false
+	}
+
+	offset, err := parseOffsetCursor(query.Get("cursor"))
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return 0, 0, false
+	}
+
+	return limit, offset, true
+}
+
+func parseTimeParam(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: parsed.UTC(), Valid: true}, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied prefix.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSearchChirps(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	jesse := createTestUser(t, mux, "jesse@breakingbad.com")
+
+	postChirp(t, mux, walt["token"].(string), map[string]interface{}{"body": "Science is <great>, yo"})
+	postChirp(t, mux, jesse["token"].(string), map[string]interface{}{"body": "Yeah science! Science, yo"})
+	postChirp(t, mux, jesse["token"].(string), map[string]interface{}{"body": "Nothing to see here"})
+
+	rec := doRequest(t, mux, "GET", "/api/search?q=science&limit=1", "", nil)
+	if rec.Code != 200 {
+		t.Fatalf("search: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	page := chirpPage{}
+	decodeResponse(t, rec, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0]["user_id"] != jesse["id"] {
+		t.Fatalf("expected jesse's chirp to rank first, got %v", page.Chirps)
+	}
+	if page.Chirps[0]["highlight"] != "Yeah <mark>science</mark>! <mark>Science</mark>, yo" {
+		t.Errorf("unexpected highlight %q", page.Chirps[0]["highlight"])
+	}
+	if page.NextCursor == nil {
+		t.Fatalf("expected a next cursor")
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/search?q=science&limit=1&cursor="+*page.NextCursor, "", nil)
+	decodeResponse(t, rec, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0]["highlight"] != "<mark>Science</mark> is &lt;great&gt;, yo" {
+		t.Errorf("unexpected second page %v", page.Chirps)
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/search?q=science&author_id="+walt["id"].(string), "", nil)
+	decodeResponse(t, rec, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0]["user_id"] != walt["id"] {
+		t.Errorf("author filter returned %v", page.Chirps)
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/search?q=science&since="+url.QueryEscape("2999-01-01T00:00:00Z"), "", nil)
+	decodeResponse(t, rec, &page)
+	if len(page.Chirps) != 0 {
+		t.Errorf("since filter returned %v", page.Chirps)
+	}
+
+	if rec := doRequest(t, mux, "GET", "/api/search?q=", "", nil); rec.Code != 400 {
+		t.Errorf("empty query: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	createTestUser(t, mux, "jesse@breakingbad.com")
+
+	if rec := doRequest(t, mux, "GET", "/api/search/users?q=walt", "", nil); rec.Code != 401 {
+		t.Errorf("anonymous user search: expected 401, got %d", rec.Code)
+	}
+
+	handle, displayName := "heisenberg", "Walter White"
+	if rec := doRequest(t, mux, "PATCH", "/api/users", walt["token"].(string), patchUserRequest{Handle: &handle, DisplayName: &displayName}); rec.Code != 200 {
+		t.Fatalf("patch user: status %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	result := struct {
+		Users []map[string]interface{} `json:"users"`
+	}{}
+	for _, q := range []string{"HEIS", "walter"} {
+		rec := doRequest(t, mux, "GET", "/api/search/users?q="+q, walt["token"].(string), nil)
+		decodeResponse(t, rec, &result)
+		if len(result.Users) != 1 || result.Users[0]["id"] != walt["id"] {
+			t.Fatalf("q=%s: unexpected users %v", q, result.Users)
+		}
+		if _, ok := result.Users[0]["email"]; ok {
+			t.Errorf("user search should not expose emails")
+		}
+	}
+
+	// emails aren't searched, so they can't be probed letter by letter
+	rec := doRequest(t, mux, "GET", "/api/search/users?q=jesse@", walt["token"].(string), nil)
+	decodeResponse(t, rec, &result)
+	if len(result.Users) != 0 {
+		t.Errorf("search matched an email address: %v", result.Users)
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/search/users?q=%25", walt["token"].(string), nil)
+	decodeResponse(t, rec, &result)
+	if len(result.Users) != 0 {
+		t.Errorf("LIKE wildcards should be escaped, got %v", result.Users)
+	}
+}
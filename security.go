@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// logSecurityEvent writes a single greppable line for events an operator
+// should look at, such as a replayed refresh token. Extra details are given
+// as alternating keys and values.
+func logSecurityEvent(event string, userID uuid.UUID, details ...interface{}) {
+	fields := []string{fmt.Sprintf("event=%s", event), fmt.Sprintf("user_id=%s", userID)}
+	for i := 0; i+1 < len(details); i += 2 {
+		fields = append(fields, fmt.Sprintf("%v=%v", details[i], details[i+1]))
+	}
+	log.Printf("security: %s", strings.Join(fields, " "))
+}
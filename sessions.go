@@ -0,0 +1,124 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"net"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxDeviceNameLength = 100
+	maxUserAgentLength  = 512
+)
+
+func getSessions(writer http.ResponseWriter, request *http.Request) {
+	userID, currentSession, err := authenticatedSession(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	sessions, err := ApiCfg.Store.ListActiveSessions(request.Context(), userID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	sessionsSlice := []map[string]interface{}{}
+	for _, session := range sessions {
+		sessionMap := makeSessionMap(session)
+		sessionMap["current"] = currentSession.Valid && currentSession.UUID == session.ID
+		sessionsSlice = append(sessionsSlice, sessionMap)
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{"sessions": sessionsSlice})
+}
+
+func makeSessionMap(session database.Session) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           session.ID.String(),
+		"created_at":   session.CreatedAt.String(),
+		"last_used_at": session.LastUsedAt.String(),
+		"user_agent":   session.UserAgent,
+		"ip":           session.Ip,
+		"device_name":  session.DeviceName,
+	}
+}
+
+func deleteSession(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	sessionID, err := uuid.Parse(request.PathValue("sessionID"))
+	if err != nil {
+		respondWithJsonError(writer, "Session not found", 404)
+		return
+	}
+
+	// revoking the session's refresh tokens ends it; access tokens already
+	// issued for it run out on their own shortly after
+	revoked, err := ApiCfg.Store.RevokeSession(request.Context(), database.RevokeSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if revoked == 0 {
+		respondWithJsonError(writer, "Session not found", 404)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
+
+func revokeOtherSessions(writer http.ResponseWriter, request *http.Request) {
+	userID, currentSession, err := authenticatedSession(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+	if !currentSession.Valid {
+		respondWithJsonError(writer, "Token is not tied to a session, log in again", 400)
+		return
+	}
+
+	revoked, err := ApiCfg.Store.RevokeOtherSessions(request.Context(), database.RevokeOtherSessionsParams{
+		UserID:        userID,
+		KeepSessionID: currentSession.UUID,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	respondWithJson(writer, 200, map[string]int64{"revoked": revoked})
+}
+
+// clientIP returns the address the request came from, without the port.
+func clientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// truncate shortens s to at most max bytes without splitting a character.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	s = s[:max]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+type sessionList struct {
+	Sessions []map[string]interface{} `json:"sessions"`
+}
+
+func TestSessions(t *testing.T) {
+	mux := newTestMux(t)
+	laptop := createTestUser(t, mux, "walt@breakingbad.com")
+	laptopToken := laptop["token"].(string)
+
+	// log in again from a named device with its own user agent
+	var body bytes.Buffer
+	credentials := userRequest{Email: "walt@breakingbad.com", Password: "hunter2", DeviceName: "Walt's phone"}
+	if err := json.NewEncoder(&body).Encode(credentials); err != nil {
+		t.Fatalf("unable to encode payload: %v", err)
+	}
+	request := httptest.NewRequest("POST", "/api/login", &body)
+	request.Header.Set("User-Agent", "ChirpyPhone/1.0")
+	request.RemoteAddr = "203.0.113.7:52100"
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, request)
+	if rec.Code != 200 {
+		t.Fatalf("login: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	phone := map[string]interface{}{}
+	decodeResponse(t, rec, &phone)
+
+	rec = doRequest(t, mux, "GET", "/api/sessions", laptopToken, nil)
+	if rec.Code != 200 {
+		t.Fatalf("sessions: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	list := sessionList{}
+	decodeResponse(t, rec, &list)
+	if len(list.Sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %v", list.Sessions)
+	}
+	for _, session := range list.Sessions {
+		switch session["id"] {
+		case laptop["session_id"]:
+			if session["current"] != true {
+				t.Errorf("expected the laptop session to be current: %v", session)
+			}
+		case phone["session_id"]:
+			if session["current"] != false || session["device_name"] != "Walt's phone" ||
+				session["user_agent"] != "ChirpyPhone/1.0" || session["ip"] != "203.0.113.7" {
+				t.Errorf("unexpected phone session %v", session)
+			}
+		default:
+			t.Errorf("unexpected session %v", session)
+		}
+	}
+
+	other := createTestUser(t, mux, "jesse@breakingbad.com")
+	if rec := doRequest(t, mux, "DELETE", "/api/sessions/"+laptop["session_id"].(string), other["token"].(string), nil); rec.Code != 404 {
+		t.Errorf("delete another user's session: expected 404, got %d", rec.Code)
+	}
+
+	if rec := doRequest(t, mux, "DELETE", "/api/sessions/"+phone["session_id"].(string), laptopToken, nil); rec.Code != 204 {
+		t.Fatalf("delete session: expected 204, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/refresh", phone["refresh_token"].(string), nil); rec.Code != 401 {
+		t.Errorf("refresh from a deleted session: expected 401, got %d", rec.Code)
+	}
+	decodeResponse(t, doRequest(t, mux, "GET", "/api/sessions", laptopToken, nil), &list)
+	if len(list.Sessions) != 1 {
+		t.Errorf("expected 1 session after delete, got %v", list.Sessions)
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	mux := newTestMux(t)
+	first := createTestUser(t, mux, "walt@breakingbad.com")
+	credentials := userRequest{Email: "walt@breakingbad.com", Password: "hunter2"}
+	second := map[string]interface{}{}
+	decodeResponse(t, doRequest(t, mux, "POST", "/api/login", "", credentials), &second)
+	third := map[string]interface{}{}
+	decodeResponse(t, doRequest(t, mux, "POST", "/api/login", "", credentials), &third)
+
+	rec := doRequest(t, mux, "POST", "/api/sessions/revoke-others", first["token"].(string), nil)
+	if rec.Code != 200 {
+		t.Fatalf("revoke others: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	revoked := map[string]int{}
+	decodeResponse(t, rec, &revoked)
+	if revoked["revoked"] != 2 {
+		t.Errorf("expected 2 revoked sessions, got %v", revoked)
+	}
+
+	for _, session := range []map[string]interface{}{second, third} {
+		if rec := doRequest(t, mux, "POST", "/api/refresh", session["refresh_token"].(string), nil); rec.Code != 401 {
+			t.Errorf("refresh from a revoked session: expected 401, got %d", rec.Code)
+		}
+	}
+	if rec := doRequest(t, mux, "POST", "/api/refresh", first["refresh_token"].(string), nil); rec.Code != 200 {
+		t.Errorf("refresh from the current session: expected 200, got %d", rec.Code)
+	}
+}
@@ -1,4 +0,0 @@
--- name: GetChirps :many
-SELECT * 
-FROM chirps
-ORDER BY created_at ASC;
\ No newline at end of file
@@ -1,10 +1,41 @@
 -- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, in_reply_to, kind, original_chirp_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     nOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: CreateChirpWithinRate :one
+WITH slot AS (
+    INSERT INTO chirp_rate_limits(user_id, posted_at)
+    VALUES (sqlc.arg(user_id), ARRAY[NOW()::timestamp])
+    ON CONFLICT (user_id) DO UPDATE
+    SET posted_at = ARRAY(
+        SELECT posted FROM unnest(chirp_rate_limits.posted_at) posted
+        WHERE posted > NOW() - INTERVAL '1 hour'
+    ) || NOW()::timestamp
+    WHERE (
+        SELECT COUNT(*) FROM unnest(chirp_rate_limits.posted_at) posted
+        WHERE posted > NOW() - INTERVAL '1 hour'
+    ) < sqlc.arg(chirps_per_hour)::int
+    RETURNING user_id
+)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, in_reply_to, kind, original_chirp_id)
+SELECT
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    sqlc.arg(body),
+    slot.user_id,
+    sqlc.arg(in_reply_to),
+    sqlc.arg(kind),
+    sqlc.arg(original_chirp_id)
+FROM slot
+RETURNING *;
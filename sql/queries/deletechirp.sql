@@ -1,3 +1,6 @@
 -- name: DeleteChirp :exec
-DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+UPDATE chirps
+SET body = '',
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
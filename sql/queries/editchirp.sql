@@ -0,0 +1,7 @@
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+AND deleted_at IS NULL
+RETURNING *;
@@ -0,0 +1,30 @@
+-- name: CreateChirpFilter :one
+INSERT INTO chirp_filters(id, created_at, updated_at, term, action)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: ListChirpFilters :many
+SELECT *
+FROM chirp_filters
+ORDER BY term ASC;
+
+-- name: GetChirpFilter :one
+SELECT *
+FROM chirp_filters
+WHERE id = $1;
+
+-- name: UpdateChirpFilter :one
+UPDATE chirp_filters
+SET term = $1, action = $2, updated_at = NOW()
+WHERE id = $3
+RETURNING *;
+
+-- name: DeleteChirpFilter :execrows
+DELETE FROM chirp_filters
+WHERE id = $1;
@@ -0,0 +1,21 @@
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags(id, created_at, chirp_id, filter_id, term)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+);
+
+-- name: ListUnresolvedChirpFlags :many
+SELECT *
+FROM chirp_flags
+WHERE resolved_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: ResolveChirpFlag :execrows
+UPDATE chirp_flags
+SET resolved_at = NOW()
+WHERE id = $1
+AND resolved_at IS NULL;
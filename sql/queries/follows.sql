@@ -0,0 +1,29 @@
+-- name: FollowUser :exec
+INSERT INTO follows(follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1
+AND followee_id = $2;
+
+-- name: ListFollowers :many
+SELECT *
+FROM follows
+WHERE followee_id = sqlc.arg(followee_id)
+AND (created_at, follower_id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListFollowing :many
+SELECT *
+FROM follows
+WHERE follower_id = sqlc.arg(follower_id)
+AND (created_at, followee_id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg(row_limit);
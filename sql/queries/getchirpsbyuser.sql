@@ -1,5 +0,0 @@
--- name: GetChirpsByUser :many
-SELECT *
-FROM chirps
-WHERE user_id = $1
-ORDER BY created_at ASC;
\ No newline at end of file
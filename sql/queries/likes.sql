@@ -0,0 +1,36 @@
+-- name: LikeChirp :exec
+INSERT INTO chirp_likes(user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: CountChirpLikes :many
+SELECT chirp_id, COUNT(*) AS like_count
+FROM chirp_likes
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY chirp_id;
+
+-- name: ListLikedChirpIDs :many
+SELECT chirp_id
+FROM chirp_likes
+WHERE user_id = sqlc.arg(user_id)
+AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: ListLikesByUser :many
+SELECT sqlc.embed(chirps), chirp_likes.created_at AS liked_at
+FROM chirp_likes
+INNER JOIN chirps
+ON chirp_likes.chirp_id = chirps.id
+WHERE chirp_likes.user_id = sqlc.arg(user_id)
+AND chirps.deleted_at IS NULL
+AND (chirp_likes.created_at, chirp_likes.chirp_id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY chirp_likes.created_at DESC, chirp_likes.chirp_id DESC
+LIMIT sqlc.arg(row_limit);
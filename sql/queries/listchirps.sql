@@ -0,0 +1,33 @@
+-- name: ListChirpsAsc :many
+SELECT *
+FROM chirps
+WHERE (created_at, id) > (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+AND deleted_at IS NULL
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListChirpsDesc :many
+SELECT *
+FROM chirps
+WHERE (created_at, id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+AND deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListChirpsByUserAsc :many
+SELECT *
+FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+AND deleted_at IS NULL
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListChirpsByUserDesc :many
+SELECT *
+FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+AND deleted_at IS NULL
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit);
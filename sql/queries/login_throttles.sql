@@ -0,0 +1,55 @@
+-- name: GetLoginThrottle :one
+SELECT *
+FROM login_throttles
+WHERE kind = $1
+AND subject = $2;
+
+-- name: RecordLoginAttempt :one
+INSERT INTO login_throttles(kind, subject, failures, last_failure_at, locked_until)
+VALUES (
+    sqlc.arg(kind),
+    sqlc.arg(subject),
+    1,
+    NOW(),
+    NOW() + make_interval(secs => (sqlc.arg(block_seconds)::int[])[1])
+)
+ON CONFLICT (kind, subject) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.last_failure_at < sqlc.arg(reset_before)::timestamp THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = NOW(),
+    locked_until = NOW() + make_interval(secs => (sqlc.arg(block_seconds)::int[])[LEAST(
+        CASE
+            WHEN login_throttles.last_failure_at < sqlc.arg(reset_before)::timestamp THEN 1
+            ELSE login_throttles.failures + 1
+        END,
+        cardinality(sqlc.arg(block_seconds)::int[])
+    )])
+WHERE login_throttles.locked_until IS NULL
+OR login_throttles.locked_until <= NOW()
+RETURNING *;
+
+-- name: ExtendLoginBlock :exec
+UPDATE login_throttles
+SET locked_until = GREATEST(locked_until, sqlc.arg(locked_until))
+WHERE kind = sqlc.arg(kind)
+AND subject = sqlc.arg(subject);
+
+-- name: RefundLoginAttempt :exec
+UPDATE login_throttles
+SET failures = GREATEST(failures - 1, 0)
+WHERE kind = $1
+AND subject = $2;
+
+-- name: ClearLoginThrottle :execrows
+DELETE FROM login_throttles
+WHERE kind = $1
+AND subject = $2;
+
+-- name: ListLoginLockouts :many
+SELECT *
+FROM login_throttles
+WHERE locked_until > NOW()
+AND kind IN ('account', 'ip')
+ORDER BY locked_until DESC, kind, subject;
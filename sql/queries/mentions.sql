@@ -0,0 +1,19 @@
+-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions(chirp_id, user_id, start_offset, end_offset)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (chirp_id, start_offset) DO NOTHING;
+
+-- name: ListChirpMentions :many
+SELECT *
+FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, start_offset;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
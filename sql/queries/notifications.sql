@@ -0,0 +1,37 @@
+-- name: CreateNotification :exec
+INSERT INTO notifications(id, created_at, user_id, actor_id, kind, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: ListNotifications :many
+SELECT *
+FROM notifications
+WHERE user_id = sqlc.arg(user_id)
+AND (NOT sqlc.arg(unread_only)::boolean OR read_at IS NULL)
+AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications
+WHERE user_id = $1
+AND read_at IS NULL;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1
+AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+AND read_at IS NULL;
@@ -0,0 +1,12 @@
+-- name: GetRechirp :one
+SELECT *
+FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+AND original_chirp_id = sqlc.arg(original_chirp_id)::uuid
+AND kind = 'rechirp'
+AND deleted_at IS NULL;
+
+-- name: ListChirpsByIDs :many
+SELECT *
+FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
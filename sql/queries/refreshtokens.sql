@@ -1,9 +1,42 @@
 -- name: MakeRefreshToken :exec
-INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at)
+INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, family_id)
 VALUES(
     $1,
     NOW(),
     NOW(),
     $2,
-    NOW() + INTERVAL '60 DAYS'
-);
\ No newline at end of file
+    NOW() + INTERVAL '60 DAYS',
+    $3
+);
+
+-- name: RotateRefreshToken :one
+WITH rotated AS (
+    UPDATE refresh_tokens
+    SET revoked_at = NOW(),
+        updated_at = NOW(),
+        replaced_by = sqlc.arg(replaced_by)
+    WHERE token = sqlc.arg(token)
+    AND revoked_at IS NULL
+    AND expires_at > NOW()
+    RETURNING *
+), replacement AS (
+    INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, family_id)
+    SELECT replaced_by, NOW(), NOW(), user_id, NOW() + INTERVAL '60 DAYS', family_id
+    FROM rotated
+)
+SELECT *
+FROM rotated;
+
+-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1
+AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL;
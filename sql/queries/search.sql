@@ -0,0 +1,27 @@
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirps),
+    ts_rank(chirps.search_vector, tsq)::real AS rank,
+    ts_headline(
+        'english',
+        replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+        tsq,
+        'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'
+    )::text AS highlight
+FROM chirps, websearch_to_tsquery('english', sqlc.arg(search)) tsq
+WHERE chirps.search_vector @@ tsq
+AND chirps.deleted_at IS NULL
+AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id)::uuid)
+AND (sqlc.narg(since)::timestamp IS NULL OR chirps.created_at >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR chirps.created_at < sqlc.narg(until)::timestamp)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
+
+-- name: SearchUsers :many
+SELECT *
+FROM users
+WHERE lower(display_name) LIKE sqlc.arg(pattern)
+OR handle LIKE sqlc.arg(pattern)
+ORDER BY handle ASC
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
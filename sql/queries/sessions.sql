@@ -0,0 +1,46 @@
+-- name: CreateSession :one
+INSERT INTO sessions(id, created_at, last_used_at, user_id, user_agent, ip, device_name)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = NOW()
+WHERE id = $1;
+
+-- name: ListActiveSessions :many
+SELECT *
+FROM sessions
+WHERE user_id = $1
+AND EXISTS (
+    SELECT 1
+    FROM refresh_tokens
+    WHERE refresh_tokens.family_id = sessions.id
+    AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.expires_at > NOW()
+)
+ORDER BY last_used_at DESC, id DESC;
+
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1
+AND user_id = $2
+AND revoked_at IS NULL;
+
+-- name: RevokeOtherSessions :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = sqlc.arg(user_id)
+AND family_id <> sqlc.arg(keep_session_id)
+AND revoked_at IS NULL;
@@ -0,0 +1,63 @@
+-- name: GetSubscription :one
+SELECT *
+FROM subscriptions
+WHERE user_id = $1;
+
+-- name: StartSubscription :one
+INSERT INTO subscriptions(id, user_id, plan, status, started_at, current_period_end, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    'active',
+    NOW(),
+    $3,
+    NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET plan = EXCLUDED.plan,
+    status = 'active',
+    started_at = CASE
+        WHEN subscriptions.status = 'expired' THEN NOW()
+        ELSE subscriptions.started_at
+    END,
+    current_period_end = GREATEST(subscriptions.current_period_end, EXCLUDED.current_period_end),
+    cancelled_at = NULL,
+    grace_notice_sent_at = NULL,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: CancelSubscription :execrows
+UPDATE subscriptions
+SET status = 'cancelled', cancelled_at = NOW(), updated_at = NOW()
+WHERE user_id = $1
+AND status IN ('active', 'past_due');
+
+-- name: EndSubscription :execrows
+UPDATE subscriptions
+SET status = 'expired',
+    current_period_end = LEAST(current_period_end, NOW()),
+    updated_at = NOW()
+WHERE user_id = $1
+AND status <> 'expired';
+
+-- name: StartSubscriptionGracePeriods :many
+UPDATE subscriptions
+SET status = 'past_due', grace_notice_sent_at = NOW(), updated_at = NOW()
+WHERE status = 'active'
+AND current_period_end <= sqlc.arg(now)
+RETURNING *;
+
+-- name: ExpireSubscriptions :many
+WITH expired AS (
+    UPDATE subscriptions
+    SET status = 'expired', updated_at = NOW()
+    WHERE (status = 'cancelled' AND current_period_end <= sqlc.arg(now))
+    OR (status = 'past_due' AND current_period_end <= sqlc.arg(grace_cutoff))
+    RETURNING user_id
+)
+UPDATE users
+SET is_chirpy_red = false, updated_at = NOW()
+FROM expired
+WHERE users.id = expired.user_id
+RETURNING users.id;
@@ -0,0 +1,49 @@
+-- name: UpsertTag :one
+INSERT INTO tags(id, created_at, name)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1
+)
+ON CONFLICT (name) DO UPDATE
+SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: TagChirp :exec
+INSERT INTO chirp_tags(chirp_id, tag_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (chirp_id, tag_id) DO NOTHING;
+
+-- name: ListChirpsByTag :many
+SELECT chirps.*
+FROM chirps
+INNER JOIN chirp_tags
+ON chirps.id = chirp_tags.chirp_id
+INNER JOIN tags
+ON chirp_tags.tag_id = tags.id
+WHERE tags.name = sqlc.arg(tag)
+AND chirps.deleted_at IS NULL
+AND (chirps.created_at, chirps.id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListTrendingTags :many
+SELECT tags.name, COUNT(*) AS chirp_count
+FROM chirp_tags
+INNER JOIN tags
+ON chirp_tags.tag_id = tags.id
+INNER JOIN chirps
+ON chirp_tags.chirp_id = chirps.id
+WHERE chirp_tags.created_at >= sqlc.arg(since)
+AND chirps.deleted_at IS NULL
+GROUP BY tags.name
+ORDER BY chirp_count DESC, tags.name ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: DeleteChirpTags :exec
+DELETE FROM chirp_tags
+WHERE chirp_id = $1;
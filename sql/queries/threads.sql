@@ -0,0 +1,44 @@
+-- name: ListReplies :many
+SELECT *
+FROM chirps
+WHERE in_reply_to = sqlc.arg(chirp_id)::uuid
+AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors(id, depth) AS (
+    SELECT chirps.in_reply_to, 1
+    FROM chirps
+    WHERE chirps.id = sqlc.arg(chirp_id)::uuid
+    AND chirps.in_reply_to IS NOT NULL
+    UNION ALL
+    SELECT chirps.in_reply_to, ancestors.depth + 1
+    FROM chirps
+    INNER JOIN ancestors
+    ON chirps.id = ancestors.id
+    WHERE chirps.in_reply_to IS NOT NULL
+)
+SELECT chirps.*
+FROM chirps
+INNER JOIN ancestors
+ON chirps.id = ancestors.id
+ORDER BY ancestors.depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants(id) AS (
+    SELECT chirps.id
+    FROM chirps
+    WHERE chirps.in_reply_to = sqlc.arg(chirp_id)::uuid
+    UNION ALL
+    SELECT chirps.id
+    FROM chirps
+    INNER JOIN descendants
+    ON chirps.in_reply_to = descendants.id
+)
+SELECT chirps.*
+FROM chirps
+INNER JOIN descendants
+ON chirps.id = descendants.id
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg(row_limit);
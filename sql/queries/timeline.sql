@@ -0,0 +1,10 @@
+-- name: ListTimeline :many
+SELECT chirps.*
+FROM chirps
+INNER JOIN follows
+ON chirps.user_id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg(follower_id)
+AND (chirps.created_at, chirps.id) < (sqlc.arg(cursor_created_at)::timestamp, sqlc.arg(cursor_id)::uuid)
+AND chirps.deleted_at IS NULL
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,37 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens(id, created_at, user_id, name, token_hash, token_prefix, scopes, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    sqlc.arg(user_id),
+    sqlc.arg(name),
+    sqlc.arg(token_hash),
+    sqlc.arg(token_prefix),
+    sqlc.arg(scopes)::text[],
+    sqlc.narg(expires_at)
+)
+RETURNING *;
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT *
+FROM personal_access_tokens
+WHERE token_hash = $1;
+
+-- name: ListPersonalAccessTokens :many
+SELECT *
+FROM personal_access_tokens
+WHERE user_id = $1
+AND revoked_at IS NULL
+ORDER BY created_at DESC, id DESC;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = NOW()
+WHERE id = $1;
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND revoked_at IS NULL;
@@ -0,0 +1,67 @@
+-- name: UpsertTOTPCredential :one
+INSERT INTO totp_credentials(user_id, created_at, secret)
+VALUES (
+    $1,
+    NOW(),
+    $2
+)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret,
+    created_at = EXCLUDED.created_at,
+    last_used_step = 0
+WHERE totp_credentials.enabled_at IS NULL
+RETURNING *;
+
+-- name: GetTOTPCredential :one
+SELECT *
+FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: EnableTOTPCredential :execrows
+UPDATE totp_credentials
+SET enabled_at = NOW()
+WHERE user_id = $1
+AND enabled_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE totp_credentials
+SET last_used_step = sqlc.arg(step)
+WHERE user_id = sqlc.arg(user_id)
+AND last_used_step < sqlc.arg(step);
+
+-- name: DeleteTOTPCredential :execrows
+DELETE FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes(id, created_at, user_id, code_hash)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2
+);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+AND code_hash = $2
+AND used_at IS NULL;
+
+-- name: UseChallengeToken :execrows
+WITH expired AS (
+    DELETE FROM used_challenge_tokens
+    WHERE expires_at < NOW()
+)
+INSERT INTO used_challenge_tokens(id, user_id, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (id) DO NOTHING;
@@ -1,5 +1,27 @@
--- name: UpdateUser :one
+-- name: UpdateUserPassword :exec
 UPDATE users
-SET email = $1, hashed_password = $2, updated_at = NOW()
-WHERE id = $3
-RETURNING *;
\ No newline at end of file
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetVerifiedEmail :one
+UPDATE users
+SET email = $2, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: PatchUser :one
+UPDATE users
+SET display_name = COALESCE(sqlc.narg(display_name), display_name),
+    handle = COALESCE(sqlc.narg(handle), handle),
+    bio = COALESCE(sqlc.narg(bio), bio),
+    location = COALESCE(sqlc.narg(location), location),
+    website = COALESCE(sqlc.narg(website), website),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_key = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
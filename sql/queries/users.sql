@@ -1,10 +1,28 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, display_name, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserByHandle :one
+SELECT *
+FROM users
+WHERE handle = $1;
+
+-- name: ListUsersByHandles :many
+SELECT *
+FROM users
+WHERE handle = ANY(sqlc.arg(handles)::text[]);
+
+-- name: GetUserStats :one
+SELECT
+    (SELECT COUNT(*) FROM follows WHERE followee_id = sqlc.arg(user_id)) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follower_id = sqlc.arg(user_id)) AS following_count,
+    (SELECT COUNT(*) FROM chirps WHERE user_id = sqlc.arg(user_id) AND deleted_at IS NULL) AS chirp_count;
@@ -0,0 +1,56 @@
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries(id, created_at, endpoint_id, event_id, event_type, payload, next_attempt_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+);
+
+-- name: GetWebhookDelivery :one
+SELECT *
+FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending'
+    AND next_attempt_at <= sqlc.arg(now)
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(row_limit)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RecordWebhookDeliveryAttempt :one
+UPDATE webhook_deliveries
+SET status = $2,
+    next_attempt_at = $3,
+    response_status = $4,
+    last_error = $5,
+    attempts = attempts + 1,
+    last_attempt_at = NOW(),
+    delivered_at = CASE WHEN $2 = 'succeeded' THEN NOW() END
+WHERE id = $1
+RETURNING *;
+
+-- name: ListWebhookDeliveries :many
+SELECT *
+FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY created_at DESC, id
+LIMIT sqlc.arg(row_limit);
+
+-- name: RetryWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = 'pending', next_attempt_at = NOW()
+WHERE id = $1
+AND status = 'dead'
+RETURNING *;
@@ -0,0 +1,34 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints(id, created_at, user_id, url, secret, events)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetWebhookEndpoint :one
+SELECT *
+FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: ListWebhookEndpoints :many
+SELECT *
+FROM webhook_endpoints
+WHERE user_id = sqlc.narg(user_id)
+OR (sqlc.narg(user_id)::uuid IS NULL AND user_id IS NULL)
+ORDER BY created_at, id;
+
+-- name: ListWebhookEndpointsForEvent :many
+SELECT *
+FROM webhook_endpoints
+WHERE sqlc.arg(event_type)::text = ANY(events)
+AND (user_id IS NULL OR user_id = sqlc.narg(user_id))
+ORDER BY created_at, id;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1;
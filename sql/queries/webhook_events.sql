@@ -0,0 +1,41 @@
+-- name: RecordWebhookEvent :one
+INSERT INTO webhook_events(id, source, event_id, event_type, payload, received_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+)
+ON CONFLICT (source, event_id) DO UPDATE
+SET source = EXCLUDED.source
+RETURNING *;
+
+-- name: GetWebhookEvent :one
+SELECT *
+FROM webhook_events
+WHERE id = $1;
+
+-- name: ClaimWebhookEvent :one
+UPDATE webhook_events
+SET status = 'processing'
+WHERE id = sqlc.arg(id)
+AND status = ANY(sqlc.arg(statuses)::text[])
+RETURNING *;
+
+-- name: FinishWebhookEvent :one
+UPDATE webhook_events
+SET status = $2,
+    last_error = $3,
+    attempts = attempts + 1,
+    processed_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListWebhookEvents :many
+SELECT *
+FROM webhook_events
+WHERE sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)
+ORDER BY received_at DESC, id
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE chirp_likes(
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT chirp_likes_user_id_chirp_id_key
+    UNIQUE (user_id, chirp_id),
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_likes_chirp_id_idx
+ON chirp_likes (chirp_id);
+
+CREATE INDEX chirp_likes_user_id_created_at_idx
+ON chirp_likes (user_id, created_at, chirp_id);
+
+-- +goose Down
+DROP TABLE chirp_likes;
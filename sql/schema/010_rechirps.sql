@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD kind TEXT
+DEFAULT 'chirp'
+NOT NULL
+CHECK (kind IN ('chirp', 'rechirp', 'quote'));
+
+ALTER TABLE chirps
+ADD original_chirp_id UUID
+REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX chirps_user_id_original_chirp_id_rechirp_idx
+ON chirps (user_id, original_chirp_id)
+WHERE kind = 'rechirp' AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX chirps_user_id_original_chirp_id_rechirp_idx;
+
+ALTER TABLE chirps
+DROP COLUMN original_chirp_id;
+
+ALTER TABLE chirps
+DROP COLUMN kind;
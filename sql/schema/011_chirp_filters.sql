@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE chirp_filters(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    term TEXT UNIQUE NOT NULL,
+    action TEXT NOT NULL
+    CHECK (action IN ('mask', 'reject', 'flag'))
+);
+
+INSERT INTO chirp_filters(id, created_at, updated_at, term, action)
+VALUES
+    (gen_random_uuid(), NOW(), NOW(), 'kerfuffle', 'mask'),
+    (gen_random_uuid(), NOW(), NOW(), 'sharbert', 'mask'),
+    (gen_random_uuid(), NOW(), NOW(), 'fornax', 'mask');
+
+CREATE TABLE chirp_flags(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL,
+    filter_id UUID,
+    term TEXT NOT NULL,
+    resolved_at TIMESTAMP,
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_filter_id
+    FOREIGN KEY (filter_id)
+    REFERENCES chirp_filters(id) ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE chirp_filters;
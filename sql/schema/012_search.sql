@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD search_vector tsvector
+GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX chirps_search_vector_idx
+ON chirps USING GIN (search_vector);
+
+ALTER TABLE users
+ADD display_name TEXT
+DEFAULT ''
+NOT NULL;
+
+CREATE INDEX users_lower_email_pattern_idx
+ON users (lower(email) text_pattern_ops);
+
+CREATE INDEX users_lower_display_name_pattern_idx
+ON users (lower(display_name) text_pattern_ops);
+
+-- +goose Down
+DROP INDEX users_lower_display_name_pattern_idx;
+DROP INDEX users_lower_email_pattern_idx;
+
+ALTER TABLE users
+DROP COLUMN display_name;
+
+DROP INDEX chirps_search_vector_idx;
+
+ALTER TABLE chirps
+DROP COLUMN search_vector;
@@ -0,0 +1,61 @@
+-- +goose Up
+ALTER TABLE users
+ADD handle TEXT;
+
+UPDATE users
+SET handle = 'user_' || substr(md5(id::text), 1, 10);
+
+ALTER TABLE users
+ALTER COLUMN handle SET NOT NULL,
+ADD CONSTRAINT users_handle_key UNIQUE (handle),
+ADD CONSTRAINT users_handle_format CHECK (handle ~ '^[a-z0-9_]{3,15}$');
+
+CREATE INDEX users_handle_pattern_idx
+ON users (handle text_pattern_ops);
+
+CREATE TABLE chirp_mentions(
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    start_offset INTEGER NOT NULL,
+    end_offset INTEGER NOT NULL,
+    PRIMARY KEY (chirp_id, start_offset),
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_mentions_user_id_idx
+ON chirp_mentions (user_id);
+
+CREATE TABLE notifications(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    kind TEXT NOT NULL
+    CHECK (kind IN ('mention')),
+    chirp_id UUID NOT NULL,
+    read_at TIMESTAMP,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_actor_id
+    FOREIGN KEY (actor_id)
+    REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id)
+    REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX notifications_user_id_created_at_id_idx
+ON notifications (user_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE notifications;
+DROP TABLE chirp_mentions;
+
+ALTER TABLE users
+DROP COLUMN handle;
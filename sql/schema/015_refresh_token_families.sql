@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ADD family_id UUID,
+ADD replaced_by TEXT;
+
+UPDATE refresh_tokens
+SET family_id = gen_random_uuid();
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX refresh_tokens_family_id_idx
+ON refresh_tokens (family_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_family_id_idx;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN replaced_by,
+DROP COLUMN family_id;
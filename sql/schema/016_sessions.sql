@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE sessions(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    device_name TEXT NOT NULL DEFAULT '',
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX sessions_user_id_idx
+ON sessions (user_id);
+
+-- every existing token family becomes a session without metadata
+INSERT INTO sessions(id, created_at, last_used_at, user_id)
+SELECT family_id, MIN(created_at), MAX(updated_at), user_id
+FROM refresh_tokens
+GROUP BY family_id, user_id;
+
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT fk_family_id
+FOREIGN KEY (family_id)
+REFERENCES sessions(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP CONSTRAINT fk_family_id;
+
+DROP TABLE sessions;
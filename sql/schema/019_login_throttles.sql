@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE login_throttles(
+    kind TEXT NOT NULL CHECK (kind IN ('account', 'ip')),
+    subject TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    PRIMARY KEY (kind, subject)
+);
+
+CREATE INDEX login_throttles_locked_until_idx
+ON login_throttles (locked_until);
+
+-- +goose Down
+DROP TABLE login_throttles;
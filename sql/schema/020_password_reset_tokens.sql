@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE password_reset_tokens(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx
+ON password_reset_tokens (user_id);
+
+-- +goose Down
+DROP TABLE password_reset_tokens;
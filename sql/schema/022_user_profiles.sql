@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN location TEXT NOT NULL DEFAULT '',
+ADD COLUMN website TEXT NOT NULL DEFAULT '',
+ADD COLUMN avatar_key TEXT;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN avatar_key,
+DROP COLUMN website,
+DROP COLUMN location,
+DROP COLUMN bio;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE webhook_events(
+    id UUID PRIMARY KEY,
+    source TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'processed', 'ignored', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    received_at TIMESTAMP NOT NULL,
+    processed_at TIMESTAMP,
+    UNIQUE (source, event_id)
+);
+
+CREATE INDEX webhook_events_received_at_idx
+ON webhook_events (received_at DESC);
+
+-- +goose Down
+DROP TABLE webhook_events;
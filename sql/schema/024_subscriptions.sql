@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE subscriptions(
+    id UUID PRIMARY KEY,
+    user_id UUID UNIQUE NOT NULL,
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL
+        CHECK (status IN ('active', 'past_due', 'cancelled', 'expired')),
+    started_at TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP NOT NULL,
+    cancelled_at TIMESTAMP,
+    grace_notice_sent_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX subscriptions_status_current_period_end_idx
+ON subscriptions (status, current_period_end);
+
+-- upgrades from before subscriptions existed get a first period from now
+INSERT INTO subscriptions(id, user_id, plan, status, started_at, current_period_end, updated_at)
+SELECT gen_random_uuid(), id, 'chirpy_red', 'active', NOW(), NOW() + INTERVAL '30 days', NOW()
+FROM users
+WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE subscriptions;
@@ -0,0 +1,47 @@
+-- +goose Up
+CREATE TABLE webhook_endpoints(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- endpoints without a user are registered by admins and get every event
+CREATE INDEX webhook_endpoints_user_id_idx
+ON webhook_endpoints (user_id);
+
+CREATE TABLE webhook_deliveries(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    endpoint_id UUID NOT NULL,
+    event_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'succeeded', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_attempt_at TIMESTAMP,
+    response_status INTEGER,
+    last_error TEXT,
+    delivered_at TIMESTAMP,
+    CONSTRAINT fk_endpoint_id
+    FOREIGN KEY (endpoint_id)
+    REFERENCES webhook_endpoints(id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_deliveries_due_idx
+ON webhook_deliveries (next_attempt_at)
+WHERE status = 'pending';
+
+CREATE INDEX webhook_deliveries_endpoint_id_created_at_idx
+ON webhook_deliveries (endpoint_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_endpoints;
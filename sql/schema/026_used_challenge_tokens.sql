@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE used_challenge_tokens(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX used_challenge_tokens_expires_at_idx
+ON used_challenge_tokens (expires_at);
+
+-- +goose Down
+DROP TABLE used_challenge_tokens;
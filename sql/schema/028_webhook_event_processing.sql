@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE webhook_events
+DROP CONSTRAINT webhook_events_status_check,
+ADD CONSTRAINT webhook_events_status_check
+CHECK (status IN ('pending', 'processing', 'processed', 'ignored', 'failed'));
+
+-- +goose Down
+UPDATE webhook_events
+SET status = 'pending'
+WHERE status = 'processing';
+
+ALTER TABLE webhook_events
+DROP CONSTRAINT webhook_events_status_check,
+ADD CONSTRAINT webhook_events_status_check
+CHECK (status IN ('pending', 'processed', 'ignored', 'failed'));
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE chirp_rate_limits(
+    user_id UUID PRIMARY KEY,
+    posted_at TIMESTAMP[] NOT NULL,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- chirps posted in the last hour count towards the limit straight away
+INSERT INTO chirp_rate_limits(user_id, posted_at)
+SELECT user_id, array_agg(created_at)
+FROM chirps
+WHERE kind <> 'rechirp'
+AND created_at > NOW() - INTERVAL '1 hour'
+GROUP BY user_id;
+
+-- +goose Down
+DROP TABLE chirp_rate_limits;
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/mail"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	chirpyRedPlan = "chirpy_red"
+
+	// subscriptionPeriod is how long an upgrade or a renewal lasts. Polka
+	// doesn't tell us, so every period is the same length.
+	subscriptionPeriod = 30 * 24 * time.Hour
+
+	// subscriptionGracePeriod keeps Chirpy Red working after a period ends
+	// without a renewal, in case the payment is only late.
+	subscriptionGracePeriod = 3 * 24 * time.Hour
+
+	subscriptionInterval = time.Hour
+)
+
+// startSubscription upgrades a user, or extends their current period if
+// renew is set.
+func startSubscription(ctx context.Context, userID uuid.UUID, renew bool) error {
+	periodStart := time.Now().UTC()
+	if renew {
+		current, err := ApiCfg.Store.GetSubscription(ctx, userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		// renewals extend the current period rather than starting a new one
+		if err == nil && current.Status != "expired" && current.CurrentPeriodEnd.After(periodStart) {
+			periodStart = current.CurrentPeriodEnd
+		}
+	}
+
+	if _, err := ApiCfg.Store.StartSubscription(ctx, database.StartSubscriptionParams{
+		UserID:           userID,
+		Plan:             chirpyRedPlan,
+		CurrentPeriodEnd: periodStart.Add(subscriptionPeriod),
+	}); err != nil {
+		return err
+	}
+
+	_, err := ApiCfg.Store.SetUserChirpyRed(ctx, database.SetUserChirpyRedParams{ID: userID, IsChirpyRed: true})
+	return err
+}
+
+// endSubscription downgrades a user immediately.
+func endSubscription(ctx context.Context, userID uuid.UUID) error {
+	if _, err := ApiCfg.Store.EndSubscription(ctx, userID); err != nil {
+		return err
+	}
+
+	_, err := ApiCfg.Store.SetUserChirpyRed(ctx, database.SetUserChirpyRedParams{ID: userID, IsChirpyRed: false})
+	return err
+}
+
+// checkSubscriptions moves subscriptions whose period ended at now into
+// their grace period, emailing the user, and expires the ones whose grace
+// period or cancelled period is over.
+func checkSubscriptions(ctx context.Context, now time.Time) error {
+	lapsed, err := ApiCfg.Store.StartSubscriptionGracePeriods(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range lapsed {
+		if err := sendGraceNotice(ctx, subscription); err != nil {
+			log.Printf("unable to send grace notice to %s: %s", subscription.UserID, err)
+		}
+	}
+
+	expired, err := ApiCfg.Store.ExpireSubscriptions(ctx, database.ExpireSubscriptionsParams{
+		Now:         now,
+		GraceCutoff: now.Add(-subscriptionGracePeriod),
+	})
+	if err != nil {
+		return err
+	}
+	for _, userID := range expired {
+		log.Printf("chirpy red subscription of %s expired", userID)
+	}
+	return nil
+}
+
+func sendGraceNotice(ctx context.Context, subscription database.Subscription) error {
+	user, err := ApiCfg.Store.GetUser(ctx, subscription.UserID)
+	if err != nil {
+		return err
+	}
+
+	graceEnd := subscription.CurrentPeriodEnd.Add(subscriptionGracePeriod)
+	return ApiCfg.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy Red subscription has lapsed",
+		Body: fmt.Sprintf("We didn't receive a renewal for the Chirpy Red subscription of @%s. "+
+			"Chirpy Red keeps working until %s; renew before then to keep it.\n",
+			user.Handle, graceEnd.Format("January 2, 2006 15:04 MST")),
+	})
+}
+
+// runSubscriptionJobs runs checkSubscriptions every interval until ctx is
+// cancelled.
+func runSubscriptionJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := checkSubscriptions(ctx, time.Now().UTC()); err != nil {
+			log.Printf("unable to check subscriptions: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func makeSubscriptionMap(subscription database.Subscription) map[string]interface{} {
+	subscriptionMap := map[string]interface{}{
+		"plan":               subscription.Plan,
+		"status":             subscription.Status,
+		"started_at":         subscription.StartedAt.String(),
+		"current_period_end": subscription.CurrentPeriodEnd.String(),
+		"renews":             subscription.Status == "active",
+		"cancelled_at":       nil,
+		"grace_period_end":   nil,
+	}
+	if subscription.CancelledAt.Valid {
+		subscriptionMap["cancelled_at"] = subscription.CancelledAt.Time.String()
+	}
+	if subscription.Status == "past_due" {
+		subscriptionMap["grace_period_end"] = subscription.CurrentPeriodEnd.Add(subscriptionGracePeriod).String()
+	}
+	return subscriptionMap
+}
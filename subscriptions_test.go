@@ -0,0 +1,119 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionExpiry(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	jesse := createTestUser(t, mux, "jesse@breakingbad.com")
+
+	send := func(id, event, userID string) {
+		t.Helper()
+		body := []byte(`{"id":"` + id + `","event":"` + event + `","data":{"user_id":"` + userID + `"}}`)
+		if rec := sendPolkaWebhook(t, mux, auth.SignWebhook("test-polka-secret", time.Now(), body), body); rec.Code != 204 {
+			t.Fatalf("%s %s: expected 204, got %d", id, event, rec.Code)
+		}
+	}
+	login := func(email string) map[string]interface{} {
+		t.Helper()
+		user := map[string]interface{}{}
+		decodeResponse(t, doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: email, Password: "hunter2"}), &user)
+		return user
+	}
+	subscription := func(user map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		sub, ok := user["subscription"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("user has no subscription: %v", user)
+		}
+		return sub
+	}
+
+	if user := login("walt@breakingbad.com"); user["subscription"] != nil {
+		t.Errorf("new user has a subscription: %v", user["subscription"])
+	}
+
+	send("evt_1", "user.upgraded", walt["id"].(string))
+	send("evt_2", "user.upgraded", jesse["id"].(string))
+	send("evt_3", "subscription.cancelled", jesse["id"].(string))
+
+	sub := subscription(login("walt@breakingbad.com"))
+	if sub["plan"] != "chirpy_red" || sub["status"] != "active" || sub["renews"] != true {
+		t.Errorf("unexpected subscription after upgrade: %v", sub)
+	}
+	periodEnd := sub["current_period_end"]
+
+	// a renewal extends the period that is already paid for
+	send("evt_4", "subscription.renewed", walt["id"].(string))
+	if sub := subscription(login("walt@breakingbad.com")); sub["current_period_end"] == periodEnd {
+		t.Errorf("renewal did not extend the period: %v", sub)
+	}
+
+	ctx := context.Background()
+	if err := checkSubscriptions(ctx, time.Now().UTC()); err != nil {
+		t.Fatalf("check subscriptions: %v", err)
+	}
+	if user := login("jesse@breakingbad.com"); user["is_chirpy_red"] != true || subscription(user)["status"] != "cancelled" {
+		t.Errorf("cancelled subscription ended before its period: %v", user)
+	}
+
+	// the cancelled subscription expires at the end of its period, without
+	// a grace period
+	if err := checkSubscriptions(ctx, time.Now().UTC().Add(subscriptionPeriod+time.Minute)); err != nil {
+		t.Fatalf("check subscriptions: %v", err)
+	}
+	if user := login("jesse@breakingbad.com"); user["is_chirpy_red"] != false || subscription(user)["status"] != "expired" {
+		t.Errorf("cancelled subscription did not expire: %v", user)
+	}
+
+	// walt's renewed subscription lapses into a grace period with a notice
+	lapsed := time.Now().UTC().Add(2*subscriptionPeriod + time.Minute)
+	if err := checkSubscriptions(ctx, lapsed); err != nil {
+		t.Fatalf("check subscriptions: %v", err)
+	}
+	user := login("walt@breakingbad.com")
+	if user["is_chirpy_red"] != true || subscription(user)["status"] != "past_due" || subscription(user)["grace_period_end"] == nil {
+		t.Errorf("lapsed subscription did not enter its grace period: %v", user)
+	}
+	notices := 0
+	for _, msg := range sentMail(t) {
+		if msg.To == "walt@breakingbad.com" && strings.Contains(msg.Subject, "lapsed") {
+			notices++
+		}
+	}
+	if notices != 1 {
+		t.Errorf("expected one grace notice, got %d", notices)
+	}
+
+	// running the job again doesn't send a second notice
+	if err := checkSubscriptions(ctx, lapsed.Add(time.Hour)); err != nil {
+		t.Fatalf("check subscriptions: %v", err)
+	}
+	if err := checkSubscriptions(ctx, lapsed.Add(subscriptionGracePeriod)); err != nil {
+		t.Fatalf("check subscriptions: %v", err)
+	}
+	if user := login("walt@breakingbad.com"); user["is_chirpy_red"] != false || subscription(user)["status"] != "expired" {
+		t.Errorf("subscription did not expire after its grace period: %v", user)
+	}
+	notices = 0
+	for _, msg := range sentMail(t) {
+		if msg.To == "walt@breakingbad.com" && strings.Contains(msg.Subject, "lapsed") {
+			notices++
+		}
+	}
+	if notices != 1 {
+		t.Errorf("expected one grace notice after the job ran again, got %d", notices)
+	}
+
+	// upgrading again starts a new subscription
+	send("evt_5", "user.upgraded", walt["id"].(string))
+	if sub := subscription(login("walt@breakingbad.com")); sub["status"] != "active" {
+		t.Errorf("upgrade after expiry did not restart the subscription: %v", sub)
+	}
+}
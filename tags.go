@@ -0,0 +1,150 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	trendingWindow   = 24 * time.Hour
+	trendingInterval = 5 * time.Minute
+	trendingLimit    = 10
+)
+
+// trendingCache holds the most recently computed trending tags so the
+// endpoint never runs the aggregate query per request.
+type trendingCache struct {
+	mu          sync.RWMutex
+	tags        []database.ListTrendingTagsRow
+	refreshedAt time.Time
+}
+
+var trending = &trendingCache{}
+
+func (cache *trendingCache) refresh(ctx context.Context) error {
+	tags, err := ApiCfg.Store.ListTrendingTags(ctx, database.ListTrendingTagsParams{
+		Since:    time.Now().UTC().Add(-trendingWindow),
+		RowLimit: trendingLimit,
+	})
+	if err != nil {
+		return err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.tags = tags
+	cache.refreshedAt = time.Now().UTC()
+	return nil
+}
+
+func (cache *trendingCache) snapshot() ([]database.ListTrendingTagsRow, time.Time) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	return cache.tags, cache.refreshedAt
+}
+
+// refreshTrending recomputes the trending tags every interval until ctx is
+// cancelled.
+func refreshTrending(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := trending.refresh(ctx); err != nil {
+			log.Printf("unable to refresh trending tags: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tagChirp records the hashtags in a new chirp's body. Failures are logged
+// rather than failing the chirp, which has already been created.
+func tagChirp(ctx context.Context, chirp database.Chirp) {
+	seen := map[string]bool{}
+	for _, hashtag := range entities.Hashtags(chirp.Body) {
+		if seen[hashtag.Tag] {
+			continue
+		}
+		seen[hashtag.Tag] = true
+
+		tag, err := ApiCfg.Store.UpsertTag(ctx, hashtag.Tag)
+		if err != nil {
+			log.Printf("unable to save tag %q: %s", hashtag.Tag, err)
+			continue
+		}
+
+		params := database.TagChirpParams{
+			ChirpID: chirp.ID,
+			TagID:   tag.ID,
+		}
+		if err := ApiCfg.Store.TagChirp(ctx, params); err != nil {
+			log.Printf("unable to tag chirp %s with %q: %s", chirp.ID, hashtag.Tag, err)
+		}
+	}
+}
+
+func getTagChirps(writer http.ResponseWriter, request *http.Request) {
+	tag, ok := entities.NormalizeTag(request.PathValue("tag"))
+	if !ok {
+		respondWithJsonError(writer, "Invalid tag", 400)
+		return
+	}
+
+	query := request.URL.Query()
+	page, err := parsePageRequest(query.Get("limit"), query.Get("cursor"), "desc")
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return
+	}
+
+	chirps, err := ApiCfg.Store.ListChirpsByTag(request.Context(), database.ListChirpsByTagParams{
+		Tag:             tag,
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		RowLimit:        page.Limit + 1,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	respondWithChirpPage(writer, request, chirps, page.Limit)
+}
+
+func getTrending(writer http.ResponseWriter, request *http.Request) {
+	tags, refreshedAt := trending.snapshot()
+
+	// the refresher has not run yet, so compute once rather than return
+	// an empty list
+	if refreshedAt.IsZero() {
+		if err := trending.refresh(request.Context()); err != nil {
+			respondWithJsonError(writer, "Something went wrong", 500)
+			return
+		}
+		tags, refreshedAt = trending.snapshot()
+	}
+
+	tagsSlice := []map[string]interface{}{}
+	for _, tag := range tags {
+		tagsSlice = append(tagsSlice, map[string]interface{}{
+			"tag":         tag.Name,
+			"chirp_count": tag.ChirpCount,
+		})
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{
+		"window_hours": int(trendingWindow.Hours()),
+		"refreshed_at": refreshedAt,
+		"tags":         tagsSlice,
+	})
+}
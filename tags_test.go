@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTagChirps(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	jesse := createTestUser(t, mux, "jesse@breakingbad.com")
+	waltToken := walt["token"].(string)
+	jesseToken := jesse["token"].(string)
+
+	first := postChirp(t, mux, waltToken, map[string]interface{}{"body": "Say my name #Heisenberg #science"})
+	postChirp(t, mux, jesseToken, map[string]interface{}{"body": "#science, yo! #SCIENCE"})
+	postChirp(t, mux, jesseToken, map[string]interface{}{"body": "not a tag: walt#science"})
+
+	rec := doRequest(t, mux, "GET", "/api/tags/Science/chirps?limit=1", "", nil)
+	if rec.Code != 200 {
+		t.Fatalf("tag chirps: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	page := chirpPage{}
+	decodeResponse(t, rec, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0]["user_id"] != jesse["id"] {
+		t.Fatalf("expected jesse's chirp first, got %v", page.Chirps)
+	}
+	if page.NextCursor == nil {
+		t.Fatalf("expected a next cursor")
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/tags/science/chirps?cursor="+*page.NextCursor, "", nil)
+	decodeResponse(t, rec, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0]["id"] != first["id"] {
+		t.Errorf("unexpected second page %v", page.Chirps)
+	}
+
+	if rec := doRequest(t, mux, "GET", "/api/tags/not-a-tag/chirps", "", nil); rec.Code != 400 {
+		t.Errorf("invalid tag: expected 400, got %d", rec.Code)
+	}
+
+	// deleted chirps drop out of the tag listing
+	doRequest(t, mux, "DELETE", "/api/chirps/"+first["id"].(string), waltToken, nil)
+	rec = doRequest(t, mux, "GET", "/api/tags/heisenberg/chirps", "", nil)
+	decodeResponse(t, rec, &page)
+	if len(page.Chirps) != 0 {
+		t.Errorf("expected no chirps after delete, got %v", page.Chirps)
+	}
+}
+
+func TestTrending(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	waltToken := walt["token"].(string)
+
+	postChirp(t, mux, waltToken, map[string]interface{}{"body": "#science #chemistry"})
+	postChirp(t, mux, waltToken, map[string]interface{}{"body": "#science"})
+
+	type trendingResponse struct {
+		WindowHours int `json:"window_hours"`
+		Tags        []struct {
+			Tag        string `json:"tag"`
+			ChirpCount int    `json:"chirp_count"`
+		} `json:"tags"`
+	}
+
+	rec := doRequest(t, mux, "GET", "/api/trending", "", nil)
+	if rec.Code != 200 {
+		t.Fatalf("trending: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	resp := trendingResponse{}
+	decodeResponse(t, rec, &resp)
+	if resp.WindowHours != 24 || len(resp.Tags) != 2 {
+		t.Fatalf("unexpected trending response %+v", resp)
+	}
+	if resp.Tags[0].Tag != "science" || resp.Tags[0].ChirpCount != 2 {
+		t.Errorf("expected science to trend first, got %+v", resp.Tags)
+	}
+
+	// the endpoint serves the cached result until the next refresh
+	postChirp(t, mux, waltToken, map[string]interface{}{"body": "#chemistry #chemistry"})
+	postChirp(t, mux, waltToken, map[string]interface{}{"body": "#chemistry"})
+	decodeResponse(t, doRequest(t, mux, "GET", "/api/trending", "", nil), &resp)
+	if resp.Tags[0].Tag != "science" {
+		t.Errorf("expected the cached ranking, got %+v", resp.Tags)
+	}
+
+	if err := trending.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	decodeResponse(t, doRequest(t, mux, "GET", "/api/trending", "", nil), &resp)
+	if resp.Tags[0].Tag != "chemistry" || resp.Tags[0].ChirpCount != 3 {
+		t.Errorf("expected chemistry after refresh, got %+v", resp.Tags)
+	}
+}
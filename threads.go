@@ -0,0 +1,90 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// maxThreadDescendants caps how many replies GET /api/chirps/{chirpID}/thread
+// returns below the requested chirp.
+const maxThreadDescendants = 500
+
+func getReplies(writer http.ResponseWriter, request *http.Request) {
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		respondWithJsonError(writer, "Chirp not found", 404)
+		return
+	}
+
+	query := request.URL.Query()
+	page, err := parsePageRequest(query.Get("limit"), query.Get("cursor"), "asc")
+	if err != nil {
+		respondWithJsonError(writer, err.Error(), 400)
+		return
+	}
+
+	if _, err := ApiCfg.Store.GetChirp(request.Context(), chirpID); err != nil {
+		respondWithJsonError(writer, "Chirp not found", 404)
+		return
+	}
+
+	replies, err := ApiCfg.Store.ListReplies(request.Context(), database.ListRepliesParams{
+		ChirpID:         chirpID,
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		RowLimit:        page.Limit + 1,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	respondWithChirpPage(writer, request, replies, page.Limit)
+}
+
+func getThread(writer http.ResponseWriter, request *http.Request) {
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		respondWithJsonError(writer, "Chirp not found", 404)
+		return
+	}
+
+	// deleted chirps are still returned here as tombstones so the thread
+	// around them stays intact
+	chirp, err := ApiCfg.Store.GetChirp(request.Context(), chirpID)
+	if err != nil {
+		respondWithJsonError(writer, "Chirp not found", 404)
+		return
+	}
+
+	ancestors, err := ApiCfg.Store.GetChirpAncestors(request.Context(), chirpID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	descendants, err := ApiCfg.Store.GetChirpDescendants(request.Context(), database.GetChirpDescendantsParams{
+		ChirpID:  chirpID,
+		RowLimit: maxThreadDescendants,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// render the whole thread in one batch, then split it back up
+	thread := append(append(ancestors, chirp), descendants...)
+	threadSlice, err := makeChirpsSlice(request, thread)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{
+		"ancestors":   threadSlice[:len(ancestors)],
+		"chirp":       threadSlice[len(ancestors)],
+		"descendants": threadSlice[len(ancestors)+1:],
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func postChirp(t *testing.T, mux *http.ServeMux, token string, payload map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	rec := doRequest(t, mux, "POST", "/api/chirps", token, payload)
+	if rec.Code != 201 {
+		t.Fatalf("create chirp: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	chirp := map[string]interface{}{}
+	decodeResponse(t, rec, &chirp)
+	return chirp
+}
+
+func TestThreadSurvivesDeletedParent(t *testing.T) {
+	mux := newTestMux(t)
+	walt := createTestUser(t, mux, "walt@breakingbad.com")
+	jesse := createTestUser(t, mux, "jesse@breakingbad.com")
+	waltToken := walt["token"].(string)
+	jesseToken := jesse["token"].(string)
+
+	root := postChirp(t, mux, waltToken, map[string]interface{}{"body": "Say my name"})
+	reply := postChirp(t, mux, jesseToken, map[string]interface{}{"body": "Heisenberg", "in_reply_to": root["id"]})
+	nested := postChirp(t, mux, waltToken, map[string]interface{}{"body": "You're goddamn right", "in_reply_to": reply["id"]})
+
+	if rec := doRequest(t, mux, "DELETE", "/api/chirps/"+reply["id"].(string), jesseToken, nil); rec.Code != 204 {
+		t.Fatalf("delete reply: status %d", rec.Code)
+	}
+
+	rec := doRequest(t, mux, "GET", "/api/chirps/"+nested["id"].(string)+"/thread", "", nil)
+	if rec.Code != 200 {
+		t.Fatalf("thread: status %d", rec.Code)
+	}
+	thread := struct {
+		Ancestors   []map[string]interface{} `json:"ancestors"`
+		Descendants []map[string]interface{} `json:"descendants"`
+	}{}
+	decodeResponse(t, rec, &thread)
+	if len(thread.Ancestors) != 2 {
+		t.Fatalf("expected 2 ancestors, got %d", len(thread.Ancestors))
+	}
+	if thread.Ancestors[0]["id"] != root["id"] {
+		t.Errorf("expected the root chirp first, got %v", thread.Ancestors[0]["id"])
+	}
+	tombstone := thread.Ancestors[1]
+	if tombstone["deleted"] != true || tombstone["body"] != "" {
+		t.Errorf("deleted reply should be a tombstone, got %v", tombstone)
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/chirps/"+root["id"].(string)+"/thread", "", nil)
+	decodeResponse(t, rec, &thread)
+	if len(thread.Descendants) != 2 {
+		t.Errorf("expected 2 descendants of the root, got %d", len(thread.Descendants))
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/chirps/"+reply["id"].(string)+"/replies", "", nil)
+	page := chirpPage{}
+	decodeResponse(t, rec, &page)
+	if len(page.Chirps) != 1 || page.Chirps[0]["id"] != nested["id"] {
+		t.Errorf("unexpected replies to the deleted chirp: %v", page.Chirps)
+	}
+
+	rec = doRequest(t, mux, "POST", "/api/chirps", waltToken, map[string]interface{}{"body": "hello?", "in_reply_to": reply["id"]})
+	if rec.Code != 400 {
+		t.Errorf("reply to deleted chirp: expected 400, got %d", rec.Code)
+	}
+}
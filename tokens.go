@@ -0,0 +1,182 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxTokenNameLength   = 100
+	maxTokenLifetimeDays = 365
+)
+
+type personalAccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+func createPersonalAccessToken(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+	tokenReq := personalAccessTokenRequest{}
+
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if err := json.Unmarshal(body, &tokenReq); err != nil {
+		respondWithJsonError(writer, "Invalid request body", 400)
+		return
+	}
+
+	name := strings.TrimSpace(tokenReq.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxTokenNameLength {
+		respondWithJsonError(writer, "Token name must be 1 to 100 characters", 400)
+		return
+	}
+
+	// personal access tokens can't manage the account, so a leaked bot
+	// token can't mint more tokens or lock its owner out
+	if len(tokenReq.Scopes) == 0 {
+		respondWithJsonError(writer, "At least one scope is required", 400)
+		return
+	}
+	for _, requested := range tokenReq.Scopes {
+		if !auth.ValidScope(requested) || requested == auth.ScopeAccountWrite {
+			respondWithJsonError(writer, "Scope not allowed: "+requested, 400)
+			return
+		}
+	}
+	scopes := []string{}
+	for _, scope := range auth.AllScopes {
+		for _, requested := range tokenReq.Scopes {
+			if requested == scope {
+				scopes = append(scopes, scope)
+				break
+			}
+		}
+	}
+
+	if tokenReq.ExpiresInDays < 0 || tokenReq.ExpiresInDays > maxTokenLifetimeDays {
+		respondWithJsonError(writer, "expires_in_days must be between 0 and 365", 400)
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if tokenReq.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{
+			Time:  time.Now().UTC().AddDate(0, 0, tokenReq.ExpiresInDays),
+			Valid: true,
+		}
+	}
+
+	token, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	accessToken, err := ApiCfg.Store.CreatePersonalAccessToken(request.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:      userID,
+		Name:        name,
+		TokenHash:   auth.HashToken(token),
+		TokenPrefix: auth.PersonalAccessTokenPrefix(token),
+		Scopes:      scopes,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// the token itself is only ever shown here
+	tokenMap := makePersonalAccessTokenMap(accessToken)
+	tokenMap["token"] = token
+	respondWithJson(writer, 201, tokenMap)
+}
+
+func getPersonalAccessTokens(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	tokens, err := ApiCfg.Store.ListPersonalAccessTokens(request.Context(), userID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	tokensSlice := []map[string]interface{}{}
+	for _, token := range tokens {
+		tokensSlice = append(tokensSlice, makePersonalAccessTokenMap(token))
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{"tokens": tokensSlice})
+}
+
+func revokePersonalAccessToken(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	tokenID, err := uuid.Parse(request.PathValue("tokenID"))
+	if err != nil {
+		respondWithJsonError(writer, "Token not found", 404)
+		return
+	}
+
+	revoked, err := ApiCfg.Store.RevokePersonalAccessToken(request.Context(), database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if revoked == 0 {
+		respondWithJsonError(writer, "Token not found", 404)
+		return
+	}
+
+	writer.WriteHeader(204)
+}
+
+func makePersonalAccessTokenMap(token database.PersonalAccessToken) map[string]interface{} {
+	tokenMap := map[string]interface{}{
+		"id":           token.ID.String(),
+		"created_at":   token.CreatedAt.String(),
+		"name":         token.Name,
+		"token_prefix": token.TokenPrefix,
+		"scopes":       token.Scopes,
+		"expires_at":   nil,
+		"last_used_at": nil,
+	}
+
+	if token.ExpiresAt.Valid {
+		tokenMap["expires_at"] = token.ExpiresAt.Time.String()
+	}
+	if token.LastUsedAt.Valid {
+		tokenMap["last_used_at"] = token.LastUsedAt.Time.String()
+	}
+
+	return tokenMap
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func createTestToken(t *testing.T, mux *http.ServeMux, token string, scopes ...string) map[string]interface{} {
+	t.Helper()
+	rec := doRequest(t, mux, "POST", "/api/tokens", token, map[string]interface{}{"name": "bot", "scopes": scopes})
+	if rec.Code != 201 {
+		t.Fatalf("create token: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	created := map[string]interface{}{}
+	decodeResponse(t, rec, &created)
+	return created
+}
+
+func TestPersonalAccessTokens(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	token := user["token"].(string)
+
+	bot := createTestToken(t, mux, token, "chirps:write")
+	botToken := bot["token"].(string)
+	if !strings.HasPrefix(botToken, bot["token_prefix"].(string)) {
+		t.Errorf("token %q does not start with its prefix %q", botToken, bot["token_prefix"])
+	}
+
+	rec := doRequest(t, mux, "POST", "/api/chirps", botToken, map[string]string{"body": "posted by a bot"})
+	if rec.Code != 201 {
+		t.Fatalf("chirp with token: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	created := map[string]interface{}{}
+	decodeResponse(t, rec, &created)
+	if created["user_id"] != user["id"] {
+		t.Errorf("chirp user_id %q does not match %q", created["user_id"], user["id"])
+	}
+
+	// the token only carries the scope it was created with
+	if rec := doRequest(t, mux, "GET", "/api/timeline", botToken, nil); rec.Code != 403 {
+		t.Errorf("timeline without chirps:read: expected 403, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/tokens", botToken, map[string]interface{}{"name": "bot", "scopes": []string{"chirps:write"}}); rec.Code != 403 {
+		t.Errorf("create token with a token: expected 403, got %d", rec.Code)
+	}
+
+	rec = doRequest(t, mux, "GET", "/api/tokens", token, nil)
+	if rec.Code != 200 {
+		t.Fatalf("list tokens: status %d", rec.Code)
+	}
+	listResp := struct {
+		Tokens []map[string]interface{} `json:"tokens"`
+	}{}
+	decodeResponse(t, rec, &listResp)
+	if len(listResp.Tokens) != 1 || listResp.Tokens[0]["id"] != bot["id"] {
+		t.Fatalf("expected the bot token to be listed, got %v", listResp.Tokens)
+	}
+	if _, ok := listResp.Tokens[0]["token"]; ok {
+		t.Errorf("listed token exposes its secret")
+	}
+	if listResp.Tokens[0]["last_used_at"] == nil {
+		t.Errorf("expected last_used_at to be set after use")
+	}
+
+	if rec := doRequest(t, mux, "DELETE", "/api/tokens/"+bot["id"].(string), token, nil); rec.Code != 204 {
+		t.Fatalf("revoke token: status %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/chirps", botToken, map[string]string{"body": "still here?"}); rec.Code != 401 {
+		t.Errorf("chirp with revoked token: expected 401, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "DELETE", "/api/tokens/"+bot["id"].(string), token, nil); rec.Code != 404 {
+		t.Errorf("revoke twice: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestPersonalAccessTokenValidation(t *testing.T) {
+	mux := newTestMux(t)
+	token := createTestUser(t, mux, "walt@breakingbad.com")["token"].(string)
+
+	cases := map[string]map[string]interface{}{
+		"no name":       {"scopes": []string{"chirps:read"}},
+		"no scopes":     {"name": "bot"},
+		"unknown scope": {"name": "bot", "scopes": []string{"chirps:admin"}},
+		"account:write": {"name": "bot", "scopes": []string{"account:write"}},
+		"too long":      {"name": "bot", "scopes": []string{"chirps:read"}, "expires_in_days": 366},
+	}
+	for name, payload := range cases {
+		if rec := doRequest(t, mux, "POST", "/api/tokens", token, payload); rec.Code != 400 {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+
+	if rec := doRequest(t, mux, "GET", "/api/tokens", "", nil); rec.Code != 401 {
+		t.Errorf("list tokens without auth: expected 401, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer        = "Chirpy"
+	recoveryCodeCount = 10
+)
+
+// totpClock is the time TOTP codes are checked against; tests replace it.
+var totpClock = time.Now
+
+type twoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+	DeviceName     string `json:"device_name"`
+}
+
+// twoFactorEnabled reports whether the user has finished enrolling in
+// two-factor authentication.
+func twoFactorEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	credential, err := ApiCfg.Store.GetTOTPCredential(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return credential.EnabledAt.Valid, nil
+}
+
+func setupTwoFactor(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	user, err := ApiCfg.Store.GetUser(request.Context(), userID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	secret, err := auth.MakeTOTPSecret()
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// setting up again before verifying replaces the pending secret and
+	// its recovery codes
+	_, err = ApiCfg.Store.UpsertTOTPCredential(request.Context(), database.UpsertTOTPCredentialParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Two-factor authentication is already enabled", 409)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	recoveryCodes, err := auth.MakeRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if err := ApiCfg.Store.DeleteRecoveryCodes(request.Context(), userID); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	for _, code := range recoveryCodes {
+		err := ApiCfg.Store.CreateRecoveryCode(request.Context(), database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+		})
+		if err != nil {
+			respondWithJsonError(writer, "Something went wrong", 500)
+			return
+		}
+	}
+
+	respondWithJson(writer, 200, map[string]interface{}{
+		"secret":         secret,
+		"otpauth_uri":    auth.TOTPURI(secret, totpIssuer, user.Email),
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// verifyTwoFactor finishes enrollment once the user proves their
+// authenticator produces the right codes.
+func verifyTwoFactor(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	twoFactorReq, ok := decodeTwoFactorRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	credential, err := ApiCfg.Store.GetTOTPCredential(request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Two-factor setup has not been started", 400)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if credential.EnabledAt.Valid {
+		respondWithJsonError(writer, "Two-factor authentication is already enabled", 409)
+		return
+	}
+
+	valid, err := checkTOTPCode(request.Context(), credential, twoFactorReq.Code)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if !valid {
+		respondWithJsonError(writer, "Invalid code", 400)
+		return
+	}
+
+	if _, err := ApiCfg.Store.EnableTOTPCredential(request.Context(), userID); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	logSecurityEvent("two_factor_enabled", userID)
+
+	writer.WriteHeader(204)
+}
+
+// disableTwoFactor turns two-factor authentication off. It takes a code
+// like a login does, so a stolen access token alone can't remove it.
+func disableTwoFactor(writer http.ResponseWriter, request *http.Request) {
+	userID, err := authenticatedUserID(request)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	twoFactorReq, ok := decodeTwoFactorRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	credential, err := ApiCfg.Store.GetTOTPCredential(request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !credential.EnabledAt.Valid) {
+		respondWithJsonError(writer, "Two-factor authentication is not enabled", 404)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	valid, err := checkSecondFactor(request.Context(), credential, twoFactorReq)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if !valid {
+		respondWithJsonError(writer, "Invalid code", 400)
+		return
+	}
+
+	if _, err := ApiCfg.Store.DeleteTOTPCredential(request.Context(), userID); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if err := ApiCfg.Store.DeleteRecoveryCodes(request.Context(), userID); err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	logSecurityEvent("two_factor_disabled", userID)
+
+	writer.WriteHeader(204)
+}
+
+// loginTwoFactor is the second step of a login for enrolled users: the
+// challenge token from /api/login and a TOTP or recovery code are exchanged
+// for access and refresh tokens.
+func loginTwoFactor(writer http.ResponseWriter, request *http.Request) {
+	twoFactorReq, ok := decodeTwoFactorRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	challenge, err := ApiCfg.Keys.ValidateChallengeToken(twoFactorReq.ChallengeToken)
+	if err != nil {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+	userID := challenge.UserID
+
+	deviceName := strings.TrimSpace(twoFactorReq.DeviceName)
+	if utf8.RuneCountInString(deviceName) > maxDeviceNameLength {
+		respondWithJsonError(writer, "Device name is too long", 400)
+		return
+	}
+
+	user, err := ApiCfg.Store.GetUser(request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	credential, err := ApiCfg.Store.GetTOTPCredential(request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !credential.EnabledAt.Valid) {
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+
+	// wrong codes count against the account like wrong passwords, so the
+	// code space can't be searched with a stolen password
+	attempt, ok := beginLoginAttempt(writer, request, user.Email)
+	if !ok {
+		return
+	}
+
+	valid, err := checkSecondFactor(request.Context(), credential, twoFactorReq)
+	if err != nil {
+		attempt.refund()
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if !valid {
+		attempt.failed(user.ID)
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+
+	// a challenge token is only good for one login; it is spent after the
+	// code is checked so a mistyped code doesn't force a new password step
+	used, err := ApiCfg.Store.UseChallengeToken(request.Context(), database.UseChallengeTokenParams{
+		ID:        challenge.ID,
+		UserID:    userID,
+		ExpiresAt: challenge.ExpiresAt,
+	})
+	if err != nil {
+		attempt.refund()
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if used == 0 {
+		attempt.failed(user.ID)
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
+	attempt.refund()
+
+	clearAccountThrottle(request.Context(), user.Email)
+	startSession(writer, request, user, deviceName)
+}
+
+func decodeTwoFactorRequest(writer http.ResponseWriter, request *http.Request) (twoFactorRequest, bool) {
+	defer request.Body.Close()
+	twoFactorReq := twoFactorRequest{}
+
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return twoFactorReq, false
+	}
+	if err := json.Unmarshal(body, &twoFactorReq); err != nil {
+		respondWithJsonError(writer, "Invalid request body", 400)
+		return twoFactorReq, false
+	}
+
+	return twoFactorReq, true
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code.
+// Recovery codes are consumed on success.
+func checkSecondFactor(ctx context.Context, credential database.TotpCredential, twoFactorReq twoFactorRequest) (bool, error) {
+	if twoFactorReq.RecoveryCode == "" {
+		return checkTOTPCode(ctx, credential, twoFactorReq.Code)
+	}
+
+	used, err := ApiCfg.Store.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   credential.UserID,
+		CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(twoFactorReq.RecoveryCode)),
+	})
+	if err != nil {
+		return false, err
+	}
+	if used == 0 {
+		return false, nil
+	}
+	logSecurityEvent("recovery_code_used", credential.UserID)
+	return true, nil
+}
+
+// checkTOTPCode validates code and records its time step, so each code
+// can be used at most once.
+func checkTOTPCode(ctx context.Context, credential database.TotpCredential, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(credential.Secret, strings.TrimSpace(code), totpClock())
+	if !ok {
+		return false, nil
+	}
+
+	used, err := ApiCfg.Store.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		Step:   step,
+		UserID: credential.UserID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return used == 1, nil
+}
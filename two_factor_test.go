@@ -0,0 +1,144 @@
+package main
+
+import (
+	"chirpy/internal/auth"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func totpCodeAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, at)
+	if err != nil {
+		t.Fatalf("unable to generate code: %v", err)
+	}
+	return code
+}
+
+// enrollTwoFactor sets up and verifies two-factor authentication for the
+// user behind token, returning the setup response.
+func enrollTwoFactor(t *testing.T, mux *http.ServeMux, token string, at time.Time) map[string]interface{} {
+	t.Helper()
+	rec := doRequest(t, mux, "POST", "/api/users/2fa/setup", token, nil)
+	if rec.Code != 200 {
+		t.Fatalf("setup: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	setup := map[string]interface{}{}
+	decodeResponse(t, rec, &setup)
+
+	code := totpCodeAt(t, setup["secret"].(string), at)
+	if rec := doRequest(t, mux, "POST", "/api/users/2fa/verify", token, map[string]string{"code": code}); rec.Code != 204 {
+		t.Fatalf("verify: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	return setup
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	mux := newTestMux(t)
+	now := time.Unix(1700000000, 0)
+	totpClock = func() time.Time { return now }
+	t.Cleanup(func() { totpClock = time.Now })
+
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	setup := enrollTwoFactor(t, mux, user["token"].(string), now)
+	secret := setup["secret"].(string)
+	if len(setup["recovery_codes"].([]interface{})) != recoveryCodeCount {
+		t.Errorf("expected %d recovery codes, got %v", recoveryCodeCount, setup["recovery_codes"])
+	}
+
+	if rec := doRequest(t, mux, "POST", "/api/users/2fa/setup", user["token"].(string), nil); rec.Code != 409 {
+		t.Errorf("setup after enrolling: expected 409, got %d", rec.Code)
+	}
+
+	challenge := func() string {
+		t.Helper()
+		rec := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "hunter2"})
+		if rec.Code != 200 {
+			t.Fatalf("login: status %d, body %s", rec.Code, rec.Body.String())
+		}
+		loginResp := map[string]interface{}{}
+		decodeResponse(t, rec, &loginResp)
+		if loginResp["two_factor_required"] != true || loginResp["token"] != nil {
+			t.Fatalf("expected a two-factor challenge, got %v", loginResp)
+		}
+		return loginResp["challenge_token"].(string)
+	}
+
+	// the challenge token can't be used as an access token
+	if rec := doRequest(t, mux, "GET", "/api/sessions", challenge(), nil); rec.Code != 401 {
+		t.Errorf("challenge as access token: expected 401, got %d", rec.Code)
+	}
+
+	// the code used to verify enrollment can't be replayed
+	replayed := totpCodeAt(t, secret, now)
+	if rec := doRequest(t, mux, "POST", "/api/login/2fa", "", twoFactorRequest{ChallengeToken: challenge(), Code: replayed}); rec.Code != 401 {
+		t.Errorf("replayed code: expected 401, got %d", rec.Code)
+	}
+
+	now = now.Add(30 * time.Second)
+	exchanged := challenge()
+	rec := doRequest(t, mux, "POST", "/api/login/2fa", "", twoFactorRequest{
+		ChallengeToken: exchanged,
+		Code:           totpCodeAt(t, secret, now),
+	})
+	if rec.Code != 200 {
+		t.Fatalf("login with code: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	loginResp := map[string]interface{}{}
+	decodeResponse(t, rec, &loginResp)
+	if loginResp["token"] == nil || loginResp["refresh_token"] == nil {
+		t.Errorf("expected tokens after two-factor login, got %v", loginResp)
+	}
+
+	// a challenge token only logs in once, even with another valid code
+	now = now.Add(30 * time.Second)
+	if rec := doRequest(t, mux, "POST", "/api/login/2fa", "", twoFactorRequest{ChallengeToken: exchanged, Code: totpCodeAt(t, secret, now)}); rec.Code != 401 {
+		t.Errorf("reused challenge token: expected 401, got %d", rec.Code)
+	}
+
+	// recovery codes work once
+	recoveryCode := setup["recovery_codes"].([]interface{})[0].(string)
+	if rec := doRequest(t, mux, "POST", "/api/login/2fa", "", twoFactorRequest{ChallengeToken: challenge(), RecoveryCode: recoveryCode}); rec.Code != 200 {
+		t.Errorf("login with recovery code: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, mux, "POST", "/api/login/2fa", "", twoFactorRequest{ChallengeToken: challenge(), RecoveryCode: recoveryCode}); rec.Code != 401 {
+		t.Errorf("reused recovery code: expected 401, got %d", rec.Code)
+	}
+
+	if rec := doRequest(t, mux, "POST", "/api/login/2fa", "", twoFactorRequest{ChallengeToken: "nope", Code: "123456"}); rec.Code != 401 {
+		t.Errorf("bad challenge: expected 401, got %d", rec.Code)
+	}
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	mux := newTestMux(t)
+	now := time.Unix(1700000000, 0)
+	totpClock = func() time.Time { return now }
+	t.Cleanup(func() { totpClock = time.Now })
+
+	token := createTestUser(t, mux, "walt@breakingbad.com")["token"].(string)
+	if rec := doRequest(t, mux, "POST", "/api/users/2fa/verify", token, map[string]string{"code": "123456"}); rec.Code != 400 {
+		t.Errorf("verify before setup: expected 400, got %d", rec.Code)
+	}
+
+	setup := enrollTwoFactor(t, mux, token, now)
+
+	if rec := doRequest(t, mux, "DELETE", "/api/users/2fa", token, map[string]string{"code": "000000"}); rec.Code != 400 {
+		t.Errorf("disable with wrong code: expected 400, got %d", rec.Code)
+	}
+
+	now = now.Add(30 * time.Second)
+	code := totpCodeAt(t, setup["secret"].(string), now)
+	if rec := doRequest(t, mux, "DELETE", "/api/users/2fa", token, map[string]string{"code": code}); rec.Code != 204 {
+		t.Fatalf("disable: status %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	// logins go straight through again
+	rec := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "hunter2"})
+	loginResp := map[string]interface{}{}
+	decodeResponse(t, rec, &loginResp)
+	if loginResp["token"] == nil {
+		t.Errorf("expected tokens after disabling two-factor, got %v", loginResp)
+	}
+}
@@ -3,18 +3,31 @@ package main
 import (
 	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"time"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
+const maxDisplayNameLength = 50
+
 type userRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	DisplayName string `json:"display_name"`
+	Handle      string `json:"handle"`
+	DeviceName  string `json:"device_name"`
 }
 
 func createUser(writer http.ResponseWriter, request *http.Request) {
@@ -32,6 +45,37 @@ func createUser(writer http.ResponseWriter, request *http.Request) {
 		respondWithJsonError(writer, "Something went wrong", 500)
 	}
 
+	email, ok := normalizeEmail(userReq.Email)
+	if !ok {
+		respondWithJsonError(writer, "Invalid email address", 400)
+		return
+	}
+
+	displayName := strings.TrimSpace(userReq.DisplayName)
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		respondWithJsonError(writer, "Display name is too long", 400)
+		return
+	}
+
+	// users who don't pick a handle get a generated one
+	handle, err := generateHandle()
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if userReq.Handle != "" {
+		var ok bool
+		handle, ok = entities.NormalizeHandle(userReq.Handle)
+		if !ok {
+			respondWithJsonError(writer, "Handles must be 3 to 15 letters, digits or underscores", 400)
+			return
+		}
+		if _, err := ApiCfg.Store.GetUserByHandle(request.Context(), handle); err == nil {
+			respondWithJsonError(writer, "Handle is already taken", 409)
+			return
+		}
+	}
+
 	hashedPassword, err := auth.HashPassword(userReq.Password)
 	if err != nil {
 		respondWithJsonError(writer, "Something went wrong", 500)
@@ -39,18 +83,24 @@ func createUser(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	newUser := database.CreateUserParams{
-		Email:          userReq.Email,
+		Email:          email,
 		HashedPassword: hashedPassword,
+		DisplayName:    displayName,
+		Handle:         handle,
 	}
 
-	user, err := ApiCfg.Queries.CreateUser(request.Context(), newUser)
+	user, err := ApiCfg.Store.CreateUser(request.Context(), newUser)
 	if err != nil {
 		errorResponse := fmt.Sprint(err)
 		respondWithJsonError(writer, errorResponse, 500)
 		return
 	}
 
-	respondWithJson(writer, 201, makeUserMap(user))
+	if err := sendEmailVerification(request.Context(), user, user.Email); err != nil {
+		log.Printf("unable to send verification email to %s: %s", user.ID, err)
+	}
+
+	respondWithJson(writer, 201, makeUserMap(request.Context(), user))
 }
 
 func login(writer http.ResponseWriter, request *http.Request) {
@@ -67,20 +117,82 @@ func login(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	user, err := ApiCfg.Queries.GetUserByEmail(request.Context(), userReq.Email)
+	// emails are stored trimmed, as normalizeEmail leaves them
+	email := strings.TrimSpace(userReq.Email)
+	attempt, ok := beginLoginAttempt(writer, request, email)
+	if !ok {
+		return
+	}
+
+	// unknown emails get the same response, after the same amount of work,
+	// as wrong passwords
+	user, err := ApiCfg.Store.GetUserByEmail(request.Context(), email)
+	if errors.Is(err, sql.ErrNoRows) {
+		auth.CheckPasswordHash(dummyPasswordHash(), userReq.Password)
+		attempt.failed(uuid.Nil)
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
 	if err != nil {
+		attempt.refund()
 		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
 
 	if err := auth.CheckPasswordHash(user.HashedPassword, userReq.Password); err != nil {
 		fmt.Println(err)
+		attempt.failed(user.ID)
 		respondWithJsonError(writer, "Unauthorized", 401)
 		return
 	}
+	attempt.refund()
+
+	deviceName := strings.TrimSpace(userReq.DeviceName)
+	if utf8.RuneCountInString(deviceName) > maxDeviceNameLength {
+		respondWithJsonError(writer, "Device name is too long", 400)
+		return
+	}
+
+	// users with two-factor authentication get a challenge to exchange for
+	// a login at /api/login/2fa instead
+	enrolled, err := twoFactorEnabled(request.Context(), user.ID)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
+	if enrolled {
+		challenge, err := ApiCfg.Keys.MakeChallengeToken(user.ID)
+		if err != nil {
+			respondWithJsonError(writer, "Something went wrong", 500)
+			return
+		}
+		respondWithJson(writer, 200, map[string]interface{}{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+		return
+	}
+
+	clearAccountThrottle(request.Context(), user.Email)
+	startSession(writer, request, user, deviceName)
+}
+
+// startSession logs user in: it creates a session, which is also the
+// refresh token family, and responds with the user and their tokens.
+func startSession(writer http.ResponseWriter, request *http.Request, user database.User, deviceName string) {
+	session, err := ApiCfg.Store.CreateSession(request.Context(), database.CreateSessionParams{
+		UserID:     user.ID,
+		UserAgent:  truncate(request.UserAgent(), maxUserAgentLength),
+		Ip:         clientIP(request),
+		DeviceName: deviceName,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return
+	}
 
-	userMap := makeUserMap(user)
-	jwtKey, err := auth.MakeJWT(user.ID, ApiCfg.Secret)
+	userMap := makeUserMap(request.Context(), user)
+	jwtKey, err := ApiCfg.Keys.MakeJWT(user.ID, uuid.NullUUID{UUID: session.ID, Valid: true}, auth.AllScopes)
 	if err != nil {
 		respondWithJsonError(writer, "unable to create login key", 500)
 		return
@@ -93,30 +205,77 @@ func login(writer http.ResponseWriter, request *http.Request) {
 	}
 
 	params := database.MakeRefreshTokenParams{
-		Token:  refToken,
-		UserID: user.ID,
+		Token:    refToken,
+		UserID:   user.ID,
+		FamilyID: session.ID,
 	}
-	if err = ApiCfg.Queries.MakeRefreshToken(request.Context(), params); err != nil {
+	if err = ApiCfg.Store.MakeRefreshToken(request.Context(), params); err != nil {
 		respondWithJsonError(writer, "Something went wrong", 500)
+		return
 	}
 
 	userMap["token"] = jwtKey
 	userMap["refresh_token"] = refToken
+	userMap["session_id"] = session.ID.String()
 	respondWithJson(writer, 200, userMap)
 }
 
-func makeUserMap(user database.User) map[string]interface{} {
+// makePublicUserMap lists the fields of a user anyone may see. Fields are
+// private unless they are added here.
+func makePublicUserMap(user database.User) map[string]interface{} {
 	userMap := map[string]interface{}{
 		"id":            user.ID.String(),
 		"created_at":    user.CreatedAt.String(),
 		"updated_at":    user.UpdatedAt.String(),
-		"email":         user.Email,
+		"handle":        user.Handle,
+		"display_name":  user.DisplayName,
 		"is_chirpy_red": user.IsChirpyRed,
+		"bio":           user.Bio,
+		"location":      user.Location,
+		"website":       user.Website,
+		"avatar_url":    nil,
+	}
+
+	if user.AvatarKey.Valid {
+		userMap["avatar_url"] = ApiCfg.Storage.URL(user.AvatarKey.String)
+	}
+	return userMap
+}
+
+// makeUserMap is the user as they see themselves: the public fields plus
+// their email, subscription and entitlements.
+func makeUserMap(ctx context.Context, user database.User) map[string]interface{} {
+	userMap := makePublicUserMap(user)
+	userMap["email"] = user.Email
+	userMap["email_verified"] = user.EmailVerifiedAt.Valid
+	userMap["subscription"] = nil
+
+	// a missing subscription only leaves it out of the response
+	var current *database.Subscription
+	subscription, err := ApiCfg.Store.GetSubscription(ctx, user.ID)
+	if err == nil {
+		current = &subscription
+		userMap["subscription"] = makeSubscriptionMap(subscription)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("unable to load subscription of %s: %s", user.ID, err)
 	}
 
+	plan := planFor(user, current)
+	userMap["entitlements"] = makeEntitlementsMap(plan, ApiCfg.Plans.Limits(plan))
+
 	return userMap
 }
 
+// generateHandle returns a random handle in the same user_xxxxxxxxxx form
+// the migration gave existing users.
+func generateHandle() (string, error) {
+	suffix := make([]byte, 5)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return "user_" + hex.EncodeToString(suffix), nil
+}
+
 func refresh(writer http.ResponseWriter, request *http.Request) {
 	defer request.Body.Close()
 	authorization, err := auth.GetBearerToken(request.Header)
@@ -125,33 +284,72 @@ func refresh(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	token, err := ApiCfg.Queries.CheckRefreshToken(request.Context(), authorization)
-	if err != nil {
+	// sign the access token before rotating, so a signing failure leaves
+	// the presented token usable for a retry instead of looking like reuse
+	current, err := ApiCfg.Store.CheckRefreshToken(request.Context(), authorization)
+	if errors.Is(err, sql.ErrNoRows) {
 		respondWithJsonError(writer, "Unauthorized", 401)
 		return
 	}
-	if token.ExpiresAt.Before(time.Now()) {
-		respondWithJsonError(writer, "Unauthorized", 401)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
-	if token.RevokedAt.Valid && token.RevokedAt.Time.Before(time.Now()) {
-		respondWithJsonError(writer, "Unauthorized", 401)
+
+	jwt, err := ApiCfg.Keys.MakeJWT(current.UserID, uuid.NullUUID{UUID: current.FamilyID, Valid: true}, auth.AllScopes)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
 
-	user, err := ApiCfg.Queries.GetUserFromRefreshToken(request.Context(), token.Token)
+	newToken, err := auth.MakeRefreshToken()
 	if err != nil {
 		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
 
-	jwt, err := auth.MakeJWT(user.ID, ApiCfg.Secret)
+	// revoke the presented token and issue its replacement in one statement.
+	// This only succeeds for a live token, so two requests can't both rotate
+	// it, and a failure leaves the presented token usable for a retry.
+	token, err := ApiCfg.Store.RotateRefreshToken(request.Context(), database.RotateRefreshTokenParams{
+		ReplacedBy: sql.NullString{String: newToken, Valid: true},
+		Token:      authorization,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		detectRefreshTokenReuse(request.Context(), authorization)
+		respondWithJsonError(writer, "Unauthorized", 401)
+		return
+	}
 	if err != nil {
 		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
 
-	respondWithJson(writer, 200, map[string]string{"token": jwt})
+	if err := ApiCfg.Store.TouchSession(request.Context(), token.FamilyID); err != nil {
+		log.Printf("unable to update session %s: %s", token.FamilyID, err)
+	}
+
+	respondWithJson(writer, 200, map[string]string{
+		"token":         jwt,
+		"refresh_token": newToken,
+	})
+}
+
+// detectRefreshTokenReuse is called when a refresh token could not be
+// rotated. A token that was already rotated should never be presented
+// again, so either it was stolen or the client that holds the family is
+// being impersonated: revoke the whole family and record the event.
+func detectRefreshTokenReuse(ctx context.Context, tokenString string) {
+	token, err := ApiCfg.Store.CheckRefreshToken(ctx, tokenString)
+	if err != nil || !token.ReplacedBy.Valid {
+		return
+	}
+
+	revoked, err := ApiCfg.Store.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+	if err != nil {
+		log.Printf("unable to revoke refresh token family %s: %s", token.FamilyID, err)
+	}
+	logSecurityEvent("refresh_token_reuse", token.UserID, "family", token.FamilyID, "revoked", revoked)
 }
 
 func revoke(writer http.ResponseWriter, request *http.Request) {
@@ -161,7 +359,7 @@ func revoke(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	if err = ApiCfg.Queries.RevokeRefreshToken(request.Context(), token); err != nil {
+	if err = ApiCfg.Store.RevokeRefreshToken(request.Context(), token); err != nil {
 		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
@@ -169,113 +367,222 @@ func revoke(writer http.ResponseWriter, request *http.Request) {
 	writer.WriteHeader(204)
 }
 
+// putUserRequest is the body of PUT /api/users, which sets the email and
+// password together.
+type putUserRequest struct {
+	Email           string `json:"email"`
+	Password        string `json:"password"`
+	CurrentPassword string `json:"current_password"`
+}
+
+// updateUser is PATCH /api/users with the email and password set, so it
+// also needs the current password and signs out the other sessions.
 func updateUser(writer http.ResponseWriter, request *http.Request) {
 	defer request.Body.Close()
-	userReq := userRequest{}
-
-	//get the user's authentication from the request header
-	token, err := auth.GetBearerToken(request.Header)
-	if err != nil {
-		respondWithJsonError(writer, "Unauthorized", 401)
-		return
-	}
+	putReq := putUserRequest{}
 
-	// verify the authentication
-	userID, err := auth.ValidateJWT(token, ApiCfg.Secret)
+	userID, sessionID, err := authenticatedSession(request)
 	if err != nil {
 		respondWithJsonError(writer, "Unauthorized", 401)
 		return
 	}
 
-	// read the request body
 	body, err := io.ReadAll(request.Body)
 	if err != nil {
 		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
-
-	// unmarshal the body json
-	if err := json.Unmarshal(body, &userReq); err != nil {
-		respondWithJsonError(writer, "Something went wrong", 500)
-		return
-	}
-
-	// hash the password
-	hashedPassword, err := auth.HashPassword(userReq.Password)
-	if err != nil {
-		respondWithJsonError(writer, "Something went wrong", 500)
+	if err := json.Unmarshal(body, &putReq); err != nil {
+		respondWithJsonError(writer, "Invalid request body", 400)
 		return
 	}
 
-	// set the argument parameters for UpdateUser
-	params := database.UpdateUserParams{
-		Email:          userReq.Email,
-		HashedPassword: hashedPassword,
-		ID:             userID,
+	patchReq := patchUserRequest{
+		Email:           &putReq.Email,
+		CurrentPassword: putReq.CurrentPassword,
 	}
-
-	// add entry to database
-	user, err := ApiCfg.Queries.UpdateUser(request.Context(), params)
-	if err != nil {
-		respondWithJsonError(writer, "Something went wrong", 500)
-		return
+	// sending the current password again isn't a change
+	if putReq.Password != putReq.CurrentPassword {
+		patchReq.Password = &putReq.Password
 	}
-
-	respondWithJson(writer, 200, makeUserMap(user))
+	applyUserPatch(writer, request, userID, sessionID, patchReq)
 }
 
-type upgradeUserRequest struct {
-	Event string `json:"event"`
-	Data  struct {
-		UserID string `json:"user_id"`
-	} `json:"data"`
+// patchUserRequest holds the fields PATCH /api/users may change. A nil
+// field is left as it is.
+type patchUserRequest struct {
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	CurrentPassword string  `json:"current_password"`
+	DisplayName     *string `json:"display_name"`
+	Handle          *string `json:"handle"`
+	Bio             *string `json:"bio"`
+	Location        *string `json:"location"`
+	Website         *string `json:"website"`
 }
 
-func upgradeUser(writer http.ResponseWriter, request *http.Request) {
+func patchUser(writer http.ResponseWriter, request *http.Request) {
 	defer request.Body.Close()
-	upgradeReq := upgradeUserRequest{}
+	patchReq := patchUserRequest{}
 
-	//check the api key
-	apiKey, err := auth.GetApiKey(request.Header)
+	userID, sessionID, err := authenticatedSession(request)
 	if err != nil {
 		respondWithJsonError(writer, "Unauthorized", 401)
 		return
 	}
-	if apiKey != ApiCfg.PolkaKey {
-		respondWithJsonError(writer, "Unauthorized", 401)
-		return
-	}
 
-	// read request body
 	body, err := io.ReadAll(request.Body)
 	if err != nil {
 		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
+	if err := json.Unmarshal(body, &patchReq); err != nil {
+		respondWithJsonError(writer, "Invalid request body", 400)
+		return
+	}
 
-	// unmarshal body json
-	if err := json.Unmarshal(body, &upgradeReq); err != nil {
+	applyUserPatch(writer, request, userID, sessionID, patchReq)
+}
+
+// applyUserPatch validates every field of patchReq before changing any of
+// them. Changing the email or password needs the current password.
+func applyUserPatch(writer http.ResponseWriter, request *http.Request, userID uuid.UUID, sessionID uuid.NullUUID, patchReq patchUserRequest) {
+	current, err := ApiCfg.Store.GetUser(request.Context(), userID)
+	if err != nil {
 		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
 
-	// if request is invalid, return with status 204
-	if upgradeReq.Event == "" || upgradeReq.Data.UserID == "" {
-		respondWithJson(writer, 204, "")
+	// validate everything before changing anything
+	params := database.PatchUserParams{ID: userID}
+	if patchReq.DisplayName != nil {
+		displayName := strings.TrimSpace(*patchReq.DisplayName)
+		if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+			respondWithJsonError(writer, "Display name is too long", 400)
+			return
+		}
+		params.DisplayName = sql.NullString{String: displayName, Valid: true}
+	}
+
+	if patchReq.Handle != nil {
+		handle, ok := entities.NormalizeHandle(*patchReq.Handle)
+		if !ok {
+			respondWithJsonError(writer, "Handles must be 3 to 15 letters, digits or underscores", 400)
+			return
+		}
+		if other, err := ApiCfg.Store.GetUserByHandle(request.Context(), handle); err == nil && other.ID != userID {
+			respondWithJsonError(writer, "Handle is already taken", 409)
+			return
+		}
+		params.Handle = sql.NullString{String: handle, Valid: true}
+	}
+
+	if !validateProfile(writer, patchReq, &params) {
 		return
 	}
 
-	userID, err := uuid.Parse(upgradeReq.Data.UserID)
+	newEmail := ""
+	if patchReq.Email != nil {
+		email, ok := normalizeEmail(*patchReq.Email)
+		if !ok {
+			respondWithJsonError(writer, "Invalid email address", 400)
+			return
+		}
+		if email != current.Email {
+			if _, err := ApiCfg.Store.GetUserByEmail(request.Context(), email); err == nil {
+				respondWithJsonError(writer, "Email is already in use", 409)
+				return
+			}
+			newEmail = email
+		}
+	}
+
+	if patchReq.Password != nil {
+		if msg := validatePassword(*patchReq.Password); msg != "" {
+			respondWithJsonError(writer, msg, 400)
+			return
+		}
+	}
+
+	// a stolen access token alone isn't enough to take over the account.
+	// Wrong guesses count towards the login lockout.
+	if newEmail != "" || patchReq.Password != nil {
+		if patchReq.CurrentPassword == "" {
+			respondWithJsonError(writer, "current_password is required to change email or password", 400)
+			return
+		}
+		attempt, ok := beginLoginAttempt(writer, request, current.Email)
+		if !ok {
+			return
+		}
+		if err := auth.CheckPasswordHash(current.HashedPassword, patchReq.CurrentPassword); err != nil {
+			attempt.failed(userID)
+			respondWithJsonError(writer, "Current password is incorrect", 403)
+			return
+		}
+		attempt.refund()
+	}
+
+	user, err := ApiCfg.Store.PatchUser(request.Context(), params)
 	if err != nil {
 		respondWithJsonError(writer, "Something went wrong", 500)
 		return
 	}
 
-	// if request is valid, upgrade user
-	if err := ApiCfg.Queries.UpgradeUser(request.Context(), userID); err != nil {
-		respondWithJsonError(writer, "User not found", 404)
-		return
+	if patchReq.Password != nil {
+		if !changePassword(writer, request, userID, sessionID, *patchReq.Password) {
+			return
+		}
+	}
+
+	userMap := makeUserMap(request.Context(), user)
+	if newEmail != "" {
+		if err := sendEmailVerification(request.Context(), user, newEmail); err != nil {
+			log.Printf("unable to send email change confirmation to %s: %s", user.ID, err)
+			respondWithJsonError(writer, "Unable to send confirmation email", 500)
+			return
+		}
+		userMap["pending_email"] = newEmail
+	}
+
+	publishUserUpdated(request.Context(), user)
+	respondWithJson(writer, 200, userMap)
+}
+
+// changePassword sets a new password and signs out every session except
+// the one making the change.
+func changePassword(writer http.ResponseWriter, request *http.Request, userID uuid.UUID, sessionID uuid.NullUUID, password string) bool {
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return false
+	}
+	err = ApiCfg.Store.UpdateUserPassword(request.Context(), database.UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return false
+	}
+
+	var revoked int64
+	if sessionID.Valid {
+		revoked, err = ApiCfg.Store.RevokeOtherSessions(request.Context(), database.RevokeOtherSessionsParams{
+			UserID:        userID,
+			KeepSessionID: sessionID.UUID,
+		})
+	} else {
+		revoked, err = ApiCfg.Store.RevokeUserRefreshTokens(request.Context(), userID)
+	}
+	if err != nil {
+		respondWithJsonError(writer, "Something went wrong", 500)
+		return false
 	}
 
-	respondWithJson(writer, 204, "")
+	if err := ApiCfg.Store.InvalidatePasswordResetTokens(request.Context(), userID); err != nil {
+		log.Printf("unable to invalidate reset tokens for %s: %s", userID, err)
+	}
+	logSecurityEvent("password_changed", userID, "revoked", revoked)
+	return true
 }
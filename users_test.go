@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPatchUser(t *testing.T) {
+	mux := newTestMux(t)
+	user := createTestUser(t, mux, "walt@breakingbad.com")
+	token := user["token"].(string)
+	createTestUserWithHandle(t, mux, "jesse@breakingbad.com", "pinkman")
+
+	// profile fields don't need the current password, and omitted fields
+	// are left alone
+	rec := doRequest(t, mux, "PATCH", "/api/users", token, map[string]string{"display_name": "Heisenberg"})
+	if rec.Code != 200 {
+		t.Fatalf("patch display name: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	patched := map[string]interface{}{}
+	decodeResponse(t, rec, &patched)
+	if patched["display_name"] != "Heisenberg" || patched["handle"] != user["handle"] || patched["email"] != "walt@breakingbad.com" {
+		t.Errorf("unexpected user after patch: %v", patched)
+	}
+
+	cases := []struct {
+		name    string
+		payload map[string]string
+		status  int
+	}{
+		{"taken handle", map[string]string{"handle": "pinkman"}, 409},
+		{"invalid handle", map[string]string{"handle": "no"}, 400},
+		{"invalid email", map[string]string{"email": "walt"}, 400},
+		{"empty password", map[string]string{"password": "", "current_password": "hunter2"}, 400},
+		{"password without current", map[string]string{"password": "heisenberg"}, 400},
+		{"email without current", map[string]string{"email": "heisenberg@breakingbad.com"}, 400},
+		{"wrong current password", map[string]string{"password": "heisenberg", "current_password": "hunter3"}, 403},
+	}
+	for _, tc := range cases {
+		if rec := doRequest(t, mux, "PATCH", "/api/users", token, tc.payload); rec.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d, body %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	// an unchanged email doesn't need the current password
+	if rec := doRequest(t, mux, "PATCH", "/api/users", token, map[string]string{"email": "walt@breakingbad.com"}); rec.Code != 200 {
+		t.Errorf("patch with the same email: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestPatchUserPasswordRevokesOtherSessions(t *testing.T) {
+	mux := newTestMux(t)
+	current := createTestUser(t, mux, "walt@breakingbad.com")
+	other := map[string]interface{}{}
+	decodeResponse(t, doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "hunter2"}), &other)
+
+	rec := doRequest(t, mux, "PATCH", "/api/users", current["token"].(string), map[string]string{
+		"password":         "heisenberg",
+		"current_password": "hunter2",
+	})
+	if rec.Code != 200 {
+		t.Fatalf("change password: status %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := doRequest(t, mux, "POST", "/api/refresh", other["refresh_token"].(string), nil); rec.Code != 401 {
+		t.Errorf("refresh other session: expected 401, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/refresh", current["refresh_token"].(string), nil); rec.Code != 200 {
+		t.Errorf("refresh current session: expected 200, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "heisenberg"}); rec.Code != 200 {
+		t.Errorf("login with new password: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestPutUserNeedsCurrentPassword(t *testing.T) {
+	mux := newTestMux(t)
+	current := createTestUser(t, mux, "walt@breakingbad.com")
+	token := current["token"].(string)
+	other := map[string]interface{}{}
+	decodeResponse(t, doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "hunter2"}), &other)
+
+	// an access token alone can't change the password
+	if rec := doRequest(t, mux, "PUT", "/api/users", token, putUserRequest{Email: "walt@breakingbad.com", Password: "heisenberg"}); rec.Code != 400 {
+		t.Errorf("put without current password: expected 400, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "PUT", "/api/users", token, putUserRequest{Email: "walt@breakingbad.com", Password: "heisenberg", CurrentPassword: "hunter3"}); rec.Code != 403 {
+		t.Errorf("put with wrong current password: expected 403, got %d", rec.Code)
+	}
+
+	rec := doRequest(t, mux, "PUT", "/api/users", token, putUserRequest{Email: "walt@breakingbad.com", Password: "heisenberg", CurrentPassword: "hunter2"})
+	if rec.Code != 200 {
+		t.Fatalf("put user: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(t, mux, "POST", "/api/refresh", other["refresh_token"].(string), nil); rec.Code != 401 {
+		t.Errorf("refresh other session: expected 401, got %d", rec.Code)
+	}
+	if rec := doRequest(t, mux, "POST", "/api/login", "", userRequest{Email: "walt@breakingbad.com", Password: "heisenberg"}); rec.Code != 200 {
+		t.Errorf("login with new password: expected 200, got %d", rec.Code)
+	}
+}